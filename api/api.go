@@ -5,6 +5,11 @@ import (
 	"github.com/gin-gonic/gin"
 	v1 "github.com/ibrat-muslim/blog-app/api/v1"
 	"github.com/ibrat-muslim/blog-app/config"
+	"github.com/ibrat-muslim/blog-app/pkg/blob"
+	"github.com/ibrat-muslim/blog-app/pkg/imaging"
+	"github.com/ibrat-muslim/blog-app/pkg/password"
+	"github.com/ibrat-muslim/blog-app/pkg/utils"
+	"github.com/ibrat-muslim/blog-app/pkg/web"
 	"github.com/ibrat-muslim/blog-app/storage"
 
 	swaggerFiles "github.com/swaggo/files"     // swagger embed files
@@ -14,9 +19,14 @@ import (
 )
 
 type RouterOptions struct {
-	Cfg      *config.Config
-	Storage  storage.StorageI
-	InMemory storage.InMemoryStorageI
+	Cfg       *config.Config
+	Storage   storage.StorageI
+	InMemory  storage.InMemoryStorageI
+	Keys      *utils.KeySet
+	Passwords *password.Service
+	Blobs     blob.BlobStore
+	Images    *imaging.Processor
+	Pages     *web.Renderer
 }
 
 // @title           Swagger for blog api
@@ -34,53 +44,127 @@ func New(opt *RouterOptions) *gin.Engine {
 	corsConfig.AllowAllOrigins = true
 	corsConfig.AllowCredentials = true
 	corsConfig.AllowHeaders = append(corsConfig.AllowHeaders, "*")
+	corsConfig.ExposeHeaders = append(corsConfig.ExposeHeaders, "X-Request-ID", "X-Impersonated-By")
 	router.Use(cors.New(corsConfig))
+	router.Use(v1.RequestID)
 
 	handlerV1 := v1.New(&v1.HandlerV1Options{
-		Cfg:      opt.Cfg,
-		Storage:  opt.Storage,
-		InMemory: opt.InMemory,
+		Cfg:       opt.Cfg,
+		Storage:   opt.Storage,
+		InMemory:  opt.InMemory,
+		Keys:      opt.Keys,
+		Passwords: opt.Passwords,
+		Blobs:     opt.Blobs,
+		Images:    opt.Images,
+		Pages:     opt.Pages,
 	})
 
-	router.Static("/media", "./media")
+	// Uploaded files keep their /media links whatever the driver, the app serves local
+	// files itself and sends clients to the bucket otherwise
+	media := router.Group("/media", v1.MediaHeaders)
+	if opt.Cfg.Blob.Driver == blob.DriverS3 {
+		media.GET("/*key", handlerV1.RedirectMedia)
+	} else {
+		media.Static("/", opt.Cfg.Blob.LocalDir)
+	}
 
 	apiV1 := router.Group("/v1")
+	apiV1.Use(handlerV1.AuditMiddleware)
 
 	apiV1.GET("/users/:id", handlerV1.GetUser)
-	apiV1.GET("/users/me", handlerV1.AuthMiddleware, handlerV1.GetUserProfile)
+	apiV1.GET("/users/me", handlerV1.AuthWithScope(utils.ScopeUsersRead), handlerV1.GetUserProfile)
 	apiV1.GET("/users", handlerV1.GetUsers)
-	apiV1.POST("/users", handlerV1.AuthMiddleware, handlerV1.CreateUser)
-	apiV1.PUT("/users/:id", handlerV1.AuthMiddleware, handlerV1.UpdateUser)
-	apiV1.DELETE("users/:id", handlerV1.AuthMiddleware, handlerV1.DeleteUser)
+	apiV1.GET("/users/me/tokens", handlerV1.AuthMiddleware, handlerV1.GetPersonalAccessTokens)
+	apiV1.POST("/users/me/tokens", handlerV1.AuthMiddleware, handlerV1.CreatePersonalAccessToken)
+	apiV1.DELETE("/users/me/tokens/:id", handlerV1.AuthMiddleware, handlerV1.RevokePersonalAccessToken)
+	apiV1.GET("/users/me/sessions", handlerV1.AuthMiddleware, handlerV1.GetSessions)
+	apiV1.DELETE("/users/me/sessions/:id", handlerV1.AuthMiddleware, handlerV1.RevokeSession)
+	apiV1.POST("/users/me/email", handlerV1.AuthMiddleware, handlerV1.ChangeEmail)
+	apiV1.POST("/users/me/email/confirm", handlerV1.AuthMiddleware, handlerV1.ConfirmEmailChange)
+	apiV1.GET("/users/me/comments", handlerV1.AuthWithScope(utils.ScopeCommentsRead), handlerV1.GetMyComments)
+	apiV1.GET("/users/me/export", handlerV1.AuthMiddleware, handlerV1.ExportData)
+	apiV1.DELETE("/users/me", handlerV1.AuthMiddleware, handlerV1.DeleteAccount)
+	apiV1.POST("/users/me/deletion/cancel", handlerV1.AuthMiddleware, handlerV1.CancelAccountDeletion)
+	apiV1.POST("/users", handlerV1.AuthWithScope(utils.ScopeUsersWrite), handlerV1.CreateUser)
+	apiV1.PUT("/users/:id", handlerV1.AuthWithScope(utils.ScopeUsersWrite), handlerV1.UpdateUser)
+	apiV1.DELETE("users/:id", handlerV1.AuthWithScope(utils.ScopeUsersWrite), handlerV1.DeleteUser)
 
 	apiV1.GET("/categories/:id", handlerV1.GetCategory)
 	apiV1.GET("/categories", handlerV1.GetCategories)
-	apiV1.POST("/categories", handlerV1.AuthMiddleware, handlerV1.CreateCategory)
-	apiV1.PUT("/categories/:id", handlerV1.AuthMiddleware, handlerV1.UpdateCategory)
-	apiV1.DELETE("categories/:id", handlerV1.AuthMiddleware, handlerV1.DeleteCategory)
+	apiV1.POST("/categories", handlerV1.AuthWithScope(utils.ScopeCategoriesWrite), handlerV1.CreateCategory)
+	apiV1.PUT("/categories/:id", handlerV1.AuthWithScope(utils.ScopeCategoriesWrite), handlerV1.UpdateCategory)
+	apiV1.DELETE("categories/:id", handlerV1.AuthWithScope(utils.ScopeCategoriesWrite), handlerV1.DeleteCategory)
 
 	apiV1.GET("/posts/:id", handlerV1.GetPost)
 	apiV1.GET("/posts", handlerV1.GetPosts)
-	apiV1.POST("/posts", handlerV1.AuthMiddleware, handlerV1.CreatePost)
-	apiV1.PUT("/posts/:id", handlerV1.AuthMiddleware, handlerV1.UpdatePost)
-	apiV1.DELETE("posts/:id", handlerV1.AuthMiddleware, handlerV1.DeletePost)
+	apiV1.POST("/posts", handlerV1.AuthWithScope(utils.ScopePostsWrite), handlerV1.CreatePost)
+	apiV1.PUT("/posts/:id", handlerV1.AuthWithScope(utils.ScopePostsWrite), handlerV1.UpdatePost)
+	apiV1.DELETE("posts/:id", handlerV1.AuthWithScope(utils.ScopePostsWrite), handlerV1.DeletePost)
 
 	apiV1.GET("/comments", handlerV1.GetComments)
-	apiV1.POST("/comments", handlerV1.AuthMiddleware, handlerV1.CreateComment)
-	apiV1.PUT("/comments/:id", handlerV1.AuthMiddleware, handlerV1.UpdateComment)
-	apiV1.DELETE("comments/:id", handlerV1.AuthMiddleware, handlerV1.DeleteComment)
+	apiV1.POST("/comments", handlerV1.AuthWithScope(utils.ScopeCommentsWrite), handlerV1.CreateComment)
+	apiV1.PUT("/comments/:id", handlerV1.AuthWithScope(utils.ScopeCommentsWrite), handlerV1.UpdateComment)
+	apiV1.DELETE("comments/:id", handlerV1.AuthWithScope(utils.ScopeCommentsWrite), handlerV1.DeleteComment)
 
-	apiV1.GET("/likes/user-post", handlerV1.AuthMiddleware, handlerV1.GetLike)
-	apiV1.POST("/likes", handlerV1.AuthMiddleware, handlerV1.CreateOrUpdateLike)
+	apiV1.GET("/likes/user-post", handlerV1.AuthWithScope(utils.ScopeLikesRead), handlerV1.GetLike)
+	apiV1.POST("/likes", handlerV1.AuthWithScope(utils.ScopeLikesWrite), handlerV1.CreateOrUpdateLike)
 
-	apiV1.POST("/file-upload", handlerV1.AuthMiddleware, handlerV1.UploadFile)
+	apiV1.POST("/file-upload", handlerV1.AuthWithScope(utils.ScopeFilesWrite), handlerV1.UploadFile)
+	apiV1.GET("/media", handlerV1.AuthWithScope(utils.ScopeFilesRead), handlerV1.GetMedia)
+	apiV1.DELETE("/media/:id", handlerV1.AuthWithScope(utils.ScopeFilesWrite), handlerV1.DeleteMedia)
 
 	apiV1.POST("/auth/register", handlerV1.Register)
 	apiV1.POST("/auth/verify", handlerV1.Verify)
 	apiV1.POST("/auth/login", handlerV1.Login)
+	apiV1.POST("/auth/login/2fa", handlerV1.LoginTwoFactor)
+	apiV1.POST("/auth/magic-link", handlerV1.SendMagicLink)
+	apiV1.GET("/auth/magic-link/consume", handlerV1.ConsumeMagicLink)
+	apiV1.GET("/auth/oidc/login", handlerV1.OIDCLogin)
+	apiV1.GET("/auth/oidc/callback", handlerV1.OIDCCallback)
+	apiV1.GET("/auth/email-change/undo", handlerV1.GetEmailChangeUndo)
+	apiV1.POST("/auth/email-change/undo", handlerV1.UndoEmailChange)
 	apiV1.POST("/auth/forgot-password", handlerV1.ForgotPassword)
 	apiV1.POST("/auth/verify-forgot-password", handlerV1.VerifyForgotPassword)
 	apiV1.POST("/auth/update-password", handlerV1.AuthMiddleware, handlerV1.UpdatePassword)
+	apiV1.POST("/auth/2fa/enroll", handlerV1.AuthMiddleware, handlerV1.EnrollTwoFactor)
+	apiV1.POST("/auth/2fa/confirm", handlerV1.AuthMiddleware, handlerV1.ConfirmTwoFactor)
+	apiV1.POST("/auth/2fa/disable", handlerV1.AuthMiddleware, handlerV1.DisableTwoFactor)
+
+	apiV1.GET("/admin/email-outbox", handlerV1.AuthMiddleware, handlerV1.GetEmailOutbox)
+	apiV1.POST("/admin/email-outbox/:id/resend", handlerV1.AuthMiddleware, handlerV1.ResendEmail)
+	apiV1.GET("/admin/email-templates/:name/preview", handlerV1.AuthMiddleware, handlerV1.PreviewEmailTemplate)
+	apiV1.POST("/admin/users/:id/ban", handlerV1.AuthMiddleware, handlerV1.BanUser)
+	apiV1.DELETE("/admin/users/:id/ban", handlerV1.AuthMiddleware, handlerV1.UnbanUser)
+	apiV1.POST("/admin/users/:id/suspend", handlerV1.AuthMiddleware, handlerV1.SuspendUser)
+	apiV1.DELETE("/admin/users/:id/suspend", handlerV1.AuthMiddleware, handlerV1.UnsuspendUser)
+	apiV1.GET("/admin/users/:id/moderation-actions", handlerV1.AuthMiddleware, handlerV1.GetModerationActions)
+	apiV1.GET("/admin/audit-log", handlerV1.AuthMiddleware, handlerV1.GetAuditLog)
+	apiV1.POST("/admin/webhooks", handlerV1.AuthMiddleware, handlerV1.CreateWebhook)
+	apiV1.GET("/admin/webhooks", handlerV1.AuthMiddleware, handlerV1.GetWebhooks)
+	apiV1.GET("/admin/webhooks/:id", handlerV1.AuthMiddleware, handlerV1.GetWebhook)
+	apiV1.PUT("/admin/webhooks/:id", handlerV1.AuthMiddleware, handlerV1.UpdateWebhook)
+	apiV1.DELETE("/admin/webhooks/:id", handlerV1.AuthMiddleware, handlerV1.DeleteWebhook)
+	apiV1.GET("/admin/webhooks/:id/deliveries", handlerV1.AuthMiddleware, handlerV1.GetWebhookDeliveries)
+	apiV1.POST("/admin/webhooks/:id/test", handlerV1.AuthMiddleware, handlerV1.SendTestWebhook)
+	apiV1.POST("/admin/impersonate/:userID", handlerV1.AuthMiddleware, handlerV1.ImpersonateUser)
+
+	router.GET("/.well-known/jwks.json", handlerV1.GetJWKS)
+
+	router.GET("/feeds/posts.rss", handlerV1.GetPostsRSS)
+	router.GET("/feeds/posts.atom", handlerV1.GetPostsAtom)
+	router.GET("/feeds/categories/:id", handlerV1.GetCategoryFeed)
+	router.GET("/feeds/authors/:id", handlerV1.GetAuthorFeed)
+
+	router.GET("/", handlerV1.HomePage)
+	router.GET("/posts/:id", handlerV1.PostPage)
+	router.GET("/categories/:id", handlerV1.CategoryPage)
+	router.GET("/authors/:id", handlerV1.AuthorPage)
+	router.GET("/search", handlerV1.SearchPage)
+	router.Group("/static", v1.StaticHeaders).StaticFS("/", web.Static())
+
+	router.GET("/sitemap.xml", handlerV1.GetSitemap)
+	router.GET("/sitemaps/:page", handlerV1.GetSitemapPage)
+	router.GET("/robots.txt", handlerV1.GetRobots)
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditLog struct {
+	ID             int64           `json:"id"`
+	ActorID        *int64          `json:"actor_id"`
+	ImpersonatorID *int64          `json:"impersonator_id"`
+	Action         string          `json:"action"`
+	TargetType     *string         `json:"target_type"`
+	TargetID       *string         `json:"target_id"`
+	Before         json.RawMessage `json:"before" swaggertype:"object"`
+	After          json.RawMessage `json:"after" swaggertype:"object"`
+	IP             string          `json:"ip"`
+	RequestID      string          `json:"request_id"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+type GetAuditLogParams struct {
+	Limit          int32  `json:"limit" binding:"required" default:"10"`
+	Page           int32  `json:"page" binding:"required" default:"1"`
+	ActorID        int64  `json:"actor_id"`
+	ImpersonatorID int64  `json:"impersonator_id"`
+	TargetType     string `json:"target_type" enums:"category,user"`
+	TargetID       string `json:"target_id"`
+	// From and To are RFC 3339 times, To is exclusive
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type GetAuditLogResponse struct {
+	Entries []*AuditLog `json:"entries"`
+	Count   int32       `json:"count"`
+}
@@ -6,7 +6,8 @@ type RegisterRequest struct {
 	FirstName string `json:"first_name" binding:"required,min=2,max=30"`
 	LastName  string `json:"last_name" binding:"required,min=2,max=30"`
 	Email     string `json:"email" binding:"required,email"`
-	Password  string `json:"password" binding:"required,min=6,max=16"`
+	Password  string `json:"password" binding:"required"`
+	Language  string `json:"language" binding:"omitempty,oneof=uz ru en"`
 }
 
 type AuthResponse struct {
@@ -22,7 +23,7 @@ type AuthResponse struct {
 
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6,max=16"`
+	Password string `json:"password" binding:"required"`
 }
 
 type VerifyRequest struct {
@@ -34,6 +35,10 @@ type ForgotPasswordRequest struct {
 	Email     string `json:"email" binding:"required,email"`
 }
 
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
 type UpdatePasswordRequest struct {
-	Password  string `json:"password" binding:"required,min=6,max=16"`
+	Password  string `json:"password" binding:"required"`
 }
\ No newline at end of file
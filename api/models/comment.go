@@ -13,11 +13,12 @@ type Comment struct {
 }
 
 type CommentUser struct {
-	ID              int64   `json:"id"`
-	FirstName       string  `json:"first_name"`
-	LastName        string  `json:"last_name"`
-	Email           string  `json:"email"`
-	ProfileImageUrl *string `json:"profile_image_url"`
+	ID                   int64             `json:"id"`
+	FirstName            string            `json:"first_name"`
+	LastName             string            `json:"last_name"`
+	Email                string            `json:"email"`
+	ProfileImageUrl      *string           `json:"profile_image_url"`
+	ProfileImageVariants map[string]string `json:"profile_image_variants,omitempty"`
 }
 
 type CreateCommentRequest struct {
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+type EmailOutbox struct {
+	ID            int64      `json:"id"`
+	Recipient     string     `json:"recipient"`
+	Subject       string     `json:"subject"`
+	Status        string     `json:"status"`
+	Attempts      int32      `json:"attempts"`
+	LastError     *string    `json:"last_error"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SentAt        *time.Time `json:"sent_at"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+}
+
+type GetEmailOutboxParams struct {
+	Limit  int32  `json:"limit" binding:"required" default:"10"`
+	Page   int32  `json:"page" binding:"required" default:"1"`
+	Status string `json:"status" enums:"pending,sent,dead"`
+}
+
+type GetEmailOutboxResponse struct {
+	Emails []*EmailOutbox `json:"emails"`
+	Count  int32          `json:"count"`
+}
@@ -0,0 +1,14 @@
+package models
+
+type FileUploadResponse struct {
+	ID int64 `json:"id"`
+	// Filename is the stable link to use in posts and profiles
+	Filename string `json:"filename"`
+	// URL is where the file can be downloaded now, it expires when the bucket is private
+	URL string `json:"url"`
+	// Width and Height of images after they are turned upright
+	Width  *int `json:"width,omitempty"`
+	Height *int `json:"height,omitempty"`
+	// Variants are the stable links of the resized copies of the image by name, for srcset
+	Variants map[string]string `json:"variants,omitempty"`
+}
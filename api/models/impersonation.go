@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+type ImpersonationResponse struct {
+	UserID      int64     `json:"user_id"`
+	Email       string    `json:"email"`
+	Type        string    `json:"type"`
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
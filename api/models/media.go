@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+type Media struct {
+	ID int64 `json:"id"`
+	// URL is the stable link to use in posts and profiles
+	URL      string `json:"url"`
+	Purpose  string `json:"purpose"`
+	MimeType string `json:"mime_type"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+	Width    *int   `json:"width,omitempty"`
+	Height   *int   `json:"height,omitempty"`
+	// Variants are the links of the resized copies of the image by name
+	Variants  map[string]string `json:"variants,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+type GetMediaResponse struct {
+	Media []*Media `json:"media"`
+	Count int32    `json:"count"`
+	// Usage is how many bytes the uploads take, Quota is the limit and zero when there is none
+	Usage int64 `json:"usage"`
+	Quota int64 `json:"quota"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+type BanUserRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+type SuspendUserRequest struct {
+	Until  time.Time `json:"until" binding:"required"`
+	Reason string    `json:"reason" binding:"required,max=500"`
+}
+
+type ModerationAction struct {
+	ID             int64      `json:"id"`
+	UserID         int64      `json:"user_id"`
+	ModeratorID    *int64     `json:"moderator_id"`
+	Action         string     `json:"action"`
+	Reason         *string    `json:"reason"`
+	SuspendedUntil *time.Time `json:"suspended_until"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type GetModerationActionsResponse struct {
+	Actions []*ModerationAction `json:"actions"`
+	Count   int32               `json:"count"`
+}
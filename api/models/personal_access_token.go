@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+type PersonalAccessToken struct {
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	Scopes      []string   `json:"scopes"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastUsedIP  *string    `json:"last_used_ip"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type CreatePersonalAccessTokenRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1" example:"posts:write"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type CreatePersonalAccessTokenResponse struct {
+	PersonalAccessToken
+	// Token is shown only once, only its hash is stored
+	Token string `json:"token"`
+}
@@ -13,6 +13,25 @@ type Post struct {
 	UpdatedAt   *time.Time    `json:"updated_at"`
 	ViewsCount  int32         `json:"views_count"`
 	LikeInfo    *PostLikeInfo `json:"like_info"`
+
+	// ImageVariants are the links of the resized copies of the image by name, for srcset
+	ImageVariants map[string]string `json:"image_variants,omitempty"`
+
+	// CoverMediaID is the item of the gallery shown as image_url
+	CoverMediaID *int64       `json:"cover_media_id"`
+	Gallery      []*PostMedia `json:"gallery"`
+}
+
+type PostMedia struct {
+	MediaID  int64   `json:"media_id"`
+	URL      string  `json:"url"`
+	MimeType string  `json:"mime_type"`
+	Width    *int    `json:"width,omitempty"`
+	Height   *int    `json:"height,omitempty"`
+	Caption  *string `json:"caption"`
+	AltText  *string `json:"alt_text"`
+	// Variants are the links of the resized copies of images by name, for srcset
+	Variants map[string]string `json:"variants,omitempty"`
 }
 
 type PostLikeInfo struct {
@@ -25,6 +44,17 @@ type CreatePostRequest struct {
 	Description string  `json:"description"`
 	ImageUrl    *string `json:"image_url"`
 	CategoryID  int64   `json:"category_id"`
+
+	// Gallery is shown in this order, its uploads must belong to the author of the post.
+	// CoverMediaID picks an image of the gallery uploaded for posts and replaces image_url
+	Gallery      []*PostMediaRequest `json:"gallery" binding:"omitempty,max=50,dive"`
+	CoverMediaID *int64              `json:"cover_media_id"`
+}
+
+type PostMediaRequest struct {
+	MediaID int64   `json:"media_id" binding:"required"`
+	Caption *string `json:"caption" binding:"omitempty,max=1000"`
+	AltText *string `json:"alt_text" binding:"omitempty,max=1000"`
 }
 
 type GetPostsParams struct {
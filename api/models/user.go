@@ -14,7 +14,16 @@ type User struct {
 	Username        *string   `json:"username"`
 	ProfileImageUrl *string   `json:"profile_image_url"`
 	Type            string    `json:"type"`
+	Language        *string   `json:"language"`
 	CreatedAt       time.Time `json:"created_at"`
+
+	// ProfileImageVariants are the links of the resized copies of the profile image by name
+	ProfileImageVariants map[string]string `json:"profile_image_variants,omitempty"`
+
+	Banned         bool       `json:"banned,omitempty"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+	// DeletionScheduledAt is only shown to the owner of the account
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
 }
 
 type CreateUserRequest struct {
@@ -23,10 +32,45 @@ type CreateUserRequest struct {
 	PhoneNumber     *string `json:"phone_number"`
 	Email           string  `json:"email" binding:"required,email"`
 	Gender          *string `json:"gender" binding:"oneof=male female"`
-	Password        string  `json:"password" binding:"required,min=6,max=16"`
+	Password        string  `json:"password" binding:"required"`
 	Username        *string `json:"username"`
 	ProfileImageUrl *string `json:"profile_image_url"`
 	Type            string  `json:"type" binding:"required,oneof=superadmin user"`
+	Language        *string `json:"language" binding:"omitempty,oneof=uz ru en"`
+}
+
+// UpdateUserRequest has no email, it is changed with a confirmation via ChangeEmailRequest.
+// The password is kept when it is empty
+type UpdateUserRequest struct {
+	FirstName       string  `json:"first_name" binding:"required,min=2,max=30"`
+	LastName        string  `json:"last_name" binding:"required,min=2,max=30"`
+	PhoneNumber     *string `json:"phone_number"`
+	Gender          *string `json:"gender" binding:"oneof=male female"`
+	Password        string  `json:"password"`
+	Username        *string `json:"username"`
+	ProfileImageUrl *string `json:"profile_image_url"`
+	Type            string  `json:"type" binding:"required,oneof=superadmin user"`
+	Language        *string `json:"language" binding:"omitempty,oneof=uz ru en"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email,max=50"`
+	Password string `json:"password" binding:"required"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// EmailChangeUndo describes the change the undo link reverts, nothing is changed until it is posted
+type EmailChangeUndo struct {
+	OldEmail  string    `json:"old_email"`
+	NewEmail  string    `json:"new_email"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type UndoEmailChangeRequest struct {
+	Token string `json:"token" binding:"required"`
 }
 
 type GetUsersResponse struct {
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Webhook struct {
+	ID        int64      `json:"id"`
+	URL       string     `json:"url"`
+	Events    []string   `json:"events"`
+	Active    bool       `json:"active"`
+	CreatedBy *int64     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+// CreateWebhookRequest also updates webhooks, an empty secret or active keeps the current one
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required,url,max=2048" example:"https://example.com/hooks/blog"`
+	Events []string `json:"events" binding:"required,min=1,dive,oneof=post.created post.updated post.deleted comment.created" example:"post.created"`
+	// Secret signs the deliveries, a random one is generated for new webhooks when it is empty
+	Secret string `json:"secret" binding:"omitempty,min=16,max=256"`
+	Active *bool  `json:"active"`
+}
+
+type CreateWebhookResponse struct {
+	Webhook
+	// Secret is shown only once
+	Secret string `json:"secret"`
+}
+
+type GetWebhooksResponse struct {
+	Webhooks []*Webhook `json:"webhooks"`
+	Count    int32      `json:"count"`
+}
+
+type WebhookDelivery struct {
+	ID            int64           `json:"id"`
+	WebhookID     int64           `json:"webhook_id"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload" swaggertype:"object"`
+	Status        string          `json:"status"`
+	Attempts      int32           `json:"attempts"`
+	ResponseCode  *int            `json:"response_code"`
+	LastError     *string         `json:"last_error"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at"`
+}
+
+type GetWebhookDeliveriesParams struct {
+	Limit  int32  `json:"limit" binding:"required" default:"10"`
+	Page   int32  `json:"page" binding:"required" default:"1"`
+	Status string `json:"status" enums:"pending,delivered,dead"`
+}
+
+type GetWebhookDeliveriesResponse struct {
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+	Count      int32              `json:"count"`
+}
+
+// WebhookPost is the data of post events, only the ID and the URL are sent for deleted posts
+type WebhookPost struct {
+	ID         int64  `json:"id"`
+	Title      string `json:"title,omitempty"`
+	UserID     int64  `json:"user_id,omitempty"`
+	CategoryID int64  `json:"category_id,omitempty"`
+	URL        string `json:"url"`
+}
+
+// WebhookComment is the data of comment events
+type WebhookComment struct {
+	ID          int64     `json:"id"`
+	PostID      int64     `json:"post_id"`
+	UserID      int64     `json:"user_id"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	PostURL     string    `json:"post_url"`
+}
+
+// WebhookPing is the data of test events
+type WebhookPing struct {
+	WebhookID int64  `json:"webhook_id"`
+	Message   string `json:"message"`
+}
@@ -0,0 +1,192 @@
+package v1
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ibrat-muslim/blog-app/api/models"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+var ErrNoAccountDeletion = errors.New("account deletion has not been requested")
+
+// @Security ApiKeyAuth
+// @Router /users/me/export [get]
+// @Summary Export my data
+// @Description Downloads a ZIP archive with the profile, posts, comments, likes and media once it is ready, until then the archive is built in the background
+// @Tags user
+// @Produce json
+// @Produce application/zip
+// @Success 200 {file} binary
+// @Success 202 {object} models.DataExport
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) ExportData(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	export, err := h.storage.DataExport().GetLatest(payload.UserID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err == nil {
+		switch export.Status {
+		case repo.DataExportStatusPending:
+			ctx.JSON(http.StatusAccepted, parseDataExportToModel(export))
+			return
+		case repo.DataExportStatusReady:
+			if export.ExpiresAt != nil && export.ExpiresAt.After(time.Now()) && exportFileExists(export.FilePath) {
+				ctx.FileAttachment(*export.FilePath, fmt.Sprintf("blog-export-%s.zip", export.CreatedAt.Format("2006-01-02")))
+				return
+			}
+		}
+	}
+
+	// A failed or expired export is requested again
+	export, err = h.storage.DataExport().Create(&repo.DataExport{
+		ID:     uuid.NewString(),
+		UserID: payload.UserID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, parseDataExportToModel(export))
+}
+
+func exportFileExists(filePath *string) bool {
+	if filePath == nil {
+		return false
+	}
+
+	_, err := os.Stat(*filePath)
+	return err == nil
+}
+
+func parseDataExportToModel(export *repo.DataExport) models.DataExport {
+	return models.DataExport{
+		ID:          export.ID,
+		Status:      export.Status,
+		CreatedAt:   export.CreatedAt,
+		CompletedAt: export.CompletedAt,
+		ExpiresAt:   export.ExpiresAt,
+	}
+}
+
+// @Security ApiKeyAuth
+// @Router /users/me [delete]
+// @Summary Delete my account
+// @Description Schedules the account for deletion after the grace period, it can be cancelled until then
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param data body models.DeleteAccountRequest true "Data"
+// @Success 202 {object} models.AccountDeletion
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) DeleteAccount(ctx *gin.Context) {
+	var req models.DeleteAccountRequest
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err := h.storage.User().Get(payload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.Email == repo.DeletedUserEmail {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrForbidden))
+		return
+	}
+
+	if h.abortIfBlocked(ctx, h.ipLimiter, ctx.ClientIP()) ||
+		h.abortIfBlocked(ctx, h.accountLimiter, user.Email) {
+		return
+	}
+
+	err = h.checkPassword(user, req.Password)
+	if err != nil {
+		err = h.registerLoginFailure(ctx, user.Email, user)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrIncorrectPassword))
+		return
+	}
+
+	deletionScheduledAt := time.Now().Add(h.cfg.AccountDeletion.GracePeriod)
+
+	err = h.storage.User().ScheduleDeletion(user.ID, &deletionScheduledAt)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, models.AccountDeletion{
+		DeletionScheduledAt: deletionScheduledAt,
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /users/me/deletion/cancel [post]
+// @Summary Cancel account deletion
+// @Description Cancels the scheduled deletion of the account
+// @Tags user
+// @Produce json
+// @Success 200 {object} models.OKResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) CancelAccountDeletion(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err := h.storage.User().Get(payload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.DeletionScheduledAt == nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrNoAccountDeletion))
+		return
+	}
+
+	err = h.storage.User().ScheduleDeletion(user.ID, nil)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.OKResponse{
+		Message: "Account deletion has been cancelled",
+	})
+}
@@ -0,0 +1,258 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ibrat-muslim/blog-app/api/models"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	requestIDKey    = "request_id"
+	auditRecordKey  = "audit_record"
+)
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+var mutatingMethods = map[string]bool{
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+type auditRecord struct {
+	action     string
+	targetType string
+	targetID   string
+	before     interface{}
+	after      interface{}
+}
+
+// RequestID tags every request with an ID, a well-formed one sent by a proxy is kept
+func RequestID(c *gin.Context) {
+	id := c.GetHeader(requestIDHeader)
+	if !requestIDPattern.MatchString(id) {
+		id = uuid.NewString()
+	}
+
+	c.Set(requestIDKey, id)
+	c.Header(requestIDHeader, id)
+	c.Next()
+}
+
+// audit describes the change made by the handler, AuditMiddleware writes it if the request succeeds.
+// before is nil for created targets and after is nil for deleted ones
+func audit(ctx *gin.Context, action, targetType string, targetID int64, before, after interface{}) {
+	ctx.Set(auditRecordKey, &auditRecord{
+		action:     action,
+		targetType: targetType,
+		targetID:   strconv.FormatInt(targetID, 10),
+		before:     before,
+		after:      after,
+	})
+}
+
+// AuditMiddleware writes the changes described by the handlers to the audit log,
+// any other successful mutating request of an authenticated user is logged with the route as the action.
+// Every request made while impersonating a user is logged, reads included
+func (h *handlerV1) AuditMiddleware(c *gin.Context) {
+	c.Next()
+
+	if c.Writer.Status() >= http.StatusBadRequest {
+		return
+	}
+
+	var actorID, impersonatorID *int64
+
+	payload, err := h.GetAuthPayload(c)
+	if err == nil {
+		actorID = &payload.UserID
+		if payload.Impersonated() {
+			impersonatorID = &payload.ImpersonatorID
+		}
+	}
+
+	if !mutatingMethods[c.Request.Method] && impersonatorID == nil {
+		return
+	}
+
+	entry := &repo.AuditLog{
+		ActorID:        actorID,
+		ImpersonatorID: impersonatorID,
+		IP:             c.ClientIP(),
+		RequestID:      c.GetString(requestIDKey),
+	}
+
+	if value, ok := c.Get(auditRecordKey); ok {
+		record := value.(*auditRecord)
+
+		entry.Action = record.action
+		entry.TargetType = &record.targetType
+		entry.TargetID = &record.targetID
+
+		entry.Before, err = marshalAuditState(record.before)
+		if err == nil {
+			entry.After, err = marshalAuditState(record.after)
+		}
+		if err != nil {
+			log.Printf("failed to encode audit log entry %s: %v", entry.Action, err)
+			return
+		}
+	} else {
+		if payload == nil {
+			return
+		}
+
+		entry.Action = c.Request.Method + " " + c.FullPath()
+		if id := c.Param("id"); id != "" {
+			entry.TargetID = &id
+		} else if selfRoute(c.FullPath()) {
+			targetType, targetID := "user", strconv.FormatInt(payload.UserID, 10)
+			entry.TargetType = &targetType
+			entry.TargetID = &targetID
+		}
+	}
+
+	_, err = h.storage.AuditLog().Create(entry)
+	if err != nil {
+		log.Printf("failed to write audit log entry %s of request %s: %v", entry.Action, entry.RequestID, err)
+	}
+}
+
+// selfRoute reports whether the route changes the account of the caller
+func selfRoute(path string) bool {
+	return strings.HasPrefix(path, "/v1/users/me") || strings.HasPrefix(path, "/v1/auth/")
+}
+
+func marshalAuditState(state interface{}) ([]byte, error) {
+	if state == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(state)
+}
+
+// @Security ApiKeyAuth
+// @Router /admin/audit-log [get]
+// @Summary Get audit log
+// @Description Get the changes made through the API with the actor, the target and, when known, the state before and after them
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param filter query models.GetAuditLogParams false "Filter"
+// @Success 200 {object} models.GetAuditLogResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetAuditLog(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if payload.UserType != repo.UserTypeSuperAdmin {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrForbidden))
+		return
+	}
+
+	params, err := validateGetAuditLogParams(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	result, err := h.storage.AuditLog().GetAll(params)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := models.GetAuditLogResponse{
+		Entries: make([]*models.AuditLog, 0),
+		Count:   result.Count,
+	}
+
+	for _, entry := range result.Entries {
+		response.Entries = append(response.Entries, &models.AuditLog{
+			ID:             entry.ID,
+			ActorID:        entry.ActorID,
+			ImpersonatorID: entry.ImpersonatorID,
+			Action:         entry.Action,
+			TargetType:     entry.TargetType,
+			TargetID:       entry.TargetID,
+			Before:         entry.Before,
+			After:          entry.After,
+			IP:             entry.IP,
+			RequestID:      entry.RequestID,
+			CreatedAt:      entry.CreatedAt,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func validateGetAuditLogParams(ctx *gin.Context) (*repo.GetAuditLogParams, error) {
+	request, err := validateGetAllParamsRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	params := &repo.GetAuditLogParams{
+		Limit:      request.Limit,
+		Page:       request.Page,
+		TargetType: ctx.Query("target_type"),
+		TargetID:   ctx.Query("target_id"),
+	}
+
+	if ctx.Query("actor_id") != "" {
+		params.ActorID, err = strconv.ParseInt(ctx.Query("actor_id"), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if ctx.Query("impersonator_id") != "" {
+		params.ImpersonatorID, err = strconv.ParseInt(ctx.Query("impersonator_id"), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	params.From, err = parseTimeQuery(ctx, "from")
+	if err != nil {
+		return nil, err
+	}
+
+	params.To, err = parseTimeQuery(ctx, "to")
+	if err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
+
+func parseTimeQuery(ctx *gin.Context, name string) (*time.Time, error) {
+	value := ctx.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC 3339 time: %w", name, err)
+	}
+
+	return &t, nil
+}
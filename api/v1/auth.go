@@ -4,11 +4,11 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/ibrat-muslim/blog-app/api/models"
 	emailPkg "github.com/ibrat-muslim/blog-app/pkg/email"
 	"github.com/ibrat-muslim/blog-app/pkg/utils"
@@ -16,8 +16,16 @@ import (
 )
 
 const (
-	RegisterCodeKey   = "register_code_"
-	ForgotPasswordKey = "forgot_password_code_"
+	RegisterCodeKey    = "register_code_"
+	ForgotPasswordKey  = "forgot_password_code_"
+	EmailChangeCodeKey = "email_change_code_"
+)
+
+const (
+	// verificationCodeTTL leaves the outbox worker room to retry a failed delivery
+	verificationCodeTTL        = 10 * time.Minute
+	accessTokenDuration        = 24 * time.Hour
+	resetPasswordTokenDuration = 30 * time.Minute
 )
 
 // @Router /auth/register [post]
@@ -29,6 +37,7 @@ const (
 // @Param data body models.RegisterRequest true "Data"
 // @Success 201 {object} models.OKResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) Register(ctx *gin.Context) {
 
@@ -46,18 +55,27 @@ func (h *handlerV1) Register(ctx *gin.Context) {
 		return
 	}
 
-	hashedPassword, err := utils.HashPassword(req.Password)
+	err = h.passwords.Validate(req.Password, req.Email, req.FirstName, req.LastName)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hashedPassword, err := h.passwords.Hash(req.Password)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
+	locale := emailPkg.MatchLocale(req.Language, ctx.GetHeader("Accept-Language"))
+
 	user := &repo.User{
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
 		Email:     req.Email,
 		Password:  hashedPassword,
 		Type:      repo.UserTypeUser,
+		Language:  &locale,
 	}
 
 	userData, err := json.Marshal(user)
@@ -72,37 +90,63 @@ func (h *handlerV1) Register(ctx *gin.Context) {
 		return
 	}
 
-	go func() {
-		err := h.sendVerificationCode(RegisterCodeKey, req.Email)
-		if err != nil {
-			fmt.Printf("failed to send verification code: %v", err)
+	err = h.sendVerificationCode(RegisterCodeKey, req.Email, locale)
+	if err != nil {
+		if errors.Is(err, ErrResendCooldown) {
+			ctx.JSON(http.StatusTooManyRequests, errorResponse(err))
+			return
 		}
-	}()
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
 
 	ctx.JSON(http.StatusCreated, models.OKResponse{
 		Message: "Verification code has been sent!",
 	})
 }
 
-func (h *handlerV1) sendVerificationCode(key, email string) error {
+func (h *handlerV1) sendVerificationCode(key, email, locale string) error {
+	ok, err := h.inMemory.SetNX(resendCooldownKey+key+email, "1", h.cfg.BruteForce.ResendCooldown)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return ErrResendCooldown
+	}
+
 	code, err := utils.GenerateRandomCode(6)
 	if err != nil {
 		return err
 	}
 
-	err = h.inMemory.Set(key+email, code, time.Minute)
+	err = h.inMemory.Set(key+email, code, verificationCodeTTL)
 	if err != nil {
 		return err
 	}
 
-	err = emailPkg.SendEmail(h.cfg, &emailPkg.SendEmailRequest{
-		To:      []string{email},
-		Subject: "Verification email",
+	// A new code gets a fresh budget of attempts
+	err = h.codeLimiter.Reset(key + email)
+	if err != nil {
+		return err
+	}
+
+	emailType := emailPkg.VerificationEmail
+	switch key {
+	case ForgotPasswordKey:
+		emailType = emailPkg.ForgotPasswordEmail
+	case EmailChangeCodeKey:
+		emailType = emailPkg.EmailChangeEmail
+	}
+
+	err = h.enqueueEmail(&emailPkg.SendEmailRequest{
+		To: []string{email},
 		Body: map[string]string{
 			"code": code,
 		},
-		Type: emailPkg.VerificationEmail,
-	})
+		Type:   emailType,
+		Locale: locale,
+	}, verificationCodeTTL)
 	if err != nil {
 		return err
 	}
@@ -121,6 +165,7 @@ func (h *handlerV1) sendVerificationCode(key, email string) error {
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 403 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) Verify(ctx *gin.Context) {
 
@@ -145,14 +190,7 @@ func (h *handlerV1) Verify(ctx *gin.Context) {
 		return
 	}
 
-	code, err := h.inMemory.Get(RegisterCodeKey + user.Email)
-	if err != nil {
-		ctx.JSON(http.StatusForbidden, errorResponse(ErrCodeExpired))
-		return
-	}
-
-	if req.Code != code {
-		ctx.JSON(http.StatusForbidden, errorResponse(ErrIncorrectCode))
+	if !h.checkVerificationCode(ctx, RegisterCodeKey, user.Email, req.Code) {
 		return
 	}
 
@@ -162,26 +200,13 @@ func (h *handlerV1) Verify(ctx *gin.Context) {
 		return
 	}
 
-	token, _, err := utils.CreateToken(h.cfg, &utils.TokenParams{
-		UserID:   result.ID,
-		UserType: result.Type,
-		Email:    result.Email,
-		Duration: time.Hour * 24,
-	})
+	response, err := h.createAuthResponse(ctx, result, accessTokenDuration, false)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, models.AuthResponse{
-		ID:          result.ID,
-		FirstName:   result.FirstName,
-		LastName:    result.LastName,
-		Email:       result.Email,
-		Type:        result.Type,
-		CreatedAt:   result.CreatedAt,
-		AccessToken: token,
-	})
+	ctx.JSON(http.StatusCreated, response)
 }
 
 // @Router /auth/login [post]
@@ -192,8 +217,10 @@ func (h *handlerV1) Verify(ctx *gin.Context) {
 // @Produce json
 // @Param data body models.LoginRequest true "Data"
 // @Success 201 {object} models.AuthResponse
+// @Success 202 {object} models.TwoFactorChallengeResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 403 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) Login(ctx *gin.Context) {
 
@@ -205,9 +232,20 @@ func (h *handlerV1) Login(ctx *gin.Context) {
 		return
 	}
 
+	if h.abortIfBlocked(ctx, h.ipLimiter, ctx.ClientIP()) ||
+		h.abortIfBlocked(ctx, h.accountLimiter, req.Email) {
+		return
+	}
+
 	result, err := h.storage.User().GetByEmail(req.Email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
+			err = h.registerLoginFailure(ctx, req.Email, nil)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+
 			ctx.JSON(http.StatusForbidden, errorResponse(ErrWrongEmailOrPass))
 			return
 		}
@@ -216,32 +254,40 @@ func (h *handlerV1) Login(ctx *gin.Context) {
 		return
 	}
 
-	err = utils.CheckPassword(req.Password, result.Password)
+	err = h.checkPassword(result, req.Password)
 	if err != nil {
+		err = h.registerLoginFailure(ctx, req.Email, result)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
 		ctx.JSON(http.StatusForbidden, errorResponse(ErrWrongEmailOrPass))
 		return
 	}
 
-	token, _, err := utils.CreateToken(h.cfg, &utils.TokenParams{
-		UserID:   result.ID,
-		UserType: result.Type,
-		Email:    result.Email,
-		Duration: time.Hour * 24,
-	})
+	err = h.accountLimiter.Reset(req.Email)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, models.AuthResponse{
-		ID:          result.ID,
-		FirstName:   result.FirstName,
-		LastName:    result.LastName,
-		Email:       result.Email,
-		Type:        result.Type,
-		CreatedAt:   result.CreatedAt,
-		AccessToken: token,
-	})
+	if h.abortIfRestricted(ctx, result) {
+		return
+	}
+
+	if result.TotpEnabled {
+		h.startTwoFactorChallenge(ctx, result, accessTokenDuration)
+		return
+	}
+
+	response, err := h.createAuthResponse(ctx, result, accessTokenDuration, false)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, response)
 }
 
 // @Router /auth/forgot-password [post]
@@ -254,6 +300,7 @@ func (h *handlerV1) Login(ctx *gin.Context) {
 // @Success 201 {object} models.OKResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) ForgotPassword(ctx *gin.Context) {
 
@@ -265,7 +312,7 @@ func (h *handlerV1) ForgotPassword(ctx *gin.Context) {
 		return
 	}
 
-	_, err = h.storage.User().GetByEmail(req.Email)
+	user, err := h.storage.User().GetByEmail(req.Email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			ctx.JSON(http.StatusNotFound, errorResponse(ErrEmailExists))
@@ -275,12 +322,15 @@ func (h *handlerV1) ForgotPassword(ctx *gin.Context) {
 		return
 	}
 
-	go func() {
-		err := h.sendVerificationCode(ForgotPasswordKey, req.Email)
-		if err != nil {
-			fmt.Printf("failed to send verification code: %v", err)
+	err = h.sendVerificationCode(ForgotPasswordKey, req.Email, userLocale(ctx, user))
+	if err != nil {
+		if errors.Is(err, ErrResendCooldown) {
+			ctx.JSON(http.StatusTooManyRequests, errorResponse(err))
+			return
 		}
-	}()
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
 
 	ctx.JSON(http.StatusCreated, models.OKResponse{
 		Message: "Verification code has been sent!",
@@ -295,8 +345,10 @@ func (h *handlerV1) ForgotPassword(ctx *gin.Context) {
 // @Produce json
 // @Param data body models.VerifyRequest true "Data"
 // @Success 201 {object} models.AuthResponse
+// @Success 202 {object} models.TwoFactorChallengeResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 403 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) VerifyForgotPassword(ctx *gin.Context) {
 
@@ -308,54 +360,43 @@ func (h *handlerV1) VerifyForgotPassword(ctx *gin.Context) {
 		return
 	}
 
-	code, err := h.inMemory.Get(ForgotPasswordKey + req.Email)
+	if !h.checkVerificationCode(ctx, ForgotPasswordKey, req.Email, req.Code) {
+		return
+	}
+
+	result, err := h.storage.User().GetByEmail(req.Email)
 	if err != nil {
-		ctx.JSON(http.StatusForbidden, errorResponse(ErrCodeExpired))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	if req.Code != code {
-		ctx.JSON(http.StatusForbidden, errorResponse(ErrIncorrectCode))
+	if h.abortIfRestricted(ctx, result) {
 		return
 	}
 
-	result, err := h.storage.User().GetByEmail(req.Email)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	if result.TotpEnabled {
+		h.startTwoFactorChallenge(ctx, result, resetPasswordTokenDuration)
 		return
 	}
 
-	token, _, err := utils.CreateToken(h.cfg, &utils.TokenParams{
-		UserID:   result.ID,
-		UserType: result.Type,
-		Email:    result.Email,
-		Duration: time.Minute * 30,
-	})
+	response, err := h.createAuthResponse(ctx, result, resetPasswordTokenDuration, false)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, models.AuthResponse{
-		ID:          result.ID,
-		FirstName:   result.FirstName,
-		LastName:    result.LastName,
-		Email:       result.Email,
-		Type:        result.Type,
-		CreatedAt:   result.CreatedAt,
-		AccessToken: token,
-	})
+	ctx.JSON(http.StatusCreated, response)
 }
 
 // @Security ApiKeyAuth
 // @Router /auth/update-password [post]
 // @Summary Update password
-// @Description Update password
+// @Description Updates the password and ends every session, a token of a new session is returned
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param data body models.UpdatePasswordRequest true "Data"
-// @Success 200 {object} models.OKResponse
+// @Success 200 {object} models.AuthResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) UpdatePassword(ctx *gin.Context) {
@@ -374,7 +415,19 @@ func (h *handlerV1) UpdatePassword(ctx *gin.Context) {
 		return
 	}
 
-	hashedPassword, err := utils.HashPassword(req.Password)
+	user, err := h.storage.User().Get(payload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.passwords.Validate(req.Password, personalData(user)...)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hashedPassword, err := h.passwords.Hash(req.Password)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
@@ -389,7 +442,59 @@ func (h *handlerV1) UpdatePassword(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, models.OKResponse{
-		Message: "Password has been updated",
+	// Whoever knew the old password is logged out everywhere, this device included
+	err = h.revokeSessions(payload.UserID, uuid.Nil)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// The caller stays logged in with a new session
+	response, err := h.createAuthResponse(ctx, user, accessTokenDuration, payload.TwoFactor)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// userLocale prefers the saved language of the user over the Accept-Language header
+func userLocale(ctx *gin.Context, user *repo.User) string {
+	language := ""
+	if user.Language != nil {
+		language = *user.Language
+	}
+
+	return emailPkg.MatchLocale(language, ctx.GetHeader("Accept-Language"))
+}
+
+// createAuthResponse starts a session on the requesting device and issues an access token for it
+func (h *handlerV1) createAuthResponse(ctx *gin.Context, user *repo.User, duration time.Duration, twoFactor bool) (*models.AuthResponse, error) {
+	sessionID, err := h.createSession(ctx, user, duration)
+	if err != nil {
+		return nil, err
+	}
+
+	token, _, err := utils.CreateToken(h.keys, &utils.TokenParams{
+		UserID:    user.ID,
+		UserType:  user.Type,
+		Email:     user.Email,
+		Duration:  duration,
+		TwoFactor: twoFactor,
+		SessionID: sessionID,
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuthResponse{
+		ID:          user.ID,
+		FirstName:   user.FirstName,
+		LastName:    user.LastName,
+		Email:       user.Email,
+		Type:        user.Type,
+		CreatedAt:   user.CreatedAt,
+		AccessToken: token,
+	}, nil
 }
@@ -0,0 +1,131 @@
+package v1
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	emailPkg "github.com/ibrat-muslim/blog-app/pkg/email"
+	"github.com/ibrat-muslim/blog-app/pkg/limiter"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+const resendCooldownKey = "verification_code_cooldown_"
+
+// abortIfBlocked responds with 429 while the identifier is delayed or locked out
+func (h *handlerV1) abortIfBlocked(ctx *gin.Context, l *limiter.Limiter, id string) bool {
+	retryAfter, err := l.Blocked(id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return true
+	}
+
+	if retryAfter > 0 {
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		ctx.JSON(http.StatusTooManyRequests, errorResponse(ErrTooManyAttempts))
+		return true
+	}
+
+	return false
+}
+
+// registerLoginFailure counts a wrong password for both the account and the client ip,
+// user is nil when the email is not registered
+func (h *handlerV1) registerLoginFailure(ctx *gin.Context, email string, user *repo.User) error {
+	ip := ctx.ClientIP()
+
+	ipResult, err := h.ipLimiter.Fail(ip)
+	if err != nil {
+		return err
+	}
+
+	if ipResult.Locked {
+		log.Printf("ip %s has been locked out for %s after too many failed attempts", ip, ipResult.RetryAfter)
+	}
+
+	accountResult, err := h.accountLimiter.Fail(email)
+	if err != nil {
+		return err
+	}
+
+	if !accountResult.Locked {
+		return nil
+	}
+
+	log.Printf("account %s has been locked out for %s after %d failed attempts from ip %s",
+		email, accountResult.RetryAfter, accountResult.Attempts, ip)
+
+	if user == nil || !h.cfg.BruteForce.NotifyLockout {
+		return nil
+	}
+
+	return h.enqueueEmail(&emailPkg.SendEmailRequest{
+		To: []string{user.Email},
+		Body: map[string]string{
+			"ip":      ip,
+			"minutes": strconv.Itoa(int(accountResult.RetryAfter / time.Minute)),
+		},
+		Type:   emailPkg.AccountLockedEmail,
+		Locale: userLocale(ctx, user),
+	}, 0)
+}
+
+// checkVerificationCode compares the code with the stored one and invalidates it
+// after too many wrong guesses, it responds itself and returns false on failure
+func (h *handlerV1) checkVerificationCode(ctx *gin.Context, key, email, code string) bool {
+	ip := ctx.ClientIP()
+
+	if h.abortIfBlocked(ctx, h.ipLimiter, ip) {
+		return false
+	}
+
+	storedCode, err := h.inMemory.Get(key + email)
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrCodeExpired))
+		return false
+	}
+
+	if code == storedCode {
+		err = h.inMemory.Del(key + email)
+		if err == nil {
+			err = h.codeLimiter.Reset(key + email)
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return false
+		}
+
+		return true
+	}
+
+	_, err = h.ipLimiter.Fail(ip)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	result, err := h.codeLimiter.Fail(key + email)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	if result.Locked {
+		log.Printf("verification code for %s has been invalidated after %d incorrect attempts", email, result.Attempts)
+
+		err = h.inMemory.Del(key + email)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return false
+		}
+
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrCodeInvalidated))
+		return false
+	}
+
+	ctx.JSON(http.StatusForbidden, errorResponse(ErrIncorrectCode))
+	return false
+}
@@ -0,0 +1,82 @@
+package v1
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cachedDocument keeps the time of the latest change for Last-Modified
+type cachedDocument struct {
+	Body    []byte    `json:"body"`
+	Updated time.Time `json:"updated"`
+}
+
+// serveCached serves the document cached under the key or builds and caches it,
+// conditional requests are answered with 304. The document is not found when build returns sql.ErrNoRows
+func (h *handlerV1) serveCached(ctx *gin.Context, key string, ttl time.Duration, contentType string, build func() ([]byte, time.Time, error)) {
+	var cached cachedDocument
+
+	data, err := h.inMemory.Get(key)
+	if err == nil {
+		err = json.Unmarshal([]byte(data), &cached)
+	}
+	if err != nil {
+		cached.Body, cached.Updated, err = build()
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				ctx.JSON(http.StatusNotFound, errorResponse(err))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		// A document that can not be cached is still served
+		encoded, err := json.Marshal(cached)
+		if err == nil {
+			_ = h.inMemory.Set(key, string(encoded), ttl)
+		}
+	}
+
+	sum := sha256.Sum256(cached.Body)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	lastModified := cached.Updated.UTC().Truncate(time.Second)
+
+	ctx.Header("ETag", etag)
+	ctx.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+
+	if notModified(ctx.Request, etag, lastModified) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	ctx.Data(http.StatusOK, contentType, cached.Body)
+}
+
+// notModified follows RFC 7232, If-Modified-Since is ignored when If-None-Match is sent
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+
+	return !lastModified.After(since)
+}
@@ -50,11 +50,19 @@ func (h *handlerV1) CreateCategory(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, models.Category{
-		ID:        resp.ID,
-		Title:     resp.Title,
-		CreatedAt: resp.CreatedAt,
-	})
+	category := parseCategoryToModel(resp)
+
+	audit(ctx, "category.create", "category", category.ID, nil, category)
+
+	ctx.JSON(http.StatusCreated, category)
+}
+
+func parseCategoryToModel(category *repo.Category) models.Category {
+	return models.Category{
+		ID:        category.ID,
+		Title:     category.Title,
+		CreatedAt: category.CreatedAt,
+	}
 }
 
 // @Router /categories/{id} [get]
@@ -181,10 +189,7 @@ func (h *handlerV1) UpdateCategory(ctx *gin.Context) {
 		return
 	}
 
-	err = h.storage.Category().Update(&repo.Category{
-		ID:    id,
-		Title: req.Title,
-	})
+	category, err := h.storage.Category().Get(id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
@@ -194,6 +199,21 @@ func (h *handlerV1) UpdateCategory(ctx *gin.Context) {
 		return
 	}
 
+	before := parseCategoryToModel(category)
+	category.Title = req.Title
+
+	err = h.storage.Category().Update(category)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	audit(ctx, "category.update", "category", id, before, parseCategoryToModel(category))
+
 	ctx.JSON(http.StatusOK, models.OKResponse{
 		Message: "successfully updated",
 	})
@@ -231,6 +251,16 @@ func (h *handlerV1) DeleteCategory(ctx *gin.Context) {
 		return
 	}
 
+	category, err := h.storage.Category().Get(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
 	err = h.storage.Category().Delete(id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -241,6 +271,8 @@ func (h *handlerV1) DeleteCategory(ctx *gin.Context) {
 		return
 	}
 
+	audit(ctx, "category.delete", "category", id, parseCategoryToModel(category), nil)
+
 	ctx.JSON(http.StatusOK, models.OKResponse{
 		Message: "successfully deleted",
 	})
@@ -9,6 +9,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/ibrat-muslim/blog-app/api/models"
+	"github.com/ibrat-muslim/blog-app/pkg/upload"
 	"github.com/ibrat-muslim/blog-app/storage/repo"
 )
 
@@ -49,6 +50,15 @@ func (h *handlerV1) CreateComment(ctx *gin.Context) {
 		return
 	}
 
+	h.dispatchWebhook(repo.WebhookEventCommentCreated, models.WebhookComment{
+		ID:          resp.ID,
+		PostID:      resp.PostID,
+		UserID:      resp.UserID,
+		Description: resp.Description,
+		CreatedAt:   resp.CreatedAt,
+		PostURL:     h.postURL(resp.PostID),
+	})
+
 	ctx.JSON(http.StatusCreated, parseCommentToModel(resp))
 }
 
@@ -113,21 +123,60 @@ func (h *handlerV1) GetComments(ctx *gin.Context) {
 		return
 	}
 
+	result, err := h.storage.Comment().GetAll(&repo.GetCommentsParams{
+		Limit:      request.Limit,
+		Page:       request.Page,
+		PostID:     request.PostID,
+		UserID:     request.UserID,
+		HideBanned: h.cfg.Moderation.HideBannedContent,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, getCommentsResponse(h, result))
+}
+
+// @Security ApiKeyAuth
+// @Router /users/me/comments [get]
+// @Summary Get my comments
+// @Description Get the comments of the current user, personal access tokens need the comments:read scope
+// @Tags comment
+// @Accept json
+// @Produce json
+// @Param filter query models.GetCommentsParams false "Filter"
+// @Success 200 {object} models.GetCommentsResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetMyComments(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	request, err := validateGetCommentsParams(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
 	result, err := h.storage.Comment().GetAll(&repo.GetCommentsParams{
 		Limit:  request.Limit,
 		Page:   request.Page,
 		PostID: request.PostID,
-		UserID: request.UserID,
+		UserID: payload.UserID,
 	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, getCommentsResponse(result))
+	ctx.JSON(http.StatusOK, getCommentsResponse(h, result))
 }
 
-func getCommentsResponse(data *repo.GetCommentsResult) *models.GetCommentsResponse {
+func getCommentsResponse(h *handlerV1, data *repo.GetCommentsResult) *models.GetCommentsResponse {
 	response := models.GetCommentsResponse{
 		Comments: make([]*models.Comment, 0),
 		Count:    data.Count,
@@ -143,6 +192,7 @@ func getCommentsResponse(data *repo.GetCommentsResult) *models.GetCommentsRespon
 			Email:           comment.User.Email,
 			ProfileImageUrl: comment.User.ProfileImageUrl,
 		}
+		c.User.ProfileImageVariants = h.imageVariants(c.User.ProfileImageUrl, upload.PurposeAvatar)
 
 		response.Comments = append(response.Comments, &c)
 	}
@@ -0,0 +1,368 @@
+package v1
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ibrat-muslim/blog-app/api/models"
+	emailPkg "github.com/ibrat-muslim/blog-app/pkg/email"
+	"github.com/ibrat-muslim/blog-app/pkg/utils"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+const (
+	emailChangeKey     = "email_change_"
+	emailChangeUndoKey = "email_change_undo_used_"
+)
+
+var (
+	ErrIncorrectPassword  = errors.New("incorrect password")
+	ErrEmailUnchanged     = errors.New("new email is the same as the current one")
+	ErrNoEmailChange      = errors.New("there is no pending email change")
+	ErrEmailChangeUndone  = errors.New("email change has already been undone")
+	ErrPreviousEmailTaken = errors.New("previous email is used by another account")
+)
+
+// @Security ApiKeyAuth
+// @Router /users/me/email [post]
+// @Summary Change email
+// @Description Sends a confirmation code to the new email and an undo link to the current one, the email changes after confirmation
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param data body models.ChangeEmailRequest true "Data"
+// @Success 200 {object} models.OKResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) ChangeEmail(ctx *gin.Context) {
+	var req models.ChangeEmailRequest
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err := h.storage.User().Get(payload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if h.abortIfBlocked(ctx, h.ipLimiter, ctx.ClientIP()) ||
+		h.abortIfBlocked(ctx, h.accountLimiter, user.Email) {
+		return
+	}
+
+	// A stolen session alone must not be enough to take the account over
+	err = h.checkPassword(user, req.Password)
+	if err != nil {
+		err = h.registerLoginFailure(ctx, user.Email, user)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrIncorrectPassword))
+		return
+	}
+
+	if strings.EqualFold(req.NewEmail, user.Email) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrEmailUnchanged))
+		return
+	}
+
+	_, err = h.storage.User().GetByEmail(req.NewEmail)
+	if !errors.Is(err, sql.ErrNoRows) {
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrEmailExists))
+		return
+	}
+
+	err = h.sendVerificationCode(EmailChangeCodeKey, req.NewEmail, userLocale(ctx, user))
+	if err != nil {
+		if errors.Is(err, ErrResendCooldown) {
+			ctx.JSON(http.StatusTooManyRequests, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.inMemory.Set(emailChangeKey+strconv.FormatInt(user.ID, 10), req.NewEmail, verificationCodeTTL)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.sendEmailChangeNotice(ctx, user, req.NewEmail)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.OKResponse{
+		Message: "Confirmation code has been sent to the new email!",
+	})
+}
+
+// sendEmailChangeNotice lets the owner of the current address undo the change
+func (h *handlerV1) sendEmailChangeNotice(ctx *gin.Context, user *repo.User, newEmail string) error {
+	token, _, err := utils.CreateEmailChangeToken(h.cfg.AuthSecretKey, user.ID, user.Email, newEmail, h.cfg.EmailChange.UndoTTL)
+	if err != nil {
+		return err
+	}
+
+	link, err := url.Parse(h.cfg.EmailChange.UndoURL)
+	if err != nil {
+		return err
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return h.enqueueEmail(&emailPkg.SendEmailRequest{
+		To: []string{user.Email},
+		Body: map[string]string{
+			"email": newEmail,
+			"link":  link.String(),
+			"days":  strconv.Itoa(int(h.cfg.EmailChange.UndoTTL / (24 * time.Hour))),
+		},
+		Type:   emailPkg.EmailChangeNotice,
+		Locale: userLocale(ctx, user),
+	}, h.cfg.EmailChange.UndoTTL)
+}
+
+// @Security ApiKeyAuth
+// @Router /users/me/email/confirm [post]
+// @Summary Confirm email change
+// @Description Confirm email change with the code sent to the new email
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param data body models.ConfirmEmailChangeRequest true "Data"
+// @Success 200 {object} models.OKResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) ConfirmEmailChange(ctx *gin.Context) {
+	var req models.ConfirmEmailChangeRequest
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	pendingKey := emailChangeKey + strconv.FormatInt(payload.UserID, 10)
+
+	newEmail, err := h.inMemory.Get(pendingKey)
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrNoEmailChange))
+		return
+	}
+
+	if !h.checkVerificationCode(ctx, EmailChangeCodeKey, newEmail, req.Code) {
+		return
+	}
+
+	// The email may have been registered while the code was on its way
+	_, err = h.storage.User().GetByEmail(newEmail)
+	if !errors.Is(err, sql.ErrNoRows) {
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrEmailExists))
+		return
+	}
+
+	err = h.storage.User().UpdateEmail(&repo.UpdateEmail{
+		UserID: payload.UserID,
+		Email:  newEmail,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.inMemory.Del(pendingKey)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.OKResponse{
+		Message: "Email has been changed",
+	})
+}
+
+// verifyUndoToken writes the error response itself and returns nil when the token can not be used
+func (h *handlerV1) verifyUndoToken(ctx *gin.Context, token string) *utils.EmailChangeClaims {
+	if h.abortIfBlocked(ctx, h.ipLimiter, ctx.ClientIP()) {
+		return nil
+	}
+
+	claims, err := utils.VerifyEmailChangeToken(h.cfg.AuthSecretKey, token)
+	if err != nil {
+		_, failErr := h.ipLimiter.Fail(ctx.ClientIP())
+		if failErr != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(failErr))
+			return nil
+		}
+
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return nil
+	}
+
+	return claims
+}
+
+// @Router /auth/email-change/undo [get]
+// @Summary Get the email change to undo
+// @Description Describes the change the link sent to the previous email reverts without changing anything,
+// @Description so mail scanners following the link do not undo it. The change is undone by posting the token
+// @Tags auth
+// @Produce json
+// @Param token query string true "Token"
+// @Success 200 {object} models.EmailChangeUndo
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetEmailChangeUndo(ctx *gin.Context) {
+	claims := h.verifyUndoToken(ctx, ctx.Query("token"))
+	if claims == nil {
+		return
+	}
+
+	_, err := h.inMemory.Get(emailChangeUndoKey + claims.ID)
+	if err == nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrEmailChangeUndone))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.EmailChangeUndo{
+		OldEmail:  claims.OldEmail,
+		NewEmail:  claims.NewEmail,
+		ExpiresAt: claims.ExpiresAt,
+	})
+}
+
+// @Router /auth/email-change/undo [post]
+// @Summary Undo email change
+// @Description Restores the previous email with the token of the link sent to it and logs out every session
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.UndoEmailChangeRequest true "Data"
+// @Success 200 {object} models.OKResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) UndoEmailChange(ctx *gin.Context) {
+	var req models.UndoEmailChangeRequest
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	claims := h.verifyUndoToken(ctx, req.Token)
+	if claims == nil {
+		return
+	}
+
+	ok, err := h.inMemory.SetNX(emailChangeUndoKey+claims.ID, "1", time.Until(claims.ExpiresAt)+time.Minute)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrEmailChangeUndone))
+		return
+	}
+
+	user, err := h.storage.User().Get(claims.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(utils.ErrInvalidToken))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	pendingKey := emailChangeKey + strconv.FormatInt(user.ID, 10)
+
+	// An unconfirmed change is cancelled so the code can not be used anymore
+	newEmail, err := h.inMemory.Get(pendingKey)
+	if err == nil {
+		err = h.inMemory.Del(pendingKey, EmailChangeCodeKey+newEmail)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	if user.Email != claims.OldEmail {
+		owner, err := h.storage.User().GetByEmail(claims.OldEmail)
+		if err == nil && owner.ID != user.ID {
+			ctx.JSON(http.StatusConflict, errorResponse(ErrPreviousEmailTaken))
+			return
+		}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		err = h.storage.User().UpdateEmail(&repo.UpdateEmail{
+			UserID: user.ID,
+			Email:  claims.OldEmail,
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	// Whoever requested the change may still be logged in
+	err = h.revokeSessions(user.ID, uuid.Nil)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.OKResponse{
+		Message: "Email change has been undone, please reset your password",
+	})
+}
@@ -0,0 +1,180 @@
+package v1
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ibrat-muslim/blog-app/api/models"
+	emailPkg "github.com/ibrat-muslim/blog-app/pkg/email"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+var (
+	ErrEmailSent    = errors.New("email has been sent, its body is not kept")
+	ErrEmailExpired = errors.New("email has expired")
+)
+
+// enqueueEmail renders the email and stores it in the outbox, the worker delivers it and retries on failures.
+// Emails with codes or links are given the ttl of those so they are not delivered once they stop working
+func (h *handlerV1) enqueueEmail(req *emailPkg.SendEmailRequest, ttl time.Duration) error {
+	msg, err := emailPkg.NewMessage(req)
+	if err != nil {
+		return err
+	}
+
+	var expiresAt *time.Time
+	if ttl > 0 {
+		t := time.Now().Add(ttl)
+		expiresAt = &t
+	}
+
+	for _, to := range msg.To {
+		_, err := h.storage.EmailOutbox().Create(&repo.EmailOutbox{
+			Recipient: to,
+			Subject:   msg.Subject,
+			HTMLBody:  msg.HTMLBody,
+			TextBody:  msg.TextBody,
+			ExpiresAt: expiresAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// @Security ApiKeyAuth
+// @Router /admin/email-outbox [get]
+// @Summary Get outbox emails
+// @Description Get outbox emails
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param filter query models.GetEmailOutboxParams false "Filter"
+// @Success 200 {object} models.GetEmailOutboxResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetEmailOutbox(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if payload.UserType != repo.UserTypeSuperAdmin {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrForbidden))
+		return
+	}
+
+	request, err := validateGetAllParamsRequest(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	result, err := h.storage.EmailOutbox().GetAll(&repo.GetEmailOutboxParams{
+		Limit:  request.Limit,
+		Page:   request.Page,
+		Status: ctx.Query("status"),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := models.GetEmailOutboxResponse{
+		Emails: make([]*models.EmailOutbox, 0),
+		Count:  result.Count,
+	}
+
+	for _, email := range result.Emails {
+		e := parseEmailOutboxToModel(email)
+		response.Emails = append(response.Emails, &e)
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /admin/email-outbox/{id}/resend [post]
+// @Summary Resend an outbox email
+// @Description Resend an outbox email
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.OKResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) ResendEmail(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if payload.UserType != repo.UserTypeSuperAdmin {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrForbidden))
+		return
+	}
+
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	email, err := h.storage.EmailOutbox().Get(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// The bodies of sent emails are not kept, expired ones are refused by Resend
+	if email.Status == repo.EmailStatusSent {
+		ctx.JSON(http.StatusConflict, errorResponse(ErrEmailSent))
+		return
+	}
+
+	err = h.storage.EmailOutbox().Resend(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusConflict, errorResponse(ErrEmailExpired))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.OKResponse{
+		Message: "Email has been queued for sending",
+	})
+}
+
+func parseEmailOutboxToModel(email *repo.EmailOutbox) models.EmailOutbox {
+	return models.EmailOutbox{
+		ID:            email.ID,
+		Recipient:     email.Recipient,
+		Subject:       email.Subject,
+		Status:        email.Status,
+		Attempts:      email.Attempts,
+		LastError:     email.LastError,
+		NextAttemptAt: email.NextAttemptAt,
+		CreatedAt:     email.CreatedAt,
+		SentAt:        email.SentAt,
+		ExpiresAt:     email.ExpiresAt,
+	}
+}
@@ -0,0 +1,61 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	emailPkg "github.com/ibrat-muslim/blog-app/pkg/email"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+// @Security ApiKeyAuth
+// @Router /admin/email-templates/{name}/preview [get]
+// @Summary Preview an email template
+// @Description Renders the template with sample data
+// @Tags admin
+// @Produce html
+// @Produce plain
+// @Param name path string true "Template name" Enums(verification_email, forgot_password_email, account_locked_email, magic_link_email, email_change_email, email_change_notice_email)
+// @Param locale query string false "Locale" Enums(en, ru, uz)
+// @Param format query string false "Format" Enums(html, text) default(html)
+// @Success 200 {string} string
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) PreviewEmailTemplate(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if payload.UserType != repo.UserTypeSuperAdmin {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrForbidden))
+		return
+	}
+
+	format := ctx.DefaultQuery("format", emailPkg.FormatHTML)
+
+	result, err := emailPkg.Preview(ctx.Param("name"), ctx.Query("locale"), format)
+	if err != nil {
+		if errors.Is(err, emailPkg.ErrUnknownTemplate) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		if errors.Is(err, emailPkg.ErrUnknownFormat) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	contentType := "text/html; charset=utf-8"
+	if format == emailPkg.FormatText {
+		contentType = "text/plain; charset=utf-8"
+	}
+
+	ctx.Data(http.StatusOK, contentType, []byte(result))
+}
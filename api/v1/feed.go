@@ -0,0 +1,220 @@
+package v1
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ibrat-muslim/blog-app/pkg/feed"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+// feedKey caches rendered feeds, the scope and the format follow it
+const feedKey = "feed_"
+
+// @Router /feeds/posts.rss [get]
+// @Summary Get the RSS feed
+// @Description Get the latest posts as RSS 2.0, conditional requests are answered with 304
+// @Tags feed
+// @Produce xml
+// @Success 200 {string} string
+// @Success 304
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetPostsRSS(ctx *gin.Context) {
+	h.servePostsFeed(ctx, "posts", feed.FormatRSS, "", "", &repo.GetPostsParams{})
+}
+
+// @Router /feeds/posts.atom [get]
+// @Summary Get the Atom feed
+// @Description Get the latest posts as Atom, conditional requests are answered with 304
+// @Tags feed
+// @Produce xml
+// @Success 200 {string} string
+// @Success 304
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetPostsAtom(ctx *gin.Context) {
+	h.servePostsFeed(ctx, "posts", feed.FormatAtom, "", "", &repo.GetPostsParams{})
+}
+
+// @Router /feeds/categories/{id} [get]
+// @Summary Get the feed of a category
+// @Description Get the latest posts of the category, the id ends with .atom for Atom and with .rss or nothing for RSS 2.0
+// @Tags feed
+// @Produce xml
+// @Param id path string true "ID with an optional .rss or .atom extension"
+// @Success 200 {string} string
+// @Success 304
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetCategoryFeed(ctx *gin.Context) {
+	id, format, err := parseFeedID(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	category, err := h.storage.Category().Get(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	scope := fmt.Sprintf("categories/%d", id)
+	h.servePostsFeed(ctx, scope, format, category.Title, "/"+scope, &repo.GetPostsParams{
+		CategoryID: id,
+	})
+}
+
+// @Router /feeds/authors/{id} [get]
+// @Summary Get the feed of an author
+// @Description Get the latest posts of the author, the id ends with .atom for Atom and with .rss or nothing for RSS 2.0
+// @Tags feed
+// @Produce xml
+// @Param id path string true "ID with an optional .rss or .atom extension"
+// @Success 200 {string} string
+// @Success 304
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetAuthorFeed(ctx *gin.Context) {
+	id, format, err := parseFeedID(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	author, err := h.storage.User().Get(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if author.Banned && h.cfg.Moderation.HideBannedContent {
+		ctx.JSON(http.StatusNotFound, errorResponse(sql.ErrNoRows))
+		return
+	}
+
+	scope := fmt.Sprintf("authors/%d", id)
+	h.servePostsFeed(ctx, scope, format, displayName(author), "/"+scope, &repo.GetPostsParams{
+		UserID: id,
+	})
+}
+
+// parseFeedID splits the format extension off the id
+func parseFeedID(param string) (int64, string, error) {
+	format := feed.FormatRSS
+	if strings.HasSuffix(param, "."+feed.FormatAtom) {
+		format = feed.FormatAtom
+	}
+	param = strings.TrimSuffix(param, "."+format)
+
+	id, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return id, format, nil
+}
+
+// servePostsFeed renders the latest posts matching the params, or takes them from the cache.
+// The subject is added to the title of the site and the page is the path of its posts on the site
+func (h *handlerV1) servePostsFeed(ctx *gin.Context, scope, format, subject, page string, params *repo.GetPostsParams) {
+	key := feedKey + scope + "." + format
+
+	h.serveCached(ctx, key, h.cfg.Feed.CacheTTL, feed.ContentType(format), func() ([]byte, time.Time, error) {
+		params.Limit = h.cfg.Feed.Size
+		params.Page = 1
+		params.HideBanned = h.cfg.Moderation.HideBannedContent
+
+		f, err := h.buildPostsFeed(params)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+
+		if subject != "" {
+			f.Title += " - " + subject
+		}
+		f.Link += page
+		f.SelfLink = h.cfg.Site.BaseURL + "/feeds/" + scope + "." + format
+
+		body, err := feed.Render(f, format)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+
+		return body, f.Updated, nil
+	})
+}
+
+// buildPostsFeed links the site itself, the caller sets the links of narrower feeds
+func (h *handlerV1) buildPostsFeed(params *repo.GetPostsParams) (*feed.Feed, error) {
+	result, err := h.storage.Post().GetAll(params)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := h.cfg.Site.BaseURL
+
+	f := &feed.Feed{
+		Title:       h.cfg.Site.Title,
+		Description: h.cfg.Site.Description,
+		Link:        baseURL,
+		Items:       make([]*feed.Item, 0, len(result.Posts)),
+	}
+
+	relations := h.newPostRelations()
+
+	for _, post := range result.Posts {
+		author, err := relations.author(post.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		category, err := relations.category(post.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+
+		updated := post.CreatedAt
+		if post.UpdatedAt != nil {
+			updated = *post.UpdatedAt
+		}
+
+		if updated.After(f.Updated) {
+			f.Updated = updated
+		}
+
+		link := fmt.Sprintf("%s/posts/%d", baseURL, post.ID)
+
+		f.Items = append(f.Items, &feed.Item{
+			ID:         link,
+			Title:      post.Title,
+			Link:       link,
+			Content:    post.Description,
+			Author:     displayName(author),
+			Categories: []string{category.Title},
+			Published:  post.CreatedAt,
+			Updated:    updated,
+		})
+	}
+
+	if f.Updated.IsZero() {
+		f.Updated = time.Now()
+	}
+
+	return f, nil
+}
@@ -1,55 +1,278 @@
 package v1
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"mime/multipart"
 	"net/http"
-	"os"
+	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/ibrat-muslim/blog-app/api/models"
+	"github.com/ibrat-muslim/blog-app/pkg/blob"
+	"github.com/ibrat-muslim/blog-app/pkg/imaging"
+	"github.com/ibrat-muslim/blog-app/pkg/upload"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+// multipartOverhead leaves room for the form around the file in the request size limit
+const multipartOverhead = 1 << 20
+
+var (
+	ErrFileTooLarge  = errors.New("file is too large")
+	ErrQuotaExceeded = repo.ErrQuotaExceeded
 )
 
 type File struct {
 	File *multipart.FileHeader `form:"file" binding:"required"`
+	// Purpose decides which types are allowed, attachment when it is empty
+	Purpose string `form:"purpose" binding:"omitempty,oneof=avatar post_image attachment"`
 }
 
 // @Security ApiKeyAuth
 // @Router /file-upload [post]
 // @Summary File upload
-// @Description File upload
+// @Description Avatars and post images can be PNG, JPEG, GIF or WebP, attachments can also be PDF, plain text or ZIP.
+// @Description The type is sniffed from the content and the extension must match it.
+// @Description Images are stored upright without their metadata, avatars and post images are also resized into the configured variants.
+// @Description A file the user has already uploaded for the purpose is returned with 200 instead of being stored again
 // @Tags file-upload
-// @Accept json
+// @Accept multipart/form-data
 // @Produce json
 // @Param file formData file true "File"
-// @Success 201 {object} models.OKResponse
+// @Param purpose formData string false "Purpose" Enums(avatar, post_image, attachment)
+// @Success 200 {object} models.FileUploadResponse
+// @Success 201 {object} models.FileUploadResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
 func (h *handlerV1) UploadFile(ctx *gin.Context) {
+	maxSize := h.cfg.Upload.MaxSize
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxSize+multipartOverhead)
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
 	var file File
 
-	err := ctx.ShouldBind(&file)
+	err = ctx.ShouldBind(&file)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, errorResponse(ErrFileTooLarge))
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if file.File.Size > maxSize {
+		ctx.JSON(http.StatusRequestEntityTooLarge, errorResponse(fmt.Errorf("%w, the limit is %d bytes", ErrFileTooLarge, maxSize)))
+		return
+	}
+
+	if file.Purpose == "" {
+		file.Purpose = upload.PurposeAttachment
+	}
+
+	src, err := file.File.Open()
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	defer src.Close()
 
-	id := uuid.New()
-	fileName := id.String() + filepath.Ext(file.File.Filename)
-	dst, _ := os.Getwd()
+	head := make([]byte, upload.SniffLen)
+	n, err := io.ReadFull(src, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	head = head[:n]
 
-	if _, err := os.Stat(dst + "/media"); os.IsNotExist(err) {
-		os.Mkdir(dst+"/media", os.ModePerm)
+	// The client's file name and Content-Type are not trusted, only the content is
+	contentType, err := upload.Validate(file.Purpose, file.File.Filename, head)
+	if err != nil {
+		ctx.JSON(http.StatusUnsupportedMediaType, errorResponse(err))
+		return
 	}
 
-	filePath := "/media/" + fileName
-	err = ctx.SaveUploadedFile(file.File, dst+filePath)
+	data, err := io.ReadAll(io.MultiReader(bytes.NewReader(head), src))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, gin.H{
-		"filename": filePath,
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	// The same file uploaded again for the same purpose is not stored twice
+	media, err := h.storage.Media().Reuse(payload.UserID, hash, file.Purpose)
+	if err == nil {
+		h.respondWithUpload(ctx, http.StatusOK, media)
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// Uploads that can not fit are refused before any work, Create checks the quota again with the variants
+	if quota := h.cfg.Media.Quota; quota > 0 {
+		usage, err := h.storage.Media().Usage(payload.UserID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if usage+int64(len(data)) > quota {
+			ctx.JSON(http.StatusRequestEntityTooLarge, errorResponse(fmt.Errorf("%w, %d of %d bytes are used", ErrQuotaExceeded, usage, quota)))
+			return
+		}
+	}
+
+	media = &repo.Media{
+		UserID:   &payload.UserID,
+		Purpose:  file.Purpose,
+		MimeType: contentType,
+		Size:     int64(len(data)),
+		SHA256:   hash,
+	}
+
+	if strings.HasPrefix(contentType, "image/") {
+		err = h.storeImage(ctx, media, data)
+		if err != nil {
+			switch {
+			case errors.Is(err, imaging.ErrInvalidImage) || errors.Is(err, imaging.ErrTooManyPixels):
+				ctx.JSON(http.StatusUnsupportedMediaType, errorResponse(err))
+			case errors.Is(err, imaging.ErrBusy):
+				ctx.JSON(http.StatusServiceUnavailable, errorResponse(err))
+			default:
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			}
+			return
+		}
+	} else {
+		media.Path = uuid.NewString() + strings.ToLower(filepath.Ext(file.File.Filename))
+
+		err = h.blobs.Put(ctx.Request.Context(), media.Path, bytes.NewReader(data), media.Size, contentType)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	status := http.StatusCreated
+
+	created, err := h.storage.Media().Create(media, h.cfg.Media.Quota)
+	if errors.Is(err, repo.ErrQuotaExceeded) {
+		h.deleteMediaFiles(ctx.Request.Context(), media.Path)
+		ctx.JSON(http.StatusRequestEntityTooLarge, errorResponse(fmt.Errorf("%w, the file takes %d bytes with its variants", ErrQuotaExceeded, media.Size+media.VariantsSize)))
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		// The same file was uploaded at the same time, the copy stored by this request is dropped
+		h.deleteMediaFiles(ctx.Request.Context(), media.Path)
+
+		status = http.StatusOK
+		created, err = h.storage.Media().Reuse(payload.UserID, hash, file.Purpose)
+	} else if err != nil {
+		// Nothing would ever link or clean up the stored files without their record
+		h.deleteMediaFiles(ctx.Request.Context(), media.Path)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	h.respondWithUpload(ctx, status, created)
+}
+
+// storeImage stores the image without its metadata along with the variants of the purpose
+func (h *handlerV1) storeImage(ctx *gin.Context, media *repo.Media, data []byte) error {
+	result, err := h.images.Process(ctx.Request.Context(), media.Purpose, data)
+	if err != nil {
+		return err
+	}
+
+	media.Path = result.Key
+	media.Size = result.Size
+	media.VariantsSize = result.VariantsSize
+	media.Width = &result.Width
+	media.Height = &result.Height
+
+	return nil
+}
+
+func (h *handlerV1) respondWithUpload(ctx *gin.Context, status int, media *repo.Media) {
+	url, err := h.blobs.URL(ctx.Request.Context(), media.Path)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	filename := blob.URLPrefix + media.Path
+
+	ctx.JSON(status, models.FileUploadResponse{
+		ID:       media.ID,
+		Filename: filename,
+		URL:      url,
+		Width:    media.Width,
+		Height:   media.Height,
+		Variants: h.imageVariants(&filename, media.Purpose),
 	})
 }
+
+// imageVariants returns the links of the variants made of an uploaded image by name,
+// nil when the link is not to a processed image
+func (h *handlerV1) imageVariants(url *string, purpose string) map[string]string {
+	key, ok := blob.KeyFromURL(url)
+	if !ok || h.images == nil {
+		return nil
+	}
+
+	keys := h.images.VariantKeys(key, purpose)
+	if keys == nil {
+		return nil
+	}
+
+	variants := make(map[string]string, len(keys))
+	for name, key := range keys {
+		variants[name] = blob.URLPrefix + key
+	}
+
+	return variants
+}
+
+// MediaHeaders stops browsers from rendering uploaded files as pages of our origin,
+// files uploaded before the type checks existed included
+func MediaHeaders(c *gin.Context) {
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Disposition", blob.ContentDisposition(mime.TypeByExtension(path.Ext(c.Request.URL.Path))))
+	c.Next()
+}
+
+// RedirectMedia sends clients to the bucket, presigned URLs are made on every request so links never expire
+func (h *handlerV1) RedirectMedia(ctx *gin.Context) {
+	url, err := h.blobs.URL(ctx.Request.Context(), strings.TrimPrefix(ctx.Param("key"), "/"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, url)
+}
@@ -2,11 +2,19 @@ package v1
 
 import (
 	"errors"
+	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ibrat-muslim/blog-app/api/models"
 	"github.com/ibrat-muslim/blog-app/config"
+	"github.com/ibrat-muslim/blog-app/pkg/blob"
+	"github.com/ibrat-muslim/blog-app/pkg/imaging"
+	"github.com/ibrat-muslim/blog-app/pkg/limiter"
+	"github.com/ibrat-muslim/blog-app/pkg/oidc"
+	"github.com/ibrat-muslim/blog-app/pkg/password"
+	"github.com/ibrat-muslim/blog-app/pkg/utils"
+	"github.com/ibrat-muslim/blog-app/pkg/web"
 	"github.com/ibrat-muslim/blog-app/storage"
 )
 
@@ -17,26 +25,84 @@ var (
 	ErrIncorrectCode    = errors.New("incorrect verification code")
 	ErrCodeExpired      = errors.New("verification code has been expired")
 	ErrForbidden        = errors.New("forbidden")
+	ErrTooManyAttempts  = errors.New("too many attempts, please try again later")
+	ErrCodeInvalidated  = errors.New("too many incorrect codes, please request a new one")
+	ErrResendCooldown   = errors.New("verification code has been sent recently, please wait before requesting a new one")
 )
 
 type handlerV1 struct {
-	cfg      *config.Config
-	storage  storage.StorageI
-	inMemory storage.InMemoryStorageI
+	cfg            *config.Config
+	storage        storage.StorageI
+	inMemory       storage.InMemoryStorageI
+	accountLimiter *limiter.Limiter
+	ipLimiter      *limiter.Limiter
+	codeLimiter    *limiter.Limiter
+	oidcProvider   *oidc.Provider
+	keys           *utils.KeySet
+	passwords      *password.Service
+	blobs          blob.BlobStore
+	images         *imaging.Processor
+	pages          *web.Renderer
+	webhookClient  *http.Client
 }
 
 type HandlerV1Options struct {
-	Cfg      *config.Config
-	Storage  storage.StorageI
-	InMemory storage.InMemoryStorageI
+	Cfg       *config.Config
+	Storage   storage.StorageI
+	InMemory  storage.InMemoryStorageI
+	Keys      *utils.KeySet
+	Passwords *password.Service
+	Blobs     blob.BlobStore
+	Images    *imaging.Processor
+	Pages     *web.Renderer
 }
 
 func New(options *HandlerV1Options) *handlerV1 {
-	return &handlerV1{
-		cfg:      options.Cfg,
-		storage:  options.Storage,
-		inMemory: options.InMemory,
+	bruteForce := options.Cfg.BruteForce
+
+	h := &handlerV1{
+		cfg:       options.Cfg,
+		storage:   options.Storage,
+		inMemory:  options.InMemory,
+		keys:      options.Keys,
+		passwords: options.Passwords,
+		blobs:     options.Blobs,
+		images:    options.Images,
+		pages:     options.Pages,
+		webhookClient: &http.Client{
+			Timeout: options.Cfg.Webhook.Timeout,
+		},
+		accountLimiter: limiter.New(options.InMemory, "login_account_", limiter.Policy{
+			FreeAttempts: bruteForce.FreeAttempts,
+			BaseDelay:    bruteForce.BaseDelay,
+			MaxAttempts:  bruteForce.MaxAccountAttempts,
+			Window:       bruteForce.AttemptWindow,
+			Lockout:      bruteForce.LockoutDuration,
+		}),
+		ipLimiter: limiter.New(options.InMemory, "auth_ip_", limiter.Policy{
+			FreeAttempts: bruteForce.FreeAttempts,
+			BaseDelay:    bruteForce.BaseDelay,
+			MaxAttempts:  bruteForce.MaxIPAttempts,
+			Window:       bruteForce.AttemptWindow,
+			Lockout:      bruteForce.LockoutDuration,
+		}),
+		codeLimiter: limiter.New(options.InMemory, "verification_code_", limiter.Policy{
+			MaxAttempts: bruteForce.MaxCodeAttempts,
+			Window:      verificationCodeTTL,
+		}),
+	}
+
+	if options.Cfg.OIDC.IssuerURL != "" {
+		h.oidcProvider = oidc.New(oidc.Config{
+			IssuerURL:    options.Cfg.OIDC.IssuerURL,
+			ClientID:     options.Cfg.OIDC.ClientID,
+			ClientSecret: options.Cfg.OIDC.ClientSecret,
+			RedirectURL:  options.Cfg.OIDC.RedirectURL,
+			Scopes:       options.Cfg.OIDC.Scopes,
+		})
 	}
+
+	return h
 }
 
 func errorResponse(err error) *models.ErrorResponse {
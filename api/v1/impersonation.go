@@ -0,0 +1,128 @@
+package v1
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ibrat-muslim/blog-app/api/models"
+	"github.com/ibrat-muslim/blog-app/pkg/utils"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+const impersonatedByHeader = "X-Impersonated-By"
+
+var (
+	ErrImpersonateSelf        = errors.New("you can not impersonate yourself")
+	ErrImpersonateSuperAdmin  = errors.New("superadmins can not be impersonated")
+	ErrImpersonationForbidden = errors.New("this action is not allowed while impersonating a user")
+)
+
+// impersonationBlockedRoutes can only be reached by the users themselves
+var impersonationBlockedRoutes = map[string]bool{
+	"POST /v1/auth/update-password":      true,
+	"POST /v1/auth/2fa/enroll":           true,
+	"POST /v1/auth/2fa/confirm":          true,
+	"POST /v1/auth/2fa/disable":          true,
+	"POST /v1/users/me/tokens":           true,
+	"DELETE /v1/users/me/tokens/:id":     true,
+	"DELETE /v1/users/me/sessions/:id":   true,
+	"POST /v1/users/me/email":            true,
+	"POST /v1/users/me/email/confirm":    true,
+	"GET /v1/users/me/export":            true,
+	"DELETE /v1/users/me":                true,
+	"POST /v1/users/me/deletion/cancel":  true,
+	"POST /v1/admin/impersonate/:userID": true,
+}
+
+// @Security ApiKeyAuth
+// @Router /admin/impersonate/{userID} [post]
+// @Summary Impersonate a user
+// @Description Issues a short-lived token to act as the user, the requests made with it are audited and can not change the credentials of the user
+// @Tags admin
+// @Produce json
+// @Param userID path int true "User ID"
+// @Success 200 {object} models.ImpersonationResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) ImpersonateUser(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if payload.UserType != repo.UserTypeSuperAdmin {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrForbidden))
+		return
+	}
+
+	id, err := strconv.ParseInt(ctx.Param("userID"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if id == payload.UserID {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrImpersonateSelf))
+		return
+	}
+
+	user, err := h.storage.User().Get(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.Type == repo.UserTypeSuperAdmin {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrImpersonateSuperAdmin))
+		return
+	}
+
+	if user.Email == repo.DeletedUserEmail {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrForbidden))
+		return
+	}
+
+	// Tokens of banned and suspended users would be rejected anyway
+	if h.abortIfRestricted(ctx, user) {
+		return
+	}
+
+	// The token shares the session of the superadmin, so it ends with it
+	token, tokenPayload, err := utils.CreateToken(h.keys, &utils.TokenParams{
+		UserID:         user.ID,
+		UserType:       user.Type,
+		Email:          user.Email,
+		Duration:       h.cfg.Impersonation.TTL,
+		SessionID:      payload.SessionID,
+		ImpersonatorID: payload.UserID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := models.ImpersonationResponse{
+		UserID:      user.ID,
+		Email:       user.Email,
+		Type:        user.Type,
+		AccessToken: token,
+		ExpiresAt:   tokenPayload.ExpiredAt,
+	}
+
+	audit(ctx, "user.impersonate", "user", user.ID, nil, gin.H{
+		"token_id":   tokenPayload.ID,
+		"expires_at": tokenPayload.ExpiredAt,
+	})
+
+	ctx.JSON(http.StatusOK, response)
+}
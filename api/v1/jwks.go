@@ -0,0 +1,14 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetJWKS publishes the public keys, so other services can verify access tokens
+// without sharing a secret. It is served outside of /v1 at the well-known path.
+func (h *handlerV1) GetJWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, h.keys.JWKS())
+}
@@ -0,0 +1,172 @@
+package v1
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ibrat-muslim/blog-app/api/models"
+	emailPkg "github.com/ibrat-muslim/blog-app/pkg/email"
+	"github.com/ibrat-muslim/blog-app/pkg/utils"
+)
+
+const (
+	magicLinkCooldownKey = "magic_link_"
+	magicLinkUsedKey     = "magic_link_used_"
+)
+
+var ErrMagicLinkUsed = errors.New("sign-in link has already been used")
+
+// @Router /auth/magic-link [post]
+// @Summary Send a sign-in link
+// @Description Emails a single-use sign-in link, the response is the same whether the email is registered or not
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.MagicLinkRequest true "Data"
+// @Success 200 {object} models.OKResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) SendMagicLink(ctx *gin.Context) {
+	var req models.MagicLinkRequest
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if h.abortIfBlocked(ctx, h.ipLimiter, ctx.ClientIP()) {
+		return
+	}
+
+	ok, err := h.inMemory.SetNX(resendCooldownKey+magicLinkCooldownKey+req.Email, "1", h.cfg.BruteForce.ResendCooldown)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !ok {
+		ctx.JSON(http.StatusTooManyRequests, errorResponse(ErrResendCooldown))
+		return
+	}
+
+	response := models.OKResponse{
+		Message: "If the email is registered, a sign-in link has been sent!",
+	}
+
+	user, err := h.storage.User().GetByEmail(req.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusOK, response)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	token, _, err := utils.CreateMagicLinkToken(h.cfg.AuthSecretKey, user.Email, h.cfg.MagicLink.TTL)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	link, err := url.Parse(h.cfg.MagicLink.URL)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	err = h.enqueueEmail(&emailPkg.SendEmailRequest{
+		To: []string{user.Email},
+		Body: map[string]string{
+			"link":    link.String(),
+			"minutes": strconv.Itoa(int(h.cfg.MagicLink.TTL / time.Minute)),
+		},
+		Type:   emailPkg.MagicLinkEmail,
+		Locale: userLocale(ctx, user),
+	}, h.cfg.MagicLink.TTL)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// @Router /auth/magic-link/consume [get]
+// @Summary Log in with a sign-in link
+// @Description Log in with a sign-in link
+// @Tags auth
+// @Produce json
+// @Param token query string true "Token"
+// @Success 201 {object} models.AuthResponse
+// @Success 202 {object} models.TwoFactorChallengeResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) ConsumeMagicLink(ctx *gin.Context) {
+	if h.abortIfBlocked(ctx, h.ipLimiter, ctx.ClientIP()) {
+		return
+	}
+
+	claims, err := utils.VerifyMagicLinkToken(h.cfg.AuthSecretKey, ctx.Query("token"))
+	if err != nil {
+		_, failErr := h.ipLimiter.Fail(ctx.ClientIP())
+		if failErr != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(failErr))
+			return
+		}
+
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	// SetNX makes concurrent requests with the same link race for a single winner
+	ok, err := h.inMemory.SetNX(magicLinkUsedKey+claims.ID, claims.Email, time.Until(claims.ExpiresAt)+time.Minute)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ErrMagicLinkUsed))
+		return
+	}
+
+	user, err := h.storage.User().GetByEmail(claims.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(utils.ErrInvalidToken))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if h.abortIfRestricted(ctx, user) {
+		return
+	}
+
+	if user.TotpEnabled {
+		h.startTwoFactorChallenge(ctx, user, accessTokenDuration)
+		return
+	}
+
+	response, err := h.createAuthResponse(ctx, user, accessTokenDuration, false)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, response)
+}
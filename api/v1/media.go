@@ -0,0 +1,163 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ibrat-muslim/blog-app/api/models"
+	"github.com/ibrat-muslim/blog-app/pkg/blob"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+var ErrMediaInUse = errors.New("file is linked by a post or a profile")
+
+// @Security ApiKeyAuth
+// @Router /media [get]
+// @Summary Get my uploads
+// @Description Get the files uploaded by the user with how much of the quota they take
+// @Tags file-upload
+// @Accept json
+// @Produce json
+// @Param filter query models.GetAllParamsRequest false "Filter"
+// @Success 200 {object} models.GetMediaResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetMedia(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	request, err := validateGetAllParamsRequest(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	result, err := h.storage.Media().GetAll(&repo.GetMediaParams{
+		Limit:  request.Limit,
+		Page:   request.Page,
+		UserID: payload.UserID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	usage, err := h.storage.Media().Usage(payload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := models.GetMediaResponse{
+		Media: make([]*models.Media, 0),
+		Count: result.Count,
+		Usage: usage,
+		Quota: h.cfg.Media.Quota,
+	}
+
+	for _, media := range result.Media {
+		m := h.parseMediaToModel(media)
+		response.Media = append(response.Media, &m)
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (h *handlerV1) parseMediaToModel(media *repo.Media) models.Media {
+	url := blob.URLPrefix + media.Path
+
+	return models.Media{
+		ID:        media.ID,
+		URL:       url,
+		Purpose:   media.Purpose,
+		MimeType:  media.MimeType,
+		Size:      media.Size,
+		SHA256:    media.SHA256,
+		Width:     media.Width,
+		Height:    media.Height,
+		Variants:  h.imageVariants(&url, media.Purpose),
+		CreatedAt: media.CreatedAt,
+	}
+}
+
+// @Security ApiKeyAuth
+// @Router /media/{id} [delete]
+// @Summary Delete an upload
+// @Description Deletes the file with its resized copies, a file linked by a post or a profile can not be deleted
+// @Tags file-upload
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.OKResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) DeleteMedia(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	media, err := h.storage.Media().Get(id, payload.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	linked, err := h.storage.Media().IsLinked(media.Path)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if linked {
+		ctx.JSON(http.StatusConflict, errorResponse(ErrMediaInUse))
+		return
+	}
+
+	err = h.storage.Media().Delete(media.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	h.deleteMediaFiles(ctx.Request.Context(), media.Path)
+
+	ctx.JSON(http.StatusOK, models.OKResponse{
+		Message: "successfully deleted",
+	})
+}
+
+// deleteMediaFiles removes the file with its resized copies, a failure leaves files behind and is only logged
+func (h *handlerV1) deleteMediaFiles(ctx context.Context, path string) {
+	for _, key := range h.images.Keys(path) {
+		err := h.blobs.Delete(ctx, key)
+		if err != nil {
+			log.Printf("failed to remove media %s: %v", key, err)
+		}
+	}
+}
@@ -3,8 +3,11 @@ package v1
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/ibrat-muslim/blog-app/pkg/utils"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
 	"github.com/gin-gonic/gin"
 )
 
@@ -13,7 +16,24 @@ const (
 	authorizationPayloadKey = "authorization_payload"
 )
 
+var twoFactorEnrollmentPaths = map[string]bool{
+	"/v1/auth/2fa/enroll":  true,
+	"/v1/auth/2fa/confirm": true,
+}
+
+// AuthMiddleware accepts session tokens only
 func (h *handlerV1) AuthMiddleware(c *gin.Context) {
+	h.authenticate(c, "")
+}
+
+// AuthWithScope also accepts personal access tokens granted the scope
+func (h *handlerV1) AuthWithScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h.authenticate(c, scope)
+	}
+}
+
+func (h *handlerV1) authenticate(c *gin.Context, scope string) {
 	accessToken := c.GetHeader(authorizationHeaderKey)
 
 	if len(accessToken) == 0 {
@@ -22,12 +42,58 @@ func (h *handlerV1) AuthMiddleware(c *gin.Context) {
 		return
 	}
 
-	payload, err := utils.VerifyToken(h.cfg, accessToken)
+	var (
+		payload *utils.Payload
+		err     error
+	)
+
+	if strings.HasPrefix(accessToken, personalAccessTokenPrefix) {
+		payload, err = h.verifyPersonalAccessToken(c, accessToken)
+	} else {
+		payload, err = utils.VerifyToken(h.keys, accessToken)
+		if err == nil {
+			err = h.checkSession(c, payload)
+		}
+		if err == nil {
+			err = h.checkRestriction(payload.UserID)
+		}
+	}
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		if errors.Is(err, utils.ErrInvalidToken) || errors.Is(err, utils.ErrExpiredToken) ||
+			errors.Is(err, ErrSessionRevoked) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		if errors.Is(err, ErrAccountBanned) || errors.Is(err, ErrAccountSuspended) {
+			c.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
+	if payload.Scopes != nil && (scope == "" || !payload.HasScope(scope)) {
+		c.AbortWithStatusJSON(http.StatusForbidden, errorResponse(ErrScopeNotAllowed))
+		return
+	}
+
+	// Superadmins can only reach the enrollment endpoints until they set up a second factor,
+	// personal access tokens can only be created from such a session
+	if h.cfg.TwoFactor.RequireSuperAdmin && payload.UserType == repo.UserTypeSuperAdmin &&
+		!payload.TwoFactor && payload.Scopes == nil && !twoFactorEnrollmentPaths[c.FullPath()] {
+		c.AbortWithStatusJSON(http.StatusForbidden, errorResponse(ErrTwoFactorRequired))
+		return
+	}
+
+	if payload.Impersonated() {
+		c.Header(impersonatedByHeader, strconv.FormatInt(payload.ImpersonatorID, 10))
+
+		if impersonationBlockedRoutes[c.Request.Method+" "+c.FullPath()] {
+			c.AbortWithStatusJSON(http.StatusForbidden, errorResponse(ErrImpersonationForbidden))
+			return
+		}
+	}
+
 	c.Set(authorizationPayloadKey, payload)
 	c.Next()
 }
@@ -43,4 +109,4 @@ func (m *handlerV1) GetAuthPayload(ctx *gin.Context) (*utils.Payload, error) {
 		return nil, errors.New("unknown user")
 	}
 	return payload, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,418 @@
+package v1
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/ibrat-muslim/blog-app/api/models"
+	"github.com/ibrat-muslim/blog-app/pkg/utils"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+// restrictionKey caches bans and suspensions for the auth middleware, users without them
+// are cached as restrictionNone for restrictionCacheDuration so not every request reads the database
+const (
+	restrictionKey           = "user_restricted_"
+	restrictionNone          = "none"
+	restrictionCacheDuration = 10 * time.Minute
+)
+
+var (
+	ErrAccountBanned    = errors.New("account has been banned")
+	ErrAccountSuspended = errors.New("account has been suspended")
+	ErrModerateSelf     = errors.New("you can not moderate your own account")
+	ErrSuspensionInPast = errors.New("suspension must end in the future")
+	ErrNotBanned        = errors.New("user is not banned")
+	ErrNotSuspended     = errors.New("user is not suspended")
+	ErrAlreadyBanned    = errors.New("user is already banned")
+)
+
+func isSuspended(user *repo.User) bool {
+	return user.SuspendedUntil != nil && user.SuspendedUntil.After(time.Now())
+}
+
+// accountRestriction explains why the user can not log in, nil when the user can
+func accountRestriction(user *repo.User) error {
+	if user.Banned {
+		if user.BanReason != nil {
+			return fmt.Errorf("%w: %s", ErrAccountBanned, *user.BanReason)
+		}
+		return ErrAccountBanned
+	}
+
+	if isSuspended(user) {
+		err := fmt.Errorf("%w until %s", ErrAccountSuspended, user.SuspendedUntil.Format(time.RFC3339))
+		if user.SuspensionReason != nil {
+			return fmt.Errorf("%w: %s", err, *user.SuspensionReason)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// abortIfRestricted refuses to log in banned and suspended users
+func (h *handlerV1) abortIfRestricted(ctx *gin.Context, user *repo.User) bool {
+	err := accountRestriction(user)
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return true
+	}
+
+	return false
+}
+
+// checkRestriction rejects tokens of users who have been banned or suspended since they were issued
+func (h *handlerV1) checkRestriction(userID int64) error {
+	key := restrictionKey + strconv.FormatInt(userID, 10)
+
+	restriction, err := h.inMemory.Get(key)
+	if errors.Is(err, redis.Nil) {
+		// The cache may have been flushed, the database has the final say
+		user, err := h.storage.User().Get(userID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.ErrInvalidToken
+			}
+			return err
+		}
+
+		if user.Banned || isSuspended(user) {
+			err = h.restrict(user)
+			if err != nil {
+				return err
+			}
+			return accountRestriction(user)
+		}
+
+		// A restriction cached meanwhile is not overwritten
+		_, err = h.inMemory.SetNX(key, restrictionNone, restrictionCacheDuration)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	switch restriction {
+	case repo.ModerationActionBan:
+		return ErrAccountBanned
+	case repo.ModerationActionSuspend:
+		return ErrAccountSuspended
+	}
+
+	return nil
+}
+
+// restrict caches the restriction of the user and ends their sessions,
+// a lifted restriction is removed from the cache
+func (h *handlerV1) restrict(user *repo.User) error {
+	key := restrictionKey + strconv.FormatInt(user.ID, 10)
+
+	switch {
+	case user.Banned:
+		err := h.inMemory.Set(key, repo.ModerationActionBan, 0)
+		if err != nil {
+			return err
+		}
+	case isSuspended(user):
+		err := h.inMemory.Set(key, repo.ModerationActionSuspend, time.Until(*user.SuspendedUntil))
+		if err != nil {
+			return err
+		}
+	default:
+		return h.inMemory.Del(key)
+	}
+
+	return h.revokeSessions(user.ID, uuid.Nil)
+}
+
+// moderate applies the action to the user and records who did it
+func (h *handlerV1) moderate(ctx *gin.Context, action *repo.ModerationAction, apply func(user *repo.User) error) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if payload.UserType != repo.UserTypeSuperAdmin {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrForbidden))
+		return
+	}
+
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if id == payload.UserID {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrModerateSelf))
+		return
+	}
+
+	user, err := h.storage.User().Get(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	before := parseUserToModel(user)
+
+	err = apply(user)
+	if err != nil {
+		if errors.Is(err, ErrNotBanned) || errors.Is(err, ErrNotSuspended) || errors.Is(err, ErrAlreadyBanned) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	action.UserID = user.ID
+	action.ModeratorID = &payload.UserID
+
+	_, err = h.storage.ModerationAction().Create(action)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.restrict(user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	after := parseUserToModel(user)
+
+	audit(ctx, "user."+action.Action, "user", user.ID, before, after)
+
+	ctx.JSON(http.StatusOK, after)
+}
+
+// @Security ApiKeyAuth
+// @Router /admin/users/{id}/ban [post]
+// @Summary Ban a user
+// @Description Bans the user until the ban is lifted, every session of the user is ended
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param data body models.BanUserRequest true "Data"
+// @Success 200 {object} models.User
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) BanUser(ctx *gin.Context) {
+	var req models.BanUserRequest
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	h.moderate(ctx, &repo.ModerationAction{
+		Action: repo.ModerationActionBan,
+		Reason: &req.Reason,
+	}, func(user *repo.User) error {
+		if user.Banned {
+			return ErrAlreadyBanned
+		}
+
+		err := h.storage.User().SetBan(user.ID, &req.Reason)
+		if err != nil {
+			return err
+		}
+
+		user.Banned = true
+		user.BanReason = &req.Reason
+		return nil
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /admin/users/{id}/ban [delete]
+// @Summary Unban a user
+// @Description Lifts the ban of the user
+// @Tags admin
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.User
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) UnbanUser(ctx *gin.Context) {
+	h.moderate(ctx, &repo.ModerationAction{
+		Action: repo.ModerationActionUnban,
+	}, func(user *repo.User) error {
+		if !user.Banned {
+			return ErrNotBanned
+		}
+
+		err := h.storage.User().SetBan(user.ID, nil)
+		if err != nil {
+			return err
+		}
+
+		user.Banned = false
+		user.BanReason = nil
+		return nil
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /admin/users/{id}/suspend [post]
+// @Summary Suspend a user
+// @Description Suspends the user until the given time, every session of the user is ended
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param data body models.SuspendUserRequest true "Data"
+// @Success 200 {object} models.User
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) SuspendUser(ctx *gin.Context) {
+	var req models.SuspendUserRequest
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !req.Until.After(time.Now()) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrSuspensionInPast))
+		return
+	}
+
+	h.moderate(ctx, &repo.ModerationAction{
+		Action:         repo.ModerationActionSuspend,
+		Reason:         &req.Reason,
+		SuspendedUntil: &req.Until,
+	}, func(user *repo.User) error {
+		// A suspension can be extended or shortened by suspending again
+		err := h.storage.User().SetSuspension(user.ID, &req.Until, &req.Reason)
+		if err != nil {
+			return err
+		}
+
+		user.SuspendedUntil = &req.Until
+		user.SuspensionReason = &req.Reason
+		return nil
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /admin/users/{id}/suspend [delete]
+// @Summary Unsuspend a user
+// @Description Lifts the suspension of the user before it ends
+// @Tags admin
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.User
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) UnsuspendUser(ctx *gin.Context) {
+	h.moderate(ctx, &repo.ModerationAction{
+		Action: repo.ModerationActionUnsuspend,
+	}, func(user *repo.User) error {
+		if !isSuspended(user) {
+			return ErrNotSuspended
+		}
+
+		err := h.storage.User().SetSuspension(user.ID, nil, nil)
+		if err != nil {
+			return err
+		}
+
+		user.SuspendedUntil = nil
+		user.SuspensionReason = nil
+		return nil
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /admin/users/{id}/moderation-actions [get]
+// @Summary Get moderation history
+// @Description Get the bans and suspensions of the user with the moderators who made them
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param filter query models.GetAllParamsRequest false "Filter"
+// @Success 200 {object} models.GetModerationActionsResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetModerationActions(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if payload.UserType != repo.UserTypeSuperAdmin {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrForbidden))
+		return
+	}
+
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	request, err := validateGetAllParamsRequest(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	result, err := h.storage.ModerationAction().GetAll(&repo.GetModerationActionsParams{
+		Limit:  request.Limit,
+		Page:   request.Page,
+		UserID: id,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := models.GetModerationActionsResponse{
+		Actions: make([]*models.ModerationAction, 0),
+		Count:   result.Count,
+	}
+
+	for _, action := range result.Actions {
+		response.Actions = append(response.Actions, &models.ModerationAction{
+			ID:             action.ID,
+			UserID:         action.UserID,
+			ModeratorID:    action.ModeratorID,
+			Action:         action.Action,
+			Reason:         action.Reason,
+			SuspendedUntil: action.SuspendedUntil,
+			CreatedAt:      action.CreatedAt,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,264 @@
+package v1
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	emailPkg "github.com/ibrat-muslim/blog-app/pkg/email"
+	"github.com/ibrat-muslim/blog-app/pkg/oidc"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+const (
+	oidcStateKey = "oidc_state_"
+	oidcStateTTL = 10 * time.Minute
+
+	// maxNameLength is the size of the name columns of users
+	maxNameLength = 30
+)
+
+var (
+	ErrOIDCDisabled         = errors.New("single sign-on is not configured")
+	ErrOIDCStateExpired     = errors.New("login request has expired, please try again")
+	ErrOIDCEmailMissing     = errors.New("identity provider did not share an email address")
+	ErrOIDCEmailNotVerified = errors.New("email address is not verified by the identity provider")
+)
+
+type oidcState struct {
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// @Router /auth/oidc/login [get]
+// @Summary Log in with the identity provider
+// @Description Redirects to the identity provider, it redirects back to the callback afterwards
+// @Tags auth
+// @Success 302
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) OIDCLogin(ctx *gin.Context) {
+	if h.oidcProvider == nil {
+		ctx.JSON(http.StatusNotFound, errorResponse(ErrOIDCDisabled))
+		return
+	}
+
+	var values [3]string
+	for i := range values {
+		value, err := oidc.GenerateVerifier()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		values[i] = value
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	data, err := json.Marshal(oidcState{
+		Nonce:    nonce,
+		Verifier: verifier,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.inMemory.Set(oidcStateKey+state, string(data), oidcStateTTL)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authURL, err := h.oidcProvider.AuthCodeURL(ctx.Request.Context(), state, nonce, verifier)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+// @Router /auth/oidc/callback [get]
+// @Summary Complete login with the identity provider
+// @Description Accounts are linked by verified email, unknown emails get a new account
+// @Tags auth
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State"
+// @Success 201 {object} models.AuthResponse
+// @Success 202 {object} models.TwoFactorChallengeResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) OIDCCallback(ctx *gin.Context) {
+	if h.oidcProvider == nil {
+		ctx.JSON(http.StatusNotFound, errorResponse(ErrOIDCDisabled))
+		return
+	}
+
+	if providerErr := ctx.Query("error"); providerErr != "" {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New(providerErr+": "+ctx.Query("error_description"))))
+		return
+	}
+
+	stateKey := oidcStateKey + ctx.Query("state")
+
+	data, err := h.inMemory.Get(stateKey)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrOIDCStateExpired))
+		return
+	}
+
+	// The state is single use, a replayed callback must not log in again
+	err = h.inMemory.Del(stateKey)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var state oidcState
+	err = json.Unmarshal([]byte(data), &state)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	token, err := h.oidcProvider.Exchange(ctx.Request.Context(), ctx.Query("code"), state.Verifier)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	claims, err := h.oidcProvider.VerifyIDToken(ctx.Request.Context(), token.IDToken, state.Nonce)
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	user, err := h.oidcUser(ctx, claims)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrOIDCEmailMissing):
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		case errors.Is(err, ErrOIDCEmailNotVerified):
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	if h.abortIfRestricted(ctx, user) {
+		return
+	}
+
+	if user.TotpEnabled {
+		h.startTwoFactorChallenge(ctx, user, accessTokenDuration)
+		return
+	}
+
+	response, err := h.createAuthResponse(ctx, user, accessTokenDuration, false)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// oidcUser returns the user linked to the identity, the identity is linked by
+// verified email on first login and a new user is registered for unknown emails
+func (h *handlerV1) oidcUser(ctx *gin.Context, claims *oidc.Claims) (*repo.User, error) {
+	provider := h.cfg.OIDC.ProviderName
+
+	identity, err := h.storage.UserIdentity().GetBySubject(provider, claims.Subject)
+	if err == nil {
+		err = h.storage.UserIdentity().UpdateLastLogin(identity.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		return h.storage.User().Get(identity.UserID)
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if claims.Email == "" {
+		return nil, ErrOIDCEmailMissing
+	}
+
+	// An unverified email could belong to someone else's account
+	if !claims.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	user, err := h.storage.User().GetByEmail(claims.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		user, err = h.registerOIDCUser(ctx, claims)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = h.storage.UserIdentity().Create(&repo.UserIdentity{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    &claims.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// registerOIDCUser creates a user without a usable password, one can be set via forgot password
+func (h *handlerV1) registerOIDCUser(ctx *gin.Context, claims *oidc.Claims) (*repo.User, error) {
+	secret, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := h.passwords.Hash(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	firstName, lastName := claims.GivenName, claims.FamilyName
+	if firstName == "" {
+		firstName = claims.Name
+	}
+
+	// Providers do not limit names, a longer one would fail the sign-in
+	firstName = truncateRunes(strings.TrimSpace(firstName), maxNameLength)
+	lastName = truncateRunes(strings.TrimSpace(lastName), maxNameLength)
+
+	locale := emailPkg.MatchLocale(claims.Locale, ctx.GetHeader("Accept-Language"))
+
+	return h.storage.User().Create(&repo.User{
+		FirstName: firstName,
+		LastName:  lastName,
+		Email:     claims.Email,
+		Password:  hashedPassword,
+		Type:      repo.UserTypeUser,
+		Language:  &locale,
+	})
+}
+
+// truncateRunes cuts the text to size characters without splitting one
+func truncateRunes(text string, size int) string {
+	if utf8.RuneCountInString(text) <= size {
+		return text
+	}
+
+	return string([]rune(text)[:size])
+}
@@ -0,0 +1,46 @@
+package v1
+
+import (
+	"log"
+
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+// checkPassword verifies the password of the user and upgrades a hash made with
+// outdated settings, a failed upgrade does not fail the check
+func (h *handlerV1) checkPassword(user *repo.User, password string) error {
+	rehash, err := h.passwords.Verify(password, user.Password)
+	if err != nil {
+		return err
+	}
+
+	if !rehash {
+		return nil
+	}
+
+	hashedPassword, err := h.passwords.Hash(password)
+	if err == nil {
+		err = h.storage.User().UpdatePassword(&repo.UpdatePassword{
+			UserID:   user.ID,
+			Password: hashedPassword,
+		})
+	}
+	if err != nil {
+		log.Printf("failed to rehash password of user %d: %v", user.ID, err)
+		return nil
+	}
+
+	user.Password = hashedPassword
+
+	return nil
+}
+
+// personalData is what a password of the user must not be based on
+func personalData(user *repo.User) []string {
+	data := []string{user.Email, user.FirstName, user.LastName}
+	if user.Username != nil {
+		data = append(data, *user.Username)
+	}
+
+	return data
+}
@@ -0,0 +1,225 @@
+package v1
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ibrat-muslim/blog-app/api/models"
+	"github.com/ibrat-muslim/blog-app/pkg/utils"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+// personalAccessTokenPrefix tells personal access tokens apart from JWTs and makes leaked ones easy to scan for
+const personalAccessTokenPrefix = "bpat_"
+
+var (
+	ErrUnknownScope        = errors.New("unknown scope")
+	ErrExpiresInPast       = errors.New("expiration time must be in the future")
+	ErrScopeNotAllowed     = errors.New("personal access token is not allowed for this action")
+	ErrPersonalTokenRevoke = errors.New("personal access token not found or already revoked")
+)
+
+// @Security ApiKeyAuth
+// @Router /users/me/tokens [get]
+// @Summary Get personal access tokens
+// @Description Get personal access tokens of the current user
+// @Tags user
+// @Accept json
+// @Produce json
+// @Success 200 {object} []models.PersonalAccessToken
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetPersonalAccessTokens(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	result, err := h.storage.PersonalAccessToken().GetAll(payload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]*models.PersonalAccessToken, 0, len(result))
+	for _, token := range result {
+		t := parsePersonalAccessTokenToModel(token)
+		response = append(response, &t)
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /users/me/tokens [post]
+// @Summary Create a personal access token
+// @Description The token is returned only once, scopes: users:read, users:write, posts:write, categories:write, comments:read, comments:write, likes:read, likes:write, files:read, files:write
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param data body models.CreatePersonalAccessTokenRequest true "Data"
+// @Success 201 {object} models.CreatePersonalAccessTokenResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) CreatePersonalAccessToken(ctx *gin.Context) {
+	var req models.CreatePersonalAccessTokenRequest
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !utils.IsValidScope(scope) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("%w: %s", ErrUnknownScope, scope)))
+			return
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrExpiresInPast))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	secret, err := randomToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	token := personalAccessTokenPrefix + secret
+
+	result, err := h.storage.PersonalAccessToken().Create(&repo.PersonalAccessToken{
+		UserID:      payload.UserID,
+		Name:        req.Name,
+		TokenHash:   hashPersonalAccessToken(token),
+		TokenPrefix: token[:len(personalAccessTokenPrefix)+8],
+		Scopes:      req.Scopes,
+		ExpiresAt:   req.ExpiresAt,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, models.CreatePersonalAccessTokenResponse{
+		PersonalAccessToken: parsePersonalAccessTokenToModel(result),
+		Token:               token,
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /users/me/tokens/{id} [delete]
+// @Summary Revoke a personal access token
+// @Description Revoke a personal access token
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.OKResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) RevokePersonalAccessToken(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.storage.PersonalAccessToken().Revoke(id, payload.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ErrPersonalTokenRevoke))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.OKResponse{
+		Message: "Personal access token has been revoked",
+	})
+}
+
+// verifyPersonalAccessToken builds the payload of an active token and records its usage
+func (h *handlerV1) verifyPersonalAccessToken(ctx *gin.Context, token string) (*utils.Payload, error) {
+	result, err := h.storage.PersonalAccessToken().GetByHash(hashPersonalAccessToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	user, err := h.storage.User().Get(result.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = accountRestriction(user)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.storage.PersonalAccessToken().Touch(result.ID, ctx.ClientIP())
+	if err != nil {
+		log.Printf("failed to record usage of personal access token %d: %v", result.ID, err)
+	}
+
+	// A nil list would mean an unscoped session token
+	scopes := make([]string, 0, len(result.Scopes))
+	scopes = append(scopes, result.Scopes...)
+
+	payload := &utils.Payload{
+		UserID:   user.ID,
+		Email:    user.Email,
+		UserType: user.Type,
+		Scopes:   scopes,
+		IssuedAt: result.CreatedAt,
+	}
+	if result.ExpiresAt != nil {
+		payload.ExpiredAt = *result.ExpiresAt
+	}
+
+	return payload, nil
+}
+
+func hashPersonalAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func parsePersonalAccessTokenToModel(token *repo.PersonalAccessToken) models.PersonalAccessToken {
+	return models.PersonalAccessToken{
+		ID:          token.ID,
+		Name:        token.Name,
+		TokenPrefix: token.TokenPrefix,
+		Scopes:      token.Scopes,
+		ExpiresAt:   token.ExpiresAt,
+		LastUsedAt:  token.LastUsedAt,
+		LastUsedIP:  token.LastUsedIP,
+		RevokedAt:   token.RevokedAt,
+		CreatedAt:   token.CreatedAt,
+	}
+}
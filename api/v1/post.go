@@ -9,6 +9,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/ibrat-muslim/blog-app/api/models"
+	"github.com/ibrat-muslim/blog-app/pkg/upload"
 	"github.com/ibrat-muslim/blog-app/storage/repo"
 )
 
@@ -39,19 +40,41 @@ func (h *handlerV1) CreatePost(ctx *gin.Context) {
 		return
 	}
 
-	resp, err := h.storage.Post().Create(&repo.Post{
+	newPost := &repo.Post{
 		Title:       req.Title,
 		Description: req.Description,
 		ImageUrl:    req.ImageUrl,
 		UserID:      payload.UserID,
 		CategoryID:  req.CategoryID,
-	})
+	}
+
+	err = h.setPostGallery(newPost, &req)
+	if err != nil {
+		if isGalleryError(err) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp, err := h.storage.Post().Create(newPost)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, parsePostToModel(resp))
+	post := parsePostToModel(resp)
+	post.ImageVariants = h.imageVariants(post.ImageUrl, upload.PurposePostImage)
+
+	post.Gallery = make([]*models.PostMedia, 0, len(resp.Media))
+	for _, item := range resp.Media {
+		post.Gallery = append(post.Gallery, h.parsePostMediaToModel(item))
+	}
+
+	h.dispatchWebhook(repo.WebhookEventPostCreated, h.parsePostToWebhook(resp))
+
+	ctx.JSON(http.StatusCreated, post)
 }
 
 // @Router /posts/{id} [get]
@@ -83,7 +106,27 @@ func (h *handlerV1) GetPost(ctx *gin.Context) {
 		return
 	}
 
+	if h.cfg.Moderation.HideBannedContent {
+		author, err := h.storage.User().Get(resp.UserID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if author.Banned {
+			ctx.JSON(http.StatusNotFound, errorResponse(sql.ErrNoRows))
+			return
+		}
+	}
+
 	post := parsePostToModel(resp)
+	post.ImageVariants = h.imageVariants(post.ImageUrl, upload.PurposePostImage)
+
+	err = h.setGalleries(&post)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
 
 	likeInfo, err := h.storage.Like().GetLikesDislikesCount(post.ID)
 	if err != nil {
@@ -180,6 +223,7 @@ func (h *handlerV1) GetPosts(ctx *gin.Context) {
 		UserID:     request.UserID,
 		CategoryID: request.CategoryID,
 		SortByDate: request.SortByDate,
+		HideBanned: h.cfg.Moderation.HideBannedContent,
 	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
@@ -203,6 +247,7 @@ func getPostsResponse(h *handlerV1, data *repo.GetPostsResult) (*models.GetPosts
 
 	for _, post := range data.Posts {
 		p := parsePostToModel(post)
+		p.ImageVariants = h.imageVariants(p.ImageUrl, upload.PurposePostImage)
 
 		likeInfo, err := h.storage.Like().GetLikesDislikesCount(p.ID)
 		if err != nil {
@@ -217,6 +262,11 @@ func getPostsResponse(h *handlerV1, data *repo.GetPostsResult) (*models.GetPosts
 		response.Posts = append(response.Posts, &p)
 	}
 
+	err := h.setGalleries(response.Posts...)
+	if err != nil {
+		return nil, err
+	}
+
 	return &response, nil
 }
 
@@ -248,16 +298,39 @@ func (h *handlerV1) UpdatePost(ctx *gin.Context) {
 		return
 	}
 
+	authorID, err := h.storage.Post().GetAuthorID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
 	updatedAt := time.Now()
 
-	err = h.storage.Post().Update(&repo.Post{
+	post := &repo.Post{
 		ID:          id,
 		Title:       req.Title,
 		Description: req.Description,
 		ImageUrl:    req.ImageUrl,
+		UserID:      authorID,
 		CategoryID:  req.CategoryID,
 		UpdatedAt:   &updatedAt,
-	})
+	}
+
+	err = h.setPostGallery(post, &req)
+	if err != nil {
+		if isGalleryError(err) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.storage.Post().Update(post)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -268,6 +341,8 @@ func (h *handlerV1) UpdatePost(ctx *gin.Context) {
 		return
 	}
 
+	h.dispatchWebhook(repo.WebhookEventPostUpdated, h.parsePostToWebhook(post))
+
 	ctx.JSON(http.StatusOK, models.OKResponse{
 		Message: "successfully updated",
 	})
@@ -302,6 +377,11 @@ func (h *handlerV1) DeletePost(ctx *gin.Context) {
 		return
 	}
 
+	h.dispatchWebhook(repo.WebhookEventPostDeleted, models.WebhookPost{
+		ID:  id,
+		URL: h.postURL(id),
+	})
+
 	ctx.JSON(http.StatusOK, models.OKResponse{
 		Message: "successfully deleted",
 	})
@@ -309,13 +389,14 @@ func (h *handlerV1) DeletePost(ctx *gin.Context) {
 
 func parsePostToModel(post *repo.Post) models.Post {
 	return models.Post{
-		ID:          post.ID,
-		Title:       post.Title,
-		Description: post.Description,
-		ImageUrl:    post.ImageUrl,
-		UserID:      post.UserID,
-		CategoryID:  post.CategoryID,
-		CreatedAt:   post.CreatedAt,
-		ViewsCount:  post.ViewsCount,
+		ID:           post.ID,
+		Title:        post.Title,
+		Description:  post.Description,
+		ImageUrl:     post.ImageUrl,
+		UserID:       post.UserID,
+		CategoryID:   post.CategoryID,
+		CreatedAt:    post.CreatedAt,
+		ViewsCount:   post.ViewsCount,
+		CoverMediaID: post.CoverMediaID,
 	}
 }
@@ -0,0 +1,130 @@
+package v1
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ibrat-muslim/blog-app/api/models"
+	"github.com/ibrat-muslim/blog-app/pkg/blob"
+	"github.com/ibrat-muslim/blog-app/pkg/upload"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+var (
+	ErrMediaNotOwned  = errors.New("gallery can only contain files uploaded by the author of the post")
+	ErrDuplicateMedia = errors.New("gallery contains the same file more than once")
+	ErrInvalidCover   = errors.New("cover must be an image of the gallery uploaded for posts")
+)
+
+func isGalleryError(err error) bool {
+	return errors.Is(err, ErrMediaNotOwned) || errors.Is(err, ErrDuplicateMedia) || errors.Is(err, ErrInvalidCover)
+}
+
+// setPostGallery checks the gallery of the request against the uploads of the author
+// and sets it on the post, a chosen cover becomes the image of the post
+func (h *handlerV1) setPostGallery(post *repo.Post, req *models.CreatePostRequest) error {
+	ids := make([]int64, 0, len(req.Gallery))
+	seen := make(map[int64]bool, len(req.Gallery))
+
+	for _, item := range req.Gallery {
+		if seen[item.MediaID] {
+			return ErrDuplicateMedia
+		}
+		seen[item.MediaID] = true
+		ids = append(ids, item.MediaID)
+	}
+
+	if req.CoverMediaID != nil && !seen[*req.CoverMediaID] {
+		return ErrInvalidCover
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	uploads, err := h.storage.Media().GetByIDs(ids)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[int64]*repo.Media, len(uploads))
+	for _, media := range uploads {
+		byID[media.ID] = media
+	}
+
+	post.Media = make([]*repo.PostMedia, 0, len(req.Gallery))
+
+	for _, item := range req.Gallery {
+		media, ok := byID[item.MediaID]
+		if !ok || media.UserID == nil || *media.UserID != post.UserID {
+			return ErrMediaNotOwned
+		}
+
+		post.Media = append(post.Media, &repo.PostMedia{
+			MediaID:  media.ID,
+			Caption:  item.Caption,
+			AltText:  item.AltText,
+			Path:     media.Path,
+			Purpose:  media.Purpose,
+			MimeType: media.MimeType,
+			Width:    media.Width,
+			Height:   media.Height,
+		})
+	}
+
+	if req.CoverMediaID != nil {
+		cover := byID[*req.CoverMediaID]
+		if cover.Purpose != upload.PurposePostImage || !strings.HasPrefix(cover.MimeType, "image/") {
+			return ErrInvalidCover
+		}
+
+		imageURL := blob.URLPrefix + cover.Path
+		post.ImageUrl = &imageURL
+		post.CoverMediaID = &cover.ID
+	}
+
+	return nil
+}
+
+// setGalleries fills the galleries of the posts with one query
+func (h *handlerV1) setGalleries(posts ...*models.Post) error {
+	ids := make([]int64, 0, len(posts))
+	byID := make(map[int64]*models.Post, len(posts))
+
+	for _, post := range posts {
+		post.Gallery = make([]*models.PostMedia, 0)
+		ids = append(ids, post.ID)
+		byID[post.ID] = post
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	items, err := h.storage.Post().GetMedia(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		post := byID[item.PostID]
+		post.Gallery = append(post.Gallery, h.parsePostMediaToModel(item))
+	}
+
+	return nil
+}
+
+func (h *handlerV1) parsePostMediaToModel(item *repo.PostMedia) *models.PostMedia {
+	url := blob.URLPrefix + item.Path
+
+	return &models.PostMedia{
+		MediaID:  item.MediaID,
+		URL:      url,
+		MimeType: item.MimeType,
+		Width:    item.Width,
+		Height:   item.Height,
+		Caption:  item.Caption,
+		AltText:  item.AltText,
+		Variants: h.imageVariants(&url, item.Purpose),
+	}
+}
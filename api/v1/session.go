@@ -0,0 +1,209 @@
+package v1
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ibrat-muslim/blog-app/api/models"
+	"github.com/ibrat-muslim/blog-app/pkg/utils"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+const (
+	sessionKey     = "session_"
+	sessionSeenKey = "session_seen_"
+
+	// sessionSeenInterval limits how often last seen time is written to the database
+	sessionSeenInterval = 5 * time.Minute
+	maxUserAgentLength  = 512
+)
+
+var (
+	ErrSessionRevoked  = errors.New("session has been revoked, please log in again")
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// createSession records the device the user logs in from and caches the session as active
+func (h *handlerV1) createSession(ctx *gin.Context, user *repo.User, duration time.Duration) (uuid.UUID, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	userAgent := ctx.GetHeader("User-Agent")
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	session, err := h.storage.Session().Create(&repo.Session{
+		ID:         id.String(),
+		UserID:     user.ID,
+		DeviceName: utils.DeviceName(userAgent),
+		UserAgent:  userAgent,
+		IP:         ctx.ClientIP(),
+		ExpiresAt:  time.Now().Add(duration),
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = h.inMemory.Set(sessionKey+session.ID, strconv.FormatInt(user.ID, 10), duration)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return id, nil
+}
+
+// checkSession rejects tokens of revoked sessions, active sessions are served from the cache
+func (h *handlerV1) checkSession(ctx *gin.Context, payload *utils.Payload) error {
+	// Tokens issued before sessions were tracked could not be revoked, their users log in again
+	if payload.SessionID == uuid.Nil {
+		return ErrSessionRevoked
+	}
+
+	id := payload.SessionID.String()
+
+	_, err := h.inMemory.Get(sessionKey + id)
+	if err != nil {
+		// The cache may have been flushed, the database has the final say
+		session, err := h.storage.Session().GetActive(id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrSessionRevoked
+			}
+			return err
+		}
+
+		err = h.inMemory.Set(sessionKey+id, strconv.FormatInt(session.UserID, 10), time.Until(session.ExpiresAt))
+		if err != nil {
+			return err
+		}
+	}
+
+	ok, err := h.inMemory.SetNX(sessionSeenKey+id, "1", sessionSeenInterval)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		err = h.storage.Session().Touch(id, ctx.ClientIP())
+		if err != nil {
+			log.Printf("failed to update last seen time of session %s: %v", id, err)
+		}
+	}
+
+	return nil
+}
+
+// revokeSessions ends every session of the user except the given one
+func (h *handlerV1) revokeSessions(userID int64, exceptID uuid.UUID) error {
+	ids, err := h.storage.Session().RevokeAll(userID, exceptID.String())
+	if err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, sessionKey+id)
+	}
+
+	return h.inMemory.Del(keys...)
+}
+
+func (h *handlerV1) revokeSession(id string, userID int64) error {
+	err := h.storage.Session().Revoke(id, userID)
+	if err != nil {
+		return err
+	}
+
+	return h.inMemory.Del(sessionKey + id)
+}
+
+// @Security ApiKeyAuth
+// @Router /users/me/sessions [get]
+// @Summary Get active sessions
+// @Description Get the devices the current user is logged in from
+// @Tags user
+// @Accept json
+// @Produce json
+// @Success 200 {object} []models.Session
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetSessions(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	result, err := h.storage.Session().GetAll(payload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]*models.Session, 0, len(result))
+	for _, session := range result {
+		response = append(response, &models.Session{
+			ID:         session.ID,
+			DeviceName: session.DeviceName,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == payload.SessionID.String(),
+		})
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /users/me/sessions/{id} [delete]
+// @Summary Revoke a session
+// @Description Logs the device out
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param id path string true "ID"
+// @Success 200 {object} models.OKResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) RevokeSession(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.revokeSession(id.String(), payload.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ErrSessionNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.OKResponse{
+		Message: "Session has been revoked",
+	})
+}
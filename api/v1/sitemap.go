@@ -0,0 +1,147 @@
+package v1
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ibrat-muslim/blog-app/pkg/sitemap"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+// sitemapKey caches rendered sitemaps, "index" or the number of the sitemap follows it
+const sitemapKey = "sitemap_"
+
+var sitemapPaths = map[string]string{
+	repo.SitemapPagePost:     "/posts/",
+	repo.SitemapPageCategory: "/categories/",
+	repo.SitemapPageAuthor:   "/authors/",
+}
+
+// @Router /sitemap.xml [get]
+// @Summary Get the sitemap
+// @Description Get the posts, categories and authors, a sitemap index is served instead when there are more than 50000 of them
+// @Tags sitemap
+// @Produce xml
+// @Success 200 {string} string
+// @Success 304
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetSitemap(ctx *gin.Context) {
+	h.serveCached(ctx, sitemapKey+"index", h.cfg.Sitemap.CacheTTL, sitemap.ContentType, func() ([]byte, time.Time, error) {
+		result, err := h.getSitemapPages(1)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+
+		if result.Count <= sitemap.MaxURLs {
+			return h.renderSitemap(result.Pages)
+		}
+
+		count := (result.Count + sitemap.MaxURLs - 1) / sitemap.MaxURLs
+
+		sitemaps := make([]*sitemap.URL, 0, count)
+		for i := int32(1); i <= count; i++ {
+			sitemaps = append(sitemaps, &sitemap.URL{
+				Loc: fmt.Sprintf("%s/sitemaps/%d.xml", h.cfg.Site.BaseURL, i),
+			})
+		}
+
+		body, err := sitemap.RenderIndex(sitemaps)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+
+		return body, time.Now(), nil
+	})
+}
+
+// @Router /sitemaps/{page} [get]
+// @Summary Get a sitemap of the index
+// @Description Get the pages of a sitemap listed by the sitemap index
+// @Tags sitemap
+// @Produce xml
+// @Param page path string true "Number of the sitemap followed by .xml"
+// @Success 200 {string} string
+// @Success 304
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetSitemapPage(ctx *gin.Context) {
+	page, err := strconv.ParseInt(strings.TrimSuffix(ctx.Param("page"), ".xml"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if page < 1 {
+		ctx.JSON(http.StatusNotFound, errorResponse(sql.ErrNoRows))
+		return
+	}
+
+	key := sitemapKey + strconv.FormatInt(page, 10)
+
+	h.serveCached(ctx, key, h.cfg.Sitemap.CacheTTL, sitemap.ContentType, func() ([]byte, time.Time, error) {
+		result, err := h.getSitemapPages(int32(page))
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+
+		if len(result.Pages) == 0 {
+			return nil, time.Time{}, sql.ErrNoRows
+		}
+
+		return h.renderSitemap(result.Pages)
+	})
+}
+
+// @Router /robots.txt [get]
+// @Summary Get robots.txt
+// @Description Get the paths crawlers must not visit and the link of the sitemap
+// @Tags sitemap
+// @Produce plain
+// @Success 200 {string} string
+func (h *handlerV1) GetRobots(ctx *gin.Context) {
+	robots := sitemap.Robots(h.cfg.Sitemap.RobotsDisallow, h.cfg.Site.BaseURL+"/sitemap.xml")
+
+	ctx.Data(http.StatusOK, "text/plain; charset=utf-8", robots)
+}
+
+func (h *handlerV1) getSitemapPages(page int32) (*repo.GetSitemapResult, error) {
+	return h.storage.Sitemap().GetAll(&repo.GetSitemapParams{
+		Limit:      sitemap.MaxURLs,
+		Page:       page,
+		HideBanned: h.cfg.Moderation.HideBannedContent,
+	})
+}
+
+// renderSitemap also returns the latest change of the pages
+func (h *handlerV1) renderSitemap(pages []*repo.SitemapPage) ([]byte, time.Time, error) {
+	var updated time.Time
+
+	urls := make([]*sitemap.URL, 0, len(pages))
+	for _, page := range pages {
+		urls = append(urls, &sitemap.URL{
+			Loc:     h.cfg.Site.BaseURL + sitemapPaths[page.Kind] + strconv.FormatInt(page.ID, 10),
+			LastMod: page.LastMod,
+		})
+
+		if page.LastMod.After(updated) {
+			updated = page.LastMod
+		}
+	}
+
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+
+	body, err := sitemap.RenderURLSet(urls)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return body, updated, nil
+}
@@ -0,0 +1,478 @@
+package v1
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ibrat-muslim/blog-app/api/models"
+	"github.com/ibrat-muslim/blog-app/pkg/totp"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+const (
+	twoFactorChallengeKey = "two_factor_challenge_"
+	twoFactorEnrollKey    = "two_factor_enroll_"
+	totpUsedStepKey       = "totp_used_step_"
+
+	twoFactorChallengeTTL = 5 * time.Minute
+	twoFactorEnrollTTL    = 10 * time.Minute
+	recoveryCodesCount    = 10
+)
+
+var (
+	ErrTwoFactorRequired        = errors.New("two-factor authentication enrollment is required")
+	ErrTwoFactorAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled      = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorEnrollExpired   = errors.New("two-factor enrollment has expired, please start again")
+	ErrIncorrectTwoFactorCode   = errors.New("incorrect two-factor code")
+	ErrChallengeExpired         = errors.New("login challenge has expired, please log in again")
+	ErrTwoFactorRequiredByAdmin = errors.New("two-factor authentication cannot be disabled for superadmins")
+)
+
+type twoFactorChallenge struct {
+	UserID   int64         `json:"user_id"`
+	Duration time.Duration `json:"duration"`
+}
+
+// startTwoFactorChallenge responds with a short-lived challenge instead of an access token
+func (h *handlerV1) startTwoFactorChallenge(ctx *gin.Context, user *repo.User, duration time.Duration) {
+	token, err := randomToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	data, err := json.Marshal(twoFactorChallenge{
+		UserID:   user.ID,
+		Duration: duration,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.inMemory.Set(twoFactorChallengeKey+token, string(data), twoFactorChallengeTTL)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, models.TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresAt:         time.Now().Add(twoFactorChallengeTTL),
+	})
+}
+
+// @Router /auth/login/2fa [post]
+// @Summary Complete login with a two-factor code
+// @Description Exchanges the login challenge and a one-time password or recovery code for an access token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.TwoFactorLoginRequest true "Data"
+// @Success 201 {object} models.AuthResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) LoginTwoFactor(ctx *gin.Context) {
+	var req models.TwoFactorLoginRequest
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if h.abortIfBlocked(ctx, h.ipLimiter, ctx.ClientIP()) {
+		return
+	}
+
+	data, err := h.inMemory.Get(twoFactorChallengeKey + req.ChallengeToken)
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrChallengeExpired))
+		return
+	}
+
+	var challenge twoFactorChallenge
+	err = json.Unmarshal([]byte(data), &challenge)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err := h.storage.User().Get(challenge.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// The user may have been banned while the challenge was pending
+	if h.abortIfRestricted(ctx, user) {
+		return
+	}
+
+	ok, err := h.checkSecondFactor(user, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !ok {
+		_, err = h.ipLimiter.Fail(ctx.ClientIP())
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		// A challenge only survives a few wrong codes, then the password is required again
+		result, err := h.codeLimiter.Fail(twoFactorChallengeKey + req.ChallengeToken)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if result.Locked {
+			log.Printf("login challenge of user %d has been invalidated after %d incorrect codes", user.ID, result.Attempts)
+
+			err = h.inMemory.Del(twoFactorChallengeKey + req.ChallengeToken)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+		}
+
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrIncorrectTwoFactorCode))
+		return
+	}
+
+	err = h.inMemory.Del(twoFactorChallengeKey + req.ChallengeToken)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response, err := h.createAuthResponse(ctx, user, challenge.Duration, true)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /auth/2fa/enroll [post]
+// @Summary Start two-factor enrollment
+// @Description Returns a new secret, it becomes active after confirmation with a first code
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.TwoFactorEnrollResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) EnrollTwoFactor(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err := h.storage.User().Get(payload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.TotpEnabled {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrTwoFactorAlreadyEnabled))
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.inMemory.Set(twoFactorEnrollKey+strconv.FormatInt(user.ID, 10), secret, twoFactorEnrollTTL)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.TwoFactorEnrollResponse{
+		Secret:     secret,
+		OtpauthURI: totp.URI(h.cfg.TwoFactor.Issuer, user.Email, secret),
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /auth/2fa/confirm [post]
+// @Summary Confirm two-factor enrollment
+// @Description Enables two-factor authentication and returns one-time recovery codes
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.TwoFactorCodeRequest true "Data"
+// @Success 200 {object} models.TwoFactorConfirmResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) ConfirmTwoFactor(ctx *gin.Context) {
+	var req models.TwoFactorCodeRequest
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	enrollKey := twoFactorEnrollKey + strconv.FormatInt(payload.UserID, 10)
+
+	secret, err := h.inMemory.Get(enrollKey)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrTwoFactorEnrollExpired))
+		return
+	}
+
+	step, ok := totp.Validate(secret, req.Code, time.Now())
+	if !ok {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrIncorrectTwoFactorCode))
+		return
+	}
+
+	err = h.inMemory.Set(totpUsedStepKey+strconv.FormatInt(payload.UserID, 10), strconv.FormatInt(step, 10), 2*totp.Skew*totp.Period+totp.Period)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.storage.RecoveryCode().Replace(payload.UserID, hashes)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.storage.User().UpdateTwoFactor(&repo.UpdateTwoFactor{
+		UserID:  payload.UserID,
+		Secret:  &secret,
+		Enabled: true,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.inMemory.Del(enrollKey)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err := h.storage.User().Get(payload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// The current token was issued without the second factor, replace it
+	response, err := h.createAuthResponse(ctx, user, accessTokenDuration, true)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if payload.SessionID != uuid.Nil {
+		err = h.revokeSession(payload.SessionID.String(), payload.UserID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, models.TwoFactorConfirmResponse{
+		RecoveryCodes: codes,
+		AccessToken:   response.AccessToken,
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /auth/2fa/disable [post]
+// @Summary Disable two-factor authentication
+// @Description Disable two-factor authentication
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.TwoFactorCodeRequest true "Data"
+// @Success 200 {object} models.OKResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) DisableTwoFactor(ctx *gin.Context) {
+	var req models.TwoFactorCodeRequest
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if payload.UserType == repo.UserTypeSuperAdmin && h.cfg.TwoFactor.RequireSuperAdmin {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrTwoFactorRequiredByAdmin))
+		return
+	}
+
+	user, err := h.storage.User().Get(payload.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !user.TotpEnabled {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ErrTwoFactorNotEnabled))
+		return
+	}
+
+	ok, err := h.checkSecondFactor(user, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !ok {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrIncorrectTwoFactorCode))
+		return
+	}
+
+	err = h.storage.User().UpdateTwoFactor(&repo.UpdateTwoFactor{
+		UserID:  user.ID,
+		Enabled: false,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = h.storage.RecoveryCode().DeleteAll(user.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.OKResponse{
+		Message: "Two-factor authentication has been disabled",
+	})
+}
+
+// checkSecondFactor accepts a one-time password that has not been used yet or an unused recovery code
+func (h *handlerV1) checkSecondFactor(user *repo.User, code string) (bool, error) {
+	if !user.TotpEnabled || user.TotpSecret == nil {
+		return false, nil
+	}
+
+	code = strings.TrimSpace(code)
+
+	step, ok := totp.Validate(*user.TotpSecret, code, time.Now())
+	if ok {
+		usedKey := totpUsedStepKey + strconv.FormatInt(user.ID, 10)
+
+		lastStep, err := h.inMemory.Get(usedKey)
+		if err == nil {
+			last, _ := strconv.ParseInt(lastStep, 10, 64)
+			if step <= last {
+				return false, nil
+			}
+		}
+
+		err = h.inMemory.Set(usedKey, strconv.FormatInt(step, 10), 2*totp.Skew*totp.Period+totp.Period)
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	err := h.storage.RecoveryCode().Use(user.ID, hashRecoveryCode(code))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// generateRecoveryCodes returns the codes shown to the user once and their hashes for storage
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	for i := 0; i < recoveryCodesCount; i++ {
+		b := make([]byte, 7)
+		_, err := rand.Read(b)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(encoding.EncodeToString(b))[:10]
+		code = code[:5] + "-" + code[5:]
+
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignores case and separators, recovery codes are random enough for sha256
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/ibrat-muslim/blog-app/api/models"
+	"github.com/ibrat-muslim/blog-app/pkg/upload"
 	"github.com/ibrat-muslim/blog-app/storage/repo"
 )
 
@@ -21,34 +22,63 @@ import (
 // @Param user body models.CreateUserRequest true "User"
 // @Success 201 {object} models.User
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) CreateUser(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if payload.UserType != repo.UserTypeSuperAdmin {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrForbidden))
+		return
+	}
 
 	var req models.CreateUserRequest
 
-	err := ctx.ShouldBindJSON(&req)
+	err = ctx.ShouldBindJSON(&req)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
 
-	resp, err := h.storage.User().Create(&repo.User{
+	user := &repo.User{
 		FirstName:       req.FirstName,
 		LastName:        req.LastName,
 		PhoneNumber:     req.PhoneNumber,
 		Email:           req.Email,
 		Gender:          req.Gender,
-		Password:        req.Password,
 		Username:        req.Username,
 		ProfileImageUrl: req.ProfileImageUrl,
 		Type:            req.Type,
-	})
+		Language:        req.Language,
+	}
+
+	err = h.passwords.Validate(req.Password, personalData(user)...)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user.Password, err = h.passwords.Hash(req.Password)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, parseUserToModel(resp))
+	resp, err := h.storage.User().Create(user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	result := parseUserToModel(resp)
+
+	audit(ctx, "user.create", "user", result.ID, nil, result)
+
+	ctx.JSON(http.StatusCreated, result)
 }
 
 // @Router /users/{id} [get]
@@ -80,7 +110,10 @@ func (h *handlerV1) GetUser(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, parseUserToModel(resp))
+	user := parseUserToModel(resp)
+	user.ProfileImageVariants = h.imageVariants(user.ProfileImageUrl, upload.PurposeAvatar)
+
+	ctx.JSON(http.StatusOK, user)
 }
 
 // @Security ApiKeyAuth
@@ -105,7 +138,11 @@ func (h *handlerV1) GetUserProfile(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, parseUserToModel(resp))
+	user := parseUserToModel(resp)
+	user.ProfileImageVariants = h.imageVariants(user.ProfileImageUrl, upload.PurposeAvatar)
+	user.DeletionScheduledAt = resp.DeletionScheduledAt
+
+	ctx.JSON(http.StatusOK, user)
 }
 
 // @Router /users [get]
@@ -135,10 +172,10 @@ func (h *handlerV1) GetUsers(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, getUsersResponse(result))
+	ctx.JSON(http.StatusOK, getUsersResponse(h, result))
 }
 
-func getUsersResponse(data *repo.GetUsersResult) *models.GetUsersResponse {
+func getUsersResponse(h *handlerV1, data *repo.GetUsersResult) *models.GetUsersResponse {
 	response := models.GetUsersResponse{
 		Users: make([]*models.User, 0),
 		Count: data.Count,
@@ -146,6 +183,7 @@ func getUsersResponse(data *repo.GetUsersResult) *models.GetUsersResponse {
 
 	for _, user := range data.Users {
 		u := parseUserToModel(user)
+		u.ProfileImageVariants = h.imageVariants(u.ProfileImageUrl, upload.PurposeAvatar)
 		response.Users = append(response.Users, &u)
 	}
 
@@ -160,15 +198,27 @@ func getUsersResponse(data *repo.GetUsersResult) *models.GetUsersResponse {
 // @Accept json
 // @Produce json
 // @Param id path int true "ID"
-// @Param user body models.CreateUserRequest true "User"
+// @Param user body models.UpdateUserRequest true "User"
 // @Success 200 {object} models.OKResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) UpdateUser(ctx *gin.Context) {
-	var req models.CreateUserRequest
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if payload.UserType != repo.UserTypeSuperAdmin {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrForbidden))
+		return
+	}
+
+	var req models.UpdateUserRequest
 
-	err := ctx.ShouldBindJSON(&req)
+	err = ctx.ShouldBindJSON(&req)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
@@ -180,18 +230,7 @@ func (h *handlerV1) UpdateUser(ctx *gin.Context) {
 		return
 	}
 
-	err = h.storage.User().Update(&repo.User{
-		ID:              id,
-		FirstName:       req.FirstName,
-		LastName:        req.LastName,
-		PhoneNumber:     req.PhoneNumber,
-		Email:           req.Email,
-		Gender:          req.Gender,
-		Password:        req.Password,
-		Username:        req.Username,
-		ProfileImageUrl: req.ProfileImageUrl,
-		Type:            req.Type,
-	})
+	user, err := h.storage.User().Get(id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
@@ -201,6 +240,44 @@ func (h *handlerV1) UpdateUser(ctx *gin.Context) {
 		return
 	}
 
+	before := parseUserToModel(user)
+
+	user.FirstName = req.FirstName
+	user.LastName = req.LastName
+	user.PhoneNumber = req.PhoneNumber
+	user.Gender = req.Gender
+	user.Username = req.Username
+	user.ProfileImageUrl = req.ProfileImageUrl
+	user.Type = req.Type
+	user.Language = req.Language
+
+	// The current password is kept when no new one is given
+	if req.Password != "" {
+		err = h.passwords.Validate(req.Password, personalData(user)...)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+
+		user.Password, err = h.passwords.Hash(req.Password)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	err = h.storage.User().Update(user)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	audit(ctx, "user.update", "user", id, before, parseUserToModel(user))
+
 	ctx.JSON(http.StatusOK, models.OKResponse{
 		Message: "successfully updated",
 	})
@@ -209,23 +286,51 @@ func (h *handlerV1) UpdateUser(ctx *gin.Context) {
 // @Security ApiKeyAuth
 // @Router /users/{id} [delete]
 // @Summary Delete a user
-// @Description Delete a user
+// @Description Delete a user right away, the content is removed or reassigned like on account deletion
 // @Tags user
 // @Accept json
 // @Produce json
 // @Param id path int true "ID"
 // @Success 200 {object} models.OKResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 func (h *handlerV1) DeleteUser(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if payload.UserType != repo.UserTypeSuperAdmin {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrForbidden))
+		return
+	}
+
 	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
 
-	err = h.storage.User().Delete(id)
+	user, err := h.storage.User().Get(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// The placeholder owns the content of deleted accounts
+	if user.Email == repo.DeletedUserEmail {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrForbidden))
+		return
+	}
+
+	err = h.storage.User().DeleteAccount(id, h.cfg.AccountDeletion.RemoveContent)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
@@ -235,13 +340,15 @@ func (h *handlerV1) DeleteUser(ctx *gin.Context) {
 		return
 	}
 
+	audit(ctx, "user.delete", "user", id, parseUserToModel(user), nil)
+
 	ctx.JSON(http.StatusOK, models.OKResponse{
 		Message: "successfully deleted",
 	})
 }
 
 func parseUserToModel(user *repo.User) models.User {
-	return models.User{
+	result := models.User{
 		ID:              user.ID,
 		FirstName:       user.FirstName,
 		LastName:        user.LastName,
@@ -251,6 +358,14 @@ func parseUserToModel(user *repo.User) models.User {
 		Username:        user.Username,
 		ProfileImageUrl: user.ProfileImageUrl,
 		Type:            user.Type,
+		Language:        user.Language,
 		CreatedAt:       user.CreatedAt,
+		Banned:          user.Banned,
 	}
+
+	if isSuspended(user) {
+		result.SuspendedUntil = user.SuspendedUntil
+	}
+
+	return result
 }
@@ -0,0 +1,458 @@
+package v1
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ibrat-muslim/blog-app/pkg/web"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+const (
+	// webExcerptSize is the length of the excerpts in lists and of page descriptions
+	webExcerptSize = 200
+	// webCommentsLimit is how many of the first comments a post page shows
+	webCommentsLimit = 100
+)
+
+// postRelations looks up each author and category of a list of posts once
+type postRelations struct {
+	h          *handlerV1
+	authors    map[int64]*repo.User
+	categories map[int64]*repo.Category
+}
+
+func (h *handlerV1) newPostRelations() *postRelations {
+	return &postRelations{
+		h:          h,
+		authors:    make(map[int64]*repo.User),
+		categories: make(map[int64]*repo.Category),
+	}
+}
+
+func (r *postRelations) author(id int64) (*repo.User, error) {
+	if author, ok := r.authors[id]; ok {
+		return author, nil
+	}
+
+	author, err := r.h.storage.User().Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.authors[id] = author
+	return author, nil
+}
+
+func (r *postRelations) category(id int64) (*repo.Category, error) {
+	if category, ok := r.categories[id]; ok {
+		return category, nil
+	}
+
+	category, err := r.h.storage.Category().Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.categories[id] = category
+	return category, nil
+}
+
+func displayName(user *repo.User) string {
+	return strings.TrimSpace(user.FirstName + " " + user.LastName)
+}
+
+// absoluteURL prefixes links of the site with its public URL
+func (h *handlerV1) absoluteURL(link string) string {
+	if strings.HasPrefix(link, "/") {
+		return h.cfg.Site.BaseURL + link
+	}
+	return link
+}
+
+// renderPage sends the page, a template that fails is logged and answered with a plain error
+func (h *handlerV1) renderPage(ctx *gin.Context, status int, name string, page *web.Page) {
+	page.Site = h.cfg.Site.Title
+
+	var buf bytes.Buffer
+
+	err := h.pages.Render(&buf, name, page)
+	if err != nil {
+		log.Printf("failed to render page %s: %v", name, err)
+		ctx.String(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	ctx.Data(status, web.ContentType, buf.Bytes())
+}
+
+func (h *handlerV1) renderErrorPage(ctx *gin.Context, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		log.Printf("failed to serve page %s: %v", ctx.Request.URL.Path, err)
+	}
+
+	h.renderPage(ctx, status, web.PageError, &web.Page{
+		Meta: web.Meta{
+			Title:   http.StatusText(status),
+			URL:     h.absoluteURL(ctx.Request.URL.Path),
+			Type:    "website",
+			NoIndex: true,
+		},
+		Content: &web.ErrorDetail{
+			Status:  status,
+			Message: http.StatusText(status),
+		},
+	})
+}
+
+// renderNotFoundOr answers missing rows with the not found page and anything else with the error page
+func (h *handlerV1) renderNotFoundOr(ctx *gin.Context, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		h.renderErrorPage(ctx, http.StatusNotFound, err)
+		return
+	}
+	h.renderErrorPage(ctx, http.StatusInternalServerError, err)
+}
+
+// HomePage lists the latest posts
+func (h *handlerV1) HomePage(ctx *gin.Context) {
+	h.renderPostList(ctx, &repo.GetPostsParams{}, &web.PostList{
+		Heading:     h.cfg.Site.Title,
+		Description: h.cfg.Site.Description,
+	}, web.Meta{
+		Title:       h.cfg.Site.Title,
+		Description: h.cfg.Site.Description,
+		Type:        "website",
+		RSS:         h.absoluteURL("/feeds/posts.rss"),
+		Atom:        h.absoluteURL("/feeds/posts.atom"),
+	}, "/")
+}
+
+// CategoryPage lists the latest posts of the category
+func (h *handlerV1) CategoryPage(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		h.renderErrorPage(ctx, http.StatusNotFound, err)
+		return
+	}
+
+	category, err := h.storage.Category().Get(id)
+	if err != nil {
+		h.renderNotFoundOr(ctx, err)
+		return
+	}
+
+	path := fmt.Sprintf("/categories/%d", id)
+
+	h.renderPostList(ctx, &repo.GetPostsParams{CategoryID: id}, &web.PostList{
+		Heading: category.Title,
+	}, web.Meta{
+		Title:       category.Title,
+		Description: fmt.Sprintf("Posts in %s", category.Title),
+		Type:        "website",
+		RSS:         h.absoluteURL("/feeds" + path + ".rss"),
+		Atom:        h.absoluteURL("/feeds" + path + ".atom"),
+	}, path)
+}
+
+// AuthorPage lists the latest posts of the author
+func (h *handlerV1) AuthorPage(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		h.renderErrorPage(ctx, http.StatusNotFound, err)
+		return
+	}
+
+	author, err := h.storage.User().Get(id)
+	if err != nil {
+		h.renderNotFoundOr(ctx, err)
+		return
+	}
+
+	// The placeholder of deleted accounts is not an author
+	if author.Email == repo.DeletedUserEmail || (author.Banned && h.cfg.Moderation.HideBannedContent) {
+		h.renderErrorPage(ctx, http.StatusNotFound, sql.ErrNoRows)
+		return
+	}
+
+	name := displayName(author)
+	path := fmt.Sprintf("/authors/%d", id)
+
+	meta := web.Meta{
+		Title:       name,
+		Description: fmt.Sprintf("Posts by %s", name),
+		Type:        "profile",
+		RSS:         h.absoluteURL("/feeds" + path + ".rss"),
+		Atom:        h.absoluteURL("/feeds" + path + ".atom"),
+	}
+	if author.ProfileImageUrl != nil {
+		meta.Image = h.absoluteURL(*author.ProfileImageUrl)
+	}
+
+	h.renderPostList(ctx, &repo.GetPostsParams{UserID: id}, &web.PostList{
+		Heading: name,
+	}, meta, path)
+}
+
+// SearchPage finds posts by their title, results are not indexed by search engines
+func (h *handlerV1) SearchPage(ctx *gin.Context) {
+	query := strings.TrimSpace(ctx.Query("q"))
+
+	meta := web.Meta{
+		Title:   "Search",
+		Type:    "website",
+		NoIndex: true,
+	}
+
+	content := &web.PostList{
+		Heading: "Search",
+		Query:   query,
+	}
+
+	if query == "" {
+		meta.URL = h.absoluteURL("/search")
+		h.renderPage(ctx, http.StatusOK, web.PageSearch, &web.Page{
+			Meta:    meta,
+			Content: content,
+		})
+		return
+	}
+
+	content.Heading = fmt.Sprintf("Search results for %q", query)
+	meta.Title = content.Heading
+
+	h.renderPostList(ctx, &repo.GetPostsParams{Search: query}, content, meta, "/search?q="+url.QueryEscape(query))
+}
+
+// renderPostList shows the page of posts requested by the page query parameter,
+// path is the link of the first page
+func (h *handlerV1) renderPostList(ctx *gin.Context, params *repo.GetPostsParams, content *web.PostList, meta web.Meta, path string) {
+	page := int64(1)
+	if ctx.Query("page") != "" {
+		var err error
+
+		page, err = strconv.ParseInt(ctx.Query("page"), 10, 32)
+		if err != nil || page < 1 {
+			h.renderErrorPage(ctx, http.StatusNotFound, err)
+			return
+		}
+	}
+
+	params.Limit = h.cfg.Site.PageSize
+	params.Page = int32(page)
+	params.HideBanned = h.cfg.Moderation.HideBannedContent
+
+	result, err := h.storage.Post().GetAll(params)
+	if err != nil {
+		h.renderErrorPage(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	link := func(page int32) string {
+		if page == 1 {
+			return h.absoluteURL(path)
+		}
+
+		separator := "?"
+		if strings.Contains(path, "?") {
+			separator = "&"
+		}
+		return h.absoluteURL(fmt.Sprintf("%s%spage=%d", path, separator, page))
+	}
+
+	pagination := web.NewPagination(params.Page, params.Limit, result.Count, link)
+	if params.Page > pagination.Pages {
+		h.renderErrorPage(ctx, http.StatusNotFound, sql.ErrNoRows)
+		return
+	}
+
+	content.Posts, err = h.postSummaries(result.Posts)
+	if err != nil {
+		h.renderErrorPage(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	meta.URL = link(params.Page)
+
+	name := web.PageList
+	if content.Query != "" {
+		name = web.PageSearch
+	}
+
+	h.renderPage(ctx, http.StatusOK, name, &web.Page{
+		Meta:       meta,
+		Pagination: pagination,
+		Content:    content,
+	})
+}
+
+func (h *handlerV1) postSummaries(posts []*repo.Post) ([]*web.PostSummary, error) {
+	relations := h.newPostRelations()
+
+	summaries := make([]*web.PostSummary, 0, len(posts))
+	for _, post := range posts {
+		author, err := relations.author(post.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		category, err := relations.category(post.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+
+		summary := &web.PostSummary{
+			URL:           fmt.Sprintf("/posts/%d", post.ID),
+			Title:         post.Title,
+			Excerpt:       web.Excerpt(post.Description, webExcerptSize),
+			AuthorName:    displayName(author),
+			AuthorURL:     fmt.Sprintf("/authors/%d", author.ID),
+			CategoryTitle: category.Title,
+			CategoryURL:   fmt.Sprintf("/categories/%d", category.ID),
+			Published:     post.CreatedAt,
+		}
+		if post.ImageUrl != nil {
+			summary.ImageURL = *post.ImageUrl
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, nil
+}
+
+// PostPage shows the post with its gallery and first comments, it counts as a view
+func (h *handlerV1) PostPage(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		h.renderErrorPage(ctx, http.StatusNotFound, err)
+		return
+	}
+
+	resp, err := h.storage.Post().Get(id)
+	if err != nil {
+		h.renderNotFoundOr(ctx, err)
+		return
+	}
+
+	relations := h.newPostRelations()
+
+	author, err := relations.author(resp.UserID)
+	if err != nil {
+		h.renderErrorPage(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	if author.Banned && h.cfg.Moderation.HideBannedContent {
+		h.renderErrorPage(ctx, http.StatusNotFound, sql.ErrNoRows)
+		return
+	}
+
+	category, err := relations.category(resp.CategoryID)
+	if err != nil {
+		h.renderErrorPage(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	post := parsePostToModel(resp)
+
+	err = h.setGalleries(&post)
+	if err != nil {
+		h.renderErrorPage(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	likeInfo, err := h.storage.Like().GetLikesDislikesCount(post.ID)
+	if err != nil {
+		h.renderErrorPage(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	comments, err := h.storage.Comment().GetAll(&repo.GetCommentsParams{
+		Limit:      webCommentsLimit,
+		Page:       1,
+		PostID:     post.ID,
+		HideBanned: h.cfg.Moderation.HideBannedContent,
+	})
+	if err != nil {
+		h.renderErrorPage(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	detail := &web.PostDetail{
+		Title:         post.Title,
+		Body:          post.Description,
+		AuthorName:    displayName(author),
+		AuthorURL:     fmt.Sprintf("/authors/%d", author.ID),
+		CategoryTitle: category.Title,
+		CategoryURL:   fmt.Sprintf("/categories/%d", category.ID),
+		Published:     post.CreatedAt,
+		Updated:       post.UpdatedAt,
+		Views:         post.ViewsCount,
+		Likes:         likeInfo.LikesCount,
+		Dislikes:      likeInfo.DislikesCount,
+		Gallery:       make([]*web.GalleryItem, 0, len(post.Gallery)),
+		Comments:      make([]*web.Comment, 0, len(comments.Comments)),
+	}
+
+	meta := web.Meta{
+		Title:       post.Title,
+		Description: web.Excerpt(post.Description, webExcerptSize),
+		URL:         h.absoluteURL(fmt.Sprintf("/posts/%d", post.ID)),
+		Type:        "article",
+	}
+
+	if post.ImageUrl != nil {
+		detail.ImageURL = *post.ImageUrl
+		meta.Image = h.absoluteURL(*post.ImageUrl)
+	}
+
+	for _, item := range post.Gallery {
+		galleryItem := &web.GalleryItem{
+			URL:    item.URL,
+			Width:  item.Width,
+			Height: item.Height,
+		}
+		if item.Caption != nil {
+			galleryItem.Caption = *item.Caption
+		}
+		if item.AltText != nil {
+			galleryItem.AltText = *item.AltText
+		}
+
+		if post.CoverMediaID != nil && *post.CoverMediaID == item.MediaID {
+			detail.ImageAlt = galleryItem.AltText
+		}
+
+		detail.Gallery = append(detail.Gallery, galleryItem)
+	}
+
+	for _, comment := range comments.Comments {
+		detail.Comments = append(detail.Comments, &web.Comment{
+			AuthorName: strings.TrimSpace(comment.User.FirstName + " " + comment.User.LastName),
+			Body:       comment.Description,
+			Created:    comment.CreatedAt,
+		})
+	}
+
+	h.renderPage(ctx, http.StatusOK, web.PagePost, &web.Page{
+		Meta:    meta,
+		Content: detail,
+	})
+}
+
+// StaticHeaders lets browsers keep assets for a year, their links change with their content
+func StaticHeaders(c *gin.Context) {
+	if c.Query("v") != "" {
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	}
+	c.Next()
+}
@@ -0,0 +1,494 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ibrat-muslim/blog-app/api/models"
+	"github.com/ibrat-muslim/blog-app/pkg/webhook"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+var ErrWebhookURLScheme = errors.New("webhook url must use http or https")
+
+// dispatchWebhook queues the event for every webhook subscribed to it, the worker delivers it.
+// A failure is only logged, the change that raised the event has already been made
+func (h *handlerV1) dispatchWebhook(event string, data interface{}) {
+	body, err := webhook.NewPayload(event, data)
+	if err == nil {
+		_, err = h.storage.WebhookDelivery().Enqueue(event, body)
+	}
+	if err != nil {
+		log.Printf("failed to queue webhook event %s: %v", event, err)
+	}
+}
+
+func (h *handlerV1) postURL(id int64) string {
+	return fmt.Sprintf("%s/posts/%d", h.cfg.Site.BaseURL, id)
+}
+
+func (h *handlerV1) parsePostToWebhook(post *repo.Post) models.WebhookPost {
+	return models.WebhookPost{
+		ID:         post.ID,
+		Title:      post.Title,
+		UserID:     post.UserID,
+		CategoryID: post.CategoryID,
+		URL:        h.postURL(post.ID),
+	}
+}
+
+// @Security ApiKeyAuth
+// @Router /admin/webhooks [post]
+// @Summary Create a webhook
+// @Description Subscribes the URL to the events, the secret signing the deliveries is only returned here
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param data body models.CreateWebhookRequest true "Data"
+// @Success 201 {object} models.CreateWebhookResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) CreateWebhook(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if payload.UserType != repo.UserTypeSuperAdmin {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrForbidden))
+		return
+	}
+
+	req, err := validateWebhookRequest(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		secret, err = randomToken()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	result, err := h.storage.Webhook().Create(&repo.Webhook{
+		URL:       req.URL,
+		Secret:    secret,
+		Events:    req.Events,
+		Active:    active,
+		CreatedBy: &payload.UserID,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	wh := parseWebhookToModel(result)
+
+	audit(ctx, "webhook.create", "webhook", wh.ID, nil, wh)
+
+	ctx.JSON(http.StatusCreated, models.CreateWebhookResponse{
+		Webhook: wh,
+		Secret:  secret,
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /admin/webhooks [get]
+// @Summary Get webhooks
+// @Description Get webhooks
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param filter query models.GetAllParamsRequest false "Filter"
+// @Success 200 {object} models.GetWebhooksResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetWebhooks(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if payload.UserType != repo.UserTypeSuperAdmin {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrForbidden))
+		return
+	}
+
+	request, err := validateGetAllParamsRequest(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	result, err := h.storage.Webhook().GetAll(&repo.GetWebhooksParams{
+		Limit: request.Limit,
+		Page:  request.Page,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := models.GetWebhooksResponse{
+		Webhooks: make([]*models.Webhook, 0),
+		Count:    result.Count,
+	}
+
+	for _, wh := range result.Webhooks {
+		w := parseWebhookToModel(wh)
+		response.Webhooks = append(response.Webhooks, &w)
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /admin/webhooks/{id} [get]
+// @Summary Get a webhook
+// @Description Get a webhook by id
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.Webhook
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetWebhook(ctx *gin.Context) {
+	wh, ok := h.getWebhookForAdmin(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, parseWebhookToModel(wh))
+}
+
+// @Security ApiKeyAuth
+// @Router /admin/webhooks/{id} [put]
+// @Summary Update a webhook
+// @Description Update a webhook, the current secret and state are kept when they are not given
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param data body models.CreateWebhookRequest true "Data"
+// @Success 200 {object} models.Webhook
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) UpdateWebhook(ctx *gin.Context) {
+	req, err := validateWebhookRequest(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	wh, ok := h.getWebhookForAdmin(ctx)
+	if !ok {
+		return
+	}
+
+	before := parseWebhookToModel(wh)
+
+	wh.URL = req.URL
+	wh.Events = req.Events
+	if req.Secret != "" {
+		wh.Secret = req.Secret
+	}
+	if req.Active != nil {
+		wh.Active = *req.Active
+	}
+	updatedAt := time.Now()
+	wh.UpdatedAt = &updatedAt
+
+	err = h.storage.Webhook().Update(wh)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	after := parseWebhookToModel(wh)
+
+	audit(ctx, "webhook.update", "webhook", wh.ID, before, after)
+
+	ctx.JSON(http.StatusOK, after)
+}
+
+// @Security ApiKeyAuth
+// @Router /admin/webhooks/{id} [delete]
+// @Summary Delete a webhook
+// @Description Delete a webhook with its delivery log
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.OKResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) DeleteWebhook(ctx *gin.Context) {
+	wh, ok := h.getWebhookForAdmin(ctx)
+	if !ok {
+		return
+	}
+
+	err := h.storage.Webhook().Delete(wh.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	audit(ctx, "webhook.delete", "webhook", wh.ID, parseWebhookToModel(wh), nil)
+
+	ctx.JSON(http.StatusOK, models.OKResponse{
+		Message: "successfully deleted",
+	})
+}
+
+// @Security ApiKeyAuth
+// @Router /admin/webhooks/{id}/deliveries [get]
+// @Summary Get webhook deliveries
+// @Description Get the deliveries of the webhook with the response code and the error of the latest attempt
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param filter query models.GetWebhookDeliveriesParams false "Filter"
+// @Success 200 {object} models.GetWebhookDeliveriesResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) GetWebhookDeliveries(ctx *gin.Context) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if payload.UserType != repo.UserTypeSuperAdmin {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrForbidden))
+		return
+	}
+
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	request, err := validateGetAllParamsRequest(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	result, err := h.storage.WebhookDelivery().GetAll(&repo.GetWebhookDeliveriesParams{
+		Limit:     request.Limit,
+		Page:      request.Page,
+		WebhookID: id,
+		Status:    ctx.Query("status"),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := models.GetWebhookDeliveriesResponse{
+		Deliveries: make([]*models.WebhookDelivery, 0),
+		Count:      result.Count,
+	}
+
+	for _, delivery := range result.Deliveries {
+		d := parseWebhookDeliveryToModel(delivery)
+		response.Deliveries = append(response.Deliveries, &d)
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// @Security ApiKeyAuth
+// @Router /admin/webhooks/{id}/test [post]
+// @Summary Send a test event
+// @Description Sends a ping event to the webhook right away, inactive webhooks included.
+// @Description The delivery is logged and returned, a failed test is not retried
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.WebhookDelivery
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+func (h *handlerV1) SendTestWebhook(ctx *gin.Context) {
+	wh, ok := h.getWebhookForAdmin(ctx)
+	if !ok {
+		return
+	}
+
+	body, err := webhook.NewPayload(repo.WebhookEventPing, models.WebhookPing{
+		WebhookID: wh.ID,
+		Message:   "This is a test event",
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// The delivery is leased until the attempt ends so the worker does not send it too
+	delivery, err := h.storage.WebhookDelivery().Create(&repo.WebhookDelivery{
+		WebhookID:     wh.ID,
+		Event:         repo.WebhookEventPing,
+		Payload:       body,
+		NextAttemptAt: time.Now().Add(h.cfg.Webhook.Lease),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	code, sendErr := webhook.Send(context.Background(), h.webhookClient, &webhook.Request{
+		URL:        wh.URL,
+		Secret:     wh.Secret,
+		Event:      repo.WebhookEventPing,
+		DeliveryID: delivery.ID,
+		Body:       body,
+	})
+
+	now := time.Now()
+	delivery.Attempts = 1
+	if code != 0 {
+		delivery.ResponseCode = &code
+	}
+
+	if sendErr == nil {
+		err = h.storage.WebhookDelivery().MarkDelivered(delivery.ID, code)
+		delivery.Status = repo.WebhookDeliveryStatusDelivered
+		delivery.DeliveredAt = &now
+	} else {
+		errMsg := sendErr.Error()
+		err = h.storage.WebhookDelivery().MarkFailed(delivery.ID, delivery.ResponseCode, errMsg, now, true)
+		delivery.Status = repo.WebhookDeliveryStatusDead
+		delivery.LastError = &errMsg
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	delivery.NextAttemptAt = now
+
+	ctx.JSON(http.StatusOK, parseWebhookDeliveryToModel(delivery))
+}
+
+// getWebhookForAdmin writes the error response itself and returns false when the webhook can not be read
+func (h *handlerV1) getWebhookForAdmin(ctx *gin.Context) (*repo.Webhook, bool) {
+	payload, err := h.GetAuthPayload(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return nil, false
+	}
+
+	if payload.UserType != repo.UserTypeSuperAdmin {
+		ctx.JSON(http.StatusForbidden, errorResponse(ErrForbidden))
+		return nil, false
+	}
+
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return nil, false
+	}
+
+	wh, err := h.storage.Webhook().Get(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return nil, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return nil, false
+	}
+
+	return wh, true
+}
+
+// validateWebhookRequest also rejects URLs of other schemes, the url binding accepts any
+func validateWebhookRequest(ctx *gin.Context) (*models.CreateWebhookRequest, error) {
+	var req models.CreateWebhookRequest
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, ErrWebhookURLScheme
+	}
+
+	return &req, nil
+}
+
+func parseWebhookToModel(wh *repo.Webhook) models.Webhook {
+	return models.Webhook{
+		ID:        wh.ID,
+		URL:       wh.URL,
+		Events:    wh.Events,
+		Active:    wh.Active,
+		CreatedBy: wh.CreatedBy,
+		CreatedAt: wh.CreatedAt,
+		UpdatedAt: wh.UpdatedAt,
+	}
+}
+
+func parseWebhookDeliveryToModel(delivery *repo.WebhookDelivery) models.WebhookDelivery {
+	return models.WebhookDelivery{
+		ID:            delivery.ID,
+		WebhookID:     delivery.WebhookID,
+		Event:         delivery.Event,
+		Payload:       delivery.Payload,
+		Status:        delivery.Status,
+		Attempts:      delivery.Attempts,
+		ResponseCode:  delivery.ResponseCode,
+		LastError:     delivery.LastError,
+		NextAttemptAt: delivery.NextAttemptAt,
+		CreatedAt:     delivery.CreatedAt,
+		DeliveredAt:   delivery.DeliveredAt,
+	}
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/http"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/jmoiron/sqlx"
@@ -10,7 +12,15 @@ import (
 
 	"github.com/ibrat-muslim/blog-app/api"
 	"github.com/ibrat-muslim/blog-app/config"
+	"github.com/ibrat-muslim/blog-app/pkg/blob"
+	emailPkg "github.com/ibrat-muslim/blog-app/pkg/email"
+	"github.com/ibrat-muslim/blog-app/pkg/imaging"
+	"github.com/ibrat-muslim/blog-app/pkg/password"
+	"github.com/ibrat-muslim/blog-app/pkg/upload"
+	"github.com/ibrat-muslim/blog-app/pkg/utils"
+	"github.com/ibrat-muslim/blog-app/pkg/web"
 	"github.com/ibrat-muslim/blog-app/storage"
+	"github.com/ibrat-muslim/blog-app/worker"
 )
 
 func main() {
@@ -37,10 +47,110 @@ func main() {
 
 	inMemory := storage.NewInMemoryStorage(rdb)
 
+	mailer, err := emailPkg.NewMailer(&cfg)
+	if err != nil {
+		log.Fatalf("failed to create mailer: %v", err)
+	}
+
+	blobs, err := blob.NewBlobStore(&cfg.Blob)
+	if err != nil {
+		log.Fatalf("failed to create blob store: %v", err)
+	}
+
+	images, err := imaging.NewProcessor(&imaging.ProcessorOptions{
+		Blobs: blobs,
+		Variants: map[string]string{
+			upload.PurposePostImage: cfg.Image.PostVariants,
+			upload.PurposeAvatar:    cfg.Image.AvatarVariants,
+		},
+		Format:    cfg.Image.Format,
+		Quality:   cfg.Image.Quality,
+		Workers:   cfg.Image.Workers,
+		QueueSize: cfg.Image.QueueSize,
+	})
+	if err != nil {
+		log.Fatalf("failed to configure image processing: %v", err)
+	}
+
+	emailOutboxWorker := worker.NewEmailOutboxWorker(&worker.EmailOutboxWorkerOptions{
+		Cfg:    &cfg.EmailOutbox,
+		Outbox: strg.EmailOutbox(),
+		Mailer: mailer,
+	})
+	go emailOutboxWorker.Run(context.Background())
+
+	dataExportWorker := worker.NewDataExportWorker(&worker.DataExportWorkerOptions{
+		Cfg:      &cfg.DataExport,
+		Exports:  strg.DataExport(),
+		Users:    strg.User(),
+		Posts:    strg.Post(),
+		Comments: strg.Comment(),
+		Likes:    strg.Like(),
+		Media:    strg.Media(),
+		Blobs:    blobs,
+	})
+	go dataExportWorker.Run(context.Background())
+
+	accountDeletionWorker := worker.NewAccountDeletionWorker(&worker.AccountDeletionWorkerOptions{
+		Cfg:       &cfg.AccountDeletion,
+		Users:     strg.User(),
+		ExportDir: cfg.DataExport.Dir,
+		Media:     strg.Media(),
+		Blobs:     blobs,
+		Images:    images,
+	})
+	go accountDeletionWorker.Run(context.Background())
+
+	mediaCleanupWorker := worker.NewMediaCleanupWorker(&worker.MediaCleanupWorkerOptions{
+		Cfg:    &cfg.Media,
+		Media:  strg.Media(),
+		Blobs:  blobs,
+		Images: images,
+	})
+	go mediaCleanupWorker.Run(context.Background())
+
+	webhookWorker := worker.NewWebhookWorker(&worker.WebhookWorkerOptions{
+		Cfg:        &cfg.Webhook,
+		Deliveries: strg.WebhookDelivery(),
+		Client: &http.Client{
+			Timeout: cfg.Webhook.Timeout,
+		},
+	})
+	go webhookWorker.Run(context.Background())
+
+	keys, err := loadKeySet(&cfg)
+	if err != nil {
+		log.Fatalf("failed to load jwt keys: %v", err)
+	}
+
+	passwords, err := password.New(password.Options{
+		Algorithm:  cfg.Password.Algorithm,
+		BcryptCost: cfg.Password.BcryptCost,
+		Argon2: password.Argon2Params{
+			Memory:      cfg.Password.Argon2Memory,
+			Iterations:  cfg.Password.Argon2Iterations,
+			Parallelism: cfg.Password.Argon2Parallelism,
+		},
+		MinLength: cfg.Password.MinLength,
+	})
+	if err != nil {
+		log.Fatalf("failed to configure password hashing: %v", err)
+	}
+
+	pages, err := web.NewRenderer()
+	if err != nil {
+		log.Fatalf("failed to parse page templates: %v", err)
+	}
+
 	apiServer := api.New(&api.RouterOptions{
-		Cfg:      &cfg,
-		Storage:  strg,
-		InMemory: inMemory,
+		Cfg:       &cfg,
+		Storage:   strg,
+		InMemory:  inMemory,
+		Keys:      keys,
+		Passwords: passwords,
+		Blobs:     blobs,
+		Images:    images,
+		Pages:     pages,
 	})
 
 	err = apiServer.Run(cfg.HttpPort)
@@ -50,3 +160,17 @@ func main() {
 
 	log.Print("Server stopped")
 }
+
+// loadKeySet falls back to HS256 with the shared secret until an asymmetric key is activated
+func loadKeySet(cfg *config.Config) (*utils.KeySet, error) {
+	if cfg.JWT.ActiveKeyID == "" {
+		return utils.NewHS256KeySet(cfg.AuthSecretKey), nil
+	}
+
+	legacySecret := ""
+	if cfg.JWT.AcceptLegacyHS256 {
+		legacySecret = cfg.AuthSecretKey
+	}
+
+	return utils.LoadKeySet(cfg.JWT.KeysDir, cfg.JWT.ActiveKeyID, legacySecret)
+}
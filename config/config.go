@@ -2,17 +2,40 @@ package config
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	HttpPort      string
-	Postgres      PostgresConfig
-	Smtp          Smtp
-	Redis         Redis
-	AuthSecretKey string
+	HttpPort        string
+	Postgres        PostgresConfig
+	Smtp            Smtp
+	Mail            Mail
+	EmailOutbox     EmailOutbox
+	BruteForce      BruteForce
+	TwoFactor       TwoFactor
+	Password        Password
+	OIDC            OIDC
+	MagicLink       MagicLink
+	EmailChange     EmailChange
+	AccountDeletion AccountDeletion
+	DataExport      DataExport
+	Moderation      Moderation
+	Impersonation   Impersonation
+	Blob            Blob
+	Upload          Upload
+	Image           Image
+	Media           Media
+	Site            Site
+	Feed            Feed
+	Sitemap         Sitemap
+	Webhook         Webhook
+	JWT             JWT
+	Redis           Redis
+	AuthSecretKey   string
 }
 
 type PostgresConfig struct {
@@ -24,8 +47,185 @@ type PostgresConfig struct {
 }
 
 type Smtp struct {
+	Host     string
+	Port     string
 	Sender   string
 	Password string
+	FromName string
+	TLSMode  string
+}
+
+type Mail struct {
+	Driver string
+	Dir    string
+}
+
+type EmailOutbox struct {
+	MaxAttempts  int32
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	PollInterval time.Duration
+	Lease        time.Duration
+	BatchSize    int32
+}
+
+type BruteForce struct {
+	FreeAttempts       int64
+	BaseDelay          time.Duration
+	MaxAccountAttempts int64
+	MaxIPAttempts      int64
+	AttemptWindow      time.Duration
+	LockoutDuration    time.Duration
+	MaxCodeAttempts    int64
+	ResendCooldown     time.Duration
+	NotifyLockout      bool
+}
+
+type TwoFactor struct {
+	Issuer            string
+	RequireSuperAdmin bool
+}
+
+// Password.Algorithm is bcrypt or argon2id, hashes made with other settings
+// are replaced on the next login. Argon2Memory is in KiB
+type Password struct {
+	Algorithm         string
+	BcryptCost        int
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+	MinLength         int
+}
+
+// OIDC login is disabled when IssuerURL is empty
+type OIDC struct {
+	ProviderName string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// MagicLink.URL is the page that receives the token, it can be a frontend route
+type MagicLink struct {
+	URL string
+	TTL time.Duration
+}
+
+// EmailChange.UndoURL receives the undo token sent to the old address, it should show the change
+// and post the token back to undo it. UndoTTL is how long the previous owner can take the address back
+type EmailChange struct {
+	UndoURL string
+	UndoTTL time.Duration
+}
+
+// AccountDeletion.RemoveContent deletes the posts and comments of deleted accounts,
+// they are reassigned to a placeholder user otherwise
+type AccountDeletion struct {
+	GracePeriod   time.Duration
+	RemoveContent bool
+	PollInterval  time.Duration
+}
+
+// DataExport archives are written to Dir and removed after TTL
+type DataExport struct {
+	Dir          string
+	TTL          time.Duration
+	PollInterval time.Duration
+	Lease        time.Duration
+	MaxAttempts  int32
+}
+
+// Moderation.HideBannedContent leaves the posts and comments of banned users out of the listings
+type Moderation struct {
+	HideBannedContent bool
+}
+
+// Impersonation tokens expire after TTL
+type Impersonation struct {
+	TTL time.Duration
+}
+
+// Blob.Driver is local or s3, local files are kept in LocalDir. S3 objects are linked through
+// S3PublicURL when the bucket is public and presigned for S3PresignTTL otherwise
+type Blob struct {
+	Driver       string
+	LocalDir     string
+	S3Endpoint   string
+	S3Region     string
+	S3Bucket     string
+	S3AccessKey  string
+	S3SecretKey  string
+	S3PathStyle  bool
+	S3PublicURL  string
+	S3PresignTTL time.Duration
+}
+
+// Upload.MaxSize is in bytes
+type Upload struct {
+	MaxSize int64
+}
+
+// Image variants are "name:WIDTHxHEIGHT" separated by commas, ":crop" fills the box and cuts
+// what overflows it and a zero side is not bounded. Format is jpeg, png or webp, empty keeps
+// the format of the original. Variant links are derived from these settings, so changing them
+// only suits images uploaded afterwards
+type Image struct {
+	PostVariants   string
+	AvatarVariants string
+	Format         string
+	Quality        int
+	Workers        int
+	QueueSize      int
+}
+
+// Media.Quota is how many bytes the uploads of each user can take, zero is unlimited.
+// Uploads no post or profile links are removed once OrphanGracePeriod has passed
+type Media struct {
+	Quota             int64
+	OrphanGracePeriod time.Duration
+	CleanupInterval   time.Duration
+}
+
+// Site.BaseURL is where readers reach the blog, links in feeds and sitemaps are built from it.
+// PageSize is how many posts a page of the site lists
+type Site struct {
+	BaseURL     string
+	Title       string
+	Description string
+	PageSize    int32
+}
+
+// Feed.Size is how many of the latest posts a feed has, feeds are cached for CacheTTL
+type Feed struct {
+	Size     int32
+	CacheTTL time.Duration
+}
+
+// Sitemap.RobotsDisallow are the paths robots.txt keeps crawlers out of
+type Sitemap struct {
+	CacheTTL       time.Duration
+	RobotsDisallow []string
+}
+
+// Webhook deliveries are retried like emails, Timeout bounds a single attempt
+type Webhook struct {
+	MaxAttempts  int32
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	PollInterval time.Duration
+	Lease        time.Duration
+	BatchSize    int32
+	Timeout      time.Duration
+}
+
+// JWT tokens are signed with AuthSecretKey (HS256) while ActiveKeyID is empty
+type JWT struct {
+	KeysDir     string
+	ActiveKeyID string
+	// AcceptLegacyHS256 keeps tokens signed with AuthSecretKey valid after switching to asymmetric keys
+	AcceptLegacyHS256 bool
 }
 
 type Redis struct {
@@ -41,6 +241,80 @@ func Load(path string) Config {
 	conf := viper.New()
 	conf.AutomaticEnv()
 
+	conf.SetDefault("SMTP_HOST", "smtp.gmail.com")
+	conf.SetDefault("SMTP_PORT", "587")
+	conf.SetDefault("SMTP_TLS_MODE", "starttls")
+	conf.SetDefault("MAIL_DRIVER", "smtp")
+	conf.SetDefault("MAIL_DIR", "./mail")
+	conf.SetDefault("EMAIL_OUTBOX_MAX_ATTEMPTS", 8)
+	conf.SetDefault("EMAIL_OUTBOX_BASE_BACKOFF", "30s")
+	conf.SetDefault("EMAIL_OUTBOX_MAX_BACKOFF", "1h")
+	conf.SetDefault("EMAIL_OUTBOX_POLL_INTERVAL", "5s")
+	conf.SetDefault("EMAIL_OUTBOX_LEASE", "2m")
+	conf.SetDefault("EMAIL_OUTBOX_BATCH_SIZE", 20)
+	conf.SetDefault("BRUTE_FORCE_FREE_ATTEMPTS", 3)
+	conf.SetDefault("BRUTE_FORCE_BASE_DELAY", "1s")
+	conf.SetDefault("BRUTE_FORCE_MAX_ACCOUNT_ATTEMPTS", 10)
+	conf.SetDefault("BRUTE_FORCE_MAX_IP_ATTEMPTS", 50)
+	conf.SetDefault("BRUTE_FORCE_ATTEMPT_WINDOW", "15m")
+	conf.SetDefault("BRUTE_FORCE_LOCKOUT_DURATION", "15m")
+	conf.SetDefault("BRUTE_FORCE_MAX_CODE_ATTEMPTS", 5)
+	conf.SetDefault("BRUTE_FORCE_RESEND_COOLDOWN", "1m")
+	conf.SetDefault("TWO_FACTOR_ISSUER", "Blog App")
+	conf.SetDefault("PASSWORD_HASH_ALGORITHM", "bcrypt")
+	conf.SetDefault("PASSWORD_BCRYPT_COST", 10)
+	conf.SetDefault("PASSWORD_ARGON2_MEMORY", 64*1024)
+	conf.SetDefault("PASSWORD_ARGON2_ITERATIONS", 3)
+	conf.SetDefault("PASSWORD_ARGON2_PARALLELISM", 2)
+	conf.SetDefault("PASSWORD_MIN_LENGTH", 8)
+	conf.SetDefault("OIDC_PROVIDER_NAME", "oidc")
+	conf.SetDefault("OIDC_SCOPES", "openid email profile")
+	conf.SetDefault("MAGIC_LINK_URL", "http://localhost:8000/v1/auth/magic-link/consume")
+	conf.SetDefault("MAGIC_LINK_TTL", "15m")
+	conf.SetDefault("EMAIL_CHANGE_UNDO_URL", "http://localhost:8000/v1/auth/email-change/undo")
+	conf.SetDefault("EMAIL_CHANGE_UNDO_TTL", "168h")
+	conf.SetDefault("ACCOUNT_DELETION_GRACE_PERIOD", "720h")
+	conf.SetDefault("ACCOUNT_DELETION_REMOVE_CONTENT", false)
+	conf.SetDefault("ACCOUNT_DELETION_POLL_INTERVAL", "1h")
+	conf.SetDefault("DATA_EXPORT_DIR", "./exports")
+	conf.SetDefault("DATA_EXPORT_TTL", "168h")
+	conf.SetDefault("DATA_EXPORT_POLL_INTERVAL", "10s")
+	conf.SetDefault("DATA_EXPORT_LEASE", "10m")
+	conf.SetDefault("DATA_EXPORT_MAX_ATTEMPTS", 3)
+	conf.SetDefault("MODERATION_HIDE_BANNED_CONTENT", false)
+	conf.SetDefault("IMPERSONATION_TTL", "15m")
+	conf.SetDefault("BLOB_DRIVER", "local")
+	conf.SetDefault("BLOB_LOCAL_DIR", "./media")
+	conf.SetDefault("BLOB_S3_REGION", "us-east-1")
+	conf.SetDefault("BLOB_S3_PATH_STYLE", true)
+	conf.SetDefault("BLOB_S3_PRESIGN_TTL", "1h")
+	conf.SetDefault("UPLOAD_MAX_SIZE", 10<<20)
+	conf.SetDefault("IMAGE_POST_VARIANTS", "thumb:320x320,medium:800x800,large:1600x1600")
+	conf.SetDefault("IMAGE_AVATAR_VARIANTS", "square:256x256:crop,thumb:64x64:crop")
+	conf.SetDefault("IMAGE_QUALITY", 85)
+	conf.SetDefault("IMAGE_WORKERS", 2)
+	conf.SetDefault("IMAGE_QUEUE_SIZE", 32)
+	conf.SetDefault("MEDIA_QUOTA", 100<<20)
+	conf.SetDefault("MEDIA_ORPHAN_GRACE_PERIOD", "24h")
+	conf.SetDefault("MEDIA_CLEANUP_INTERVAL", "1h")
+	conf.SetDefault("PUBLIC_BASE_URL", "http://localhost:8000")
+	conf.SetDefault("SITE_TITLE", "Blog")
+	conf.SetDefault("SITE_DESCRIPTION", "The latest posts of the blog")
+	conf.SetDefault("SITE_PAGE_SIZE", 10)
+	conf.SetDefault("FEED_SIZE", 20)
+	conf.SetDefault("FEED_CACHE_TTL", "5m")
+	conf.SetDefault("SITEMAP_CACHE_TTL", "1h")
+	conf.SetDefault("ROBOTS_DISALLOW", "/v1/ /swagger/")
+	conf.SetDefault("WEBHOOK_MAX_ATTEMPTS", 8)
+	conf.SetDefault("WEBHOOK_BASE_BACKOFF", "30s")
+	conf.SetDefault("WEBHOOK_MAX_BACKOFF", "1h")
+	conf.SetDefault("WEBHOOK_POLL_INTERVAL", "5s")
+	conf.SetDefault("WEBHOOK_LEASE", "2m")
+	conf.SetDefault("WEBHOOK_BATCH_SIZE", 20)
+	conf.SetDefault("WEBHOOK_TIMEOUT", "10s")
+	conf.SetDefault("JWT_KEYS_DIR", "./keys")
+	conf.SetDefault("JWT_ACCEPT_LEGACY_HS256", true)
+
 	cfg := Config{
 		HttpPort: conf.GetString("HTTP_PORT"),
 		Postgres: PostgresConfig{
@@ -51,8 +325,137 @@ func Load(path string) Config {
 			Database: conf.GetString("POSTGRES_DATABASE"),
 		},
 		Smtp: Smtp{
+			Host:     conf.GetString("SMTP_HOST"),
+			Port:     conf.GetString("SMTP_PORT"),
 			Sender:   conf.GetString("SMTP_SENDER"),
 			Password: conf.GetString("SMTP_PASSWORD"),
+			FromName: conf.GetString("SMTP_FROM_NAME"),
+			TLSMode:  conf.GetString("SMTP_TLS_MODE"),
+		},
+		Mail: Mail{
+			Driver: conf.GetString("MAIL_DRIVER"),
+			Dir:    conf.GetString("MAIL_DIR"),
+		},
+		EmailOutbox: EmailOutbox{
+			MaxAttempts:  conf.GetInt32("EMAIL_OUTBOX_MAX_ATTEMPTS"),
+			BaseBackoff:  conf.GetDuration("EMAIL_OUTBOX_BASE_BACKOFF"),
+			MaxBackoff:   conf.GetDuration("EMAIL_OUTBOX_MAX_BACKOFF"),
+			PollInterval: conf.GetDuration("EMAIL_OUTBOX_POLL_INTERVAL"),
+			Lease:        conf.GetDuration("EMAIL_OUTBOX_LEASE"),
+			BatchSize:    conf.GetInt32("EMAIL_OUTBOX_BATCH_SIZE"),
+		},
+		BruteForce: BruteForce{
+			FreeAttempts:       conf.GetInt64("BRUTE_FORCE_FREE_ATTEMPTS"),
+			BaseDelay:          conf.GetDuration("BRUTE_FORCE_BASE_DELAY"),
+			MaxAccountAttempts: conf.GetInt64("BRUTE_FORCE_MAX_ACCOUNT_ATTEMPTS"),
+			MaxIPAttempts:      conf.GetInt64("BRUTE_FORCE_MAX_IP_ATTEMPTS"),
+			AttemptWindow:      conf.GetDuration("BRUTE_FORCE_ATTEMPT_WINDOW"),
+			LockoutDuration:    conf.GetDuration("BRUTE_FORCE_LOCKOUT_DURATION"),
+			MaxCodeAttempts:    conf.GetInt64("BRUTE_FORCE_MAX_CODE_ATTEMPTS"),
+			ResendCooldown:     conf.GetDuration("BRUTE_FORCE_RESEND_COOLDOWN"),
+			NotifyLockout:      conf.GetBool("BRUTE_FORCE_NOTIFY_LOCKOUT"),
+		},
+		TwoFactor: TwoFactor{
+			Issuer:            conf.GetString("TWO_FACTOR_ISSUER"),
+			RequireSuperAdmin: conf.GetBool("TWO_FACTOR_REQUIRE_SUPERADMIN"),
+		},
+		Password: Password{
+			Algorithm:         conf.GetString("PASSWORD_HASH_ALGORITHM"),
+			BcryptCost:        conf.GetInt("PASSWORD_BCRYPT_COST"),
+			Argon2Memory:      conf.GetUint32("PASSWORD_ARGON2_MEMORY"),
+			Argon2Iterations:  conf.GetUint32("PASSWORD_ARGON2_ITERATIONS"),
+			Argon2Parallelism: uint8(conf.GetUint("PASSWORD_ARGON2_PARALLELISM")),
+			MinLength:         conf.GetInt("PASSWORD_MIN_LENGTH"),
+		},
+		OIDC: OIDC{
+			ProviderName: conf.GetString("OIDC_PROVIDER_NAME"),
+			IssuerURL:    conf.GetString("OIDC_ISSUER_URL"),
+			ClientID:     conf.GetString("OIDC_CLIENT_ID"),
+			ClientSecret: conf.GetString("OIDC_CLIENT_SECRET"),
+			RedirectURL:  conf.GetString("OIDC_REDIRECT_URL"),
+			Scopes:       strings.Fields(conf.GetString("OIDC_SCOPES")),
+		},
+		MagicLink: MagicLink{
+			URL: conf.GetString("MAGIC_LINK_URL"),
+			TTL: conf.GetDuration("MAGIC_LINK_TTL"),
+		},
+		EmailChange: EmailChange{
+			UndoURL: conf.GetString("EMAIL_CHANGE_UNDO_URL"),
+			UndoTTL: conf.GetDuration("EMAIL_CHANGE_UNDO_TTL"),
+		},
+		AccountDeletion: AccountDeletion{
+			GracePeriod:   conf.GetDuration("ACCOUNT_DELETION_GRACE_PERIOD"),
+			RemoveContent: conf.GetBool("ACCOUNT_DELETION_REMOVE_CONTENT"),
+			PollInterval:  conf.GetDuration("ACCOUNT_DELETION_POLL_INTERVAL"),
+		},
+		DataExport: DataExport{
+			Dir:          conf.GetString("DATA_EXPORT_DIR"),
+			TTL:          conf.GetDuration("DATA_EXPORT_TTL"),
+			PollInterval: conf.GetDuration("DATA_EXPORT_POLL_INTERVAL"),
+			Lease:        conf.GetDuration("DATA_EXPORT_LEASE"),
+			MaxAttempts:  conf.GetInt32("DATA_EXPORT_MAX_ATTEMPTS"),
+		},
+		Moderation: Moderation{
+			HideBannedContent: conf.GetBool("MODERATION_HIDE_BANNED_CONTENT"),
+		},
+		Impersonation: Impersonation{
+			TTL: conf.GetDuration("IMPERSONATION_TTL"),
+		},
+		Blob: Blob{
+			Driver:       conf.GetString("BLOB_DRIVER"),
+			LocalDir:     conf.GetString("BLOB_LOCAL_DIR"),
+			S3Endpoint:   conf.GetString("BLOB_S3_ENDPOINT"),
+			S3Region:     conf.GetString("BLOB_S3_REGION"),
+			S3Bucket:     conf.GetString("BLOB_S3_BUCKET"),
+			S3AccessKey:  conf.GetString("BLOB_S3_ACCESS_KEY"),
+			S3SecretKey:  conf.GetString("BLOB_S3_SECRET_KEY"),
+			S3PathStyle:  conf.GetBool("BLOB_S3_PATH_STYLE"),
+			S3PublicURL:  conf.GetString("BLOB_S3_PUBLIC_URL"),
+			S3PresignTTL: conf.GetDuration("BLOB_S3_PRESIGN_TTL"),
+		},
+		Upload: Upload{
+			MaxSize: conf.GetInt64("UPLOAD_MAX_SIZE"),
+		},
+		Image: Image{
+			PostVariants:   conf.GetString("IMAGE_POST_VARIANTS"),
+			AvatarVariants: conf.GetString("IMAGE_AVATAR_VARIANTS"),
+			Format:         conf.GetString("IMAGE_FORMAT"),
+			Quality:        conf.GetInt("IMAGE_QUALITY"),
+			Workers:        conf.GetInt("IMAGE_WORKERS"),
+			QueueSize:      conf.GetInt("IMAGE_QUEUE_SIZE"),
+		},
+		Media: Media{
+			Quota:             conf.GetInt64("MEDIA_QUOTA"),
+			OrphanGracePeriod: conf.GetDuration("MEDIA_ORPHAN_GRACE_PERIOD"),
+			CleanupInterval:   conf.GetDuration("MEDIA_CLEANUP_INTERVAL"),
+		},
+		Site: Site{
+			BaseURL:     strings.TrimSuffix(conf.GetString("PUBLIC_BASE_URL"), "/"),
+			Title:       conf.GetString("SITE_TITLE"),
+			Description: conf.GetString("SITE_DESCRIPTION"),
+			PageSize:    conf.GetInt32("SITE_PAGE_SIZE"),
+		},
+		Feed: Feed{
+			Size:     conf.GetInt32("FEED_SIZE"),
+			CacheTTL: conf.GetDuration("FEED_CACHE_TTL"),
+		},
+		Sitemap: Sitemap{
+			CacheTTL:       conf.GetDuration("SITEMAP_CACHE_TTL"),
+			RobotsDisallow: strings.Fields(conf.GetString("ROBOTS_DISALLOW")),
+		},
+		Webhook: Webhook{
+			MaxAttempts:  conf.GetInt32("WEBHOOK_MAX_ATTEMPTS"),
+			BaseBackoff:  conf.GetDuration("WEBHOOK_BASE_BACKOFF"),
+			MaxBackoff:   conf.GetDuration("WEBHOOK_MAX_BACKOFF"),
+			PollInterval: conf.GetDuration("WEBHOOK_POLL_INTERVAL"),
+			Lease:        conf.GetDuration("WEBHOOK_LEASE"),
+			BatchSize:    conf.GetInt32("WEBHOOK_BATCH_SIZE"),
+			Timeout:      conf.GetDuration("WEBHOOK_TIMEOUT"),
+		},
+		JWT: JWT{
+			KeysDir:           conf.GetString("JWT_KEYS_DIR"),
+			ActiveKeyID:       conf.GetString("JWT_ACTIVE_KEY_ID"),
+			AcceptLegacyHS256: conf.GetBool("JWT_ACCEPT_LEGACY_HS256"),
 		},
 		Redis: Redis{
 			Addr: conf.GetString("REDIS_ADDR"),
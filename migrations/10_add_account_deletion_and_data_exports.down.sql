@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS data_exports;
+
+DROP INDEX IF EXISTS users_deletion_scheduled_at_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS deletion_scheduled_at;
+
+-- The placeholder user is kept, content of deleted accounts may still belong to it
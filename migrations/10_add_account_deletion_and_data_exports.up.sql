@@ -0,0 +1,25 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_scheduled_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS users_deletion_scheduled_at_idx ON users(deletion_scheduled_at)
+WHERE deletion_scheduled_at IS NOT NULL;
+
+-- Posts and comments of deleted accounts are reassigned to this user, the password can never match
+INSERT INTO users(first_name, last_name, email, password, type)
+VALUES('Deleted', 'User', 'deleted-user@blog.invalid', '!', 'user')
+ON CONFLICT (email) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS data_exports(
+    id UUID PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN('pending', 'ready', 'failed')),
+    file_path VARCHAR,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS data_exports_user_id_idx ON data_exports(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS data_exports_pending_idx ON data_exports(next_attempt_at) WHERE status = 'pending';
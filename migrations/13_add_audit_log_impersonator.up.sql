@@ -0,0 +1,4 @@
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS impersonator_id INTEGER;
+
+CREATE INDEX IF NOT EXISTS audit_log_impersonator_id_idx ON audit_log(impersonator_id, created_at DESC)
+WHERE impersonator_id IS NOT NULL;
@@ -0,0 +1,18 @@
+-- Files of deleted accounts are kept while posts link them, the cleanup job removes them afterwards
+CREATE TABLE IF NOT EXISTS media(
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    path VARCHAR NOT NULL UNIQUE,
+    purpose VARCHAR(20) NOT NULL,
+    mime_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    width INTEGER,
+    height INTEGER,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_uploaded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The same content uploaded again by the user for the same purpose is stored once
+CREATE UNIQUE INDEX IF NOT EXISTS media_user_content_idx ON media(user_id, sha256, purpose);
+CREATE INDEX IF NOT EXISTS media_last_uploaded_at_idx ON media(last_uploaded_at);
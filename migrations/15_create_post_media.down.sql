@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS post_media;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS cover_media_id;
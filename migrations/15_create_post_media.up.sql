@@ -0,0 +1,14 @@
+-- image_url keeps the link of the cover for clients that only know it
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS cover_media_id BIGINT REFERENCES media(id) ON DELETE SET NULL;
+
+-- Uploads in a gallery can not be deleted until the post drops them
+CREATE TABLE IF NOT EXISTS post_media(
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    media_id BIGINT NOT NULL REFERENCES media(id),
+    position INTEGER NOT NULL,
+    caption TEXT,
+    alt_text TEXT,
+    PRIMARY KEY(post_id, media_id)
+);
+
+CREATE INDEX IF NOT EXISTS post_media_media_id_idx ON post_media(media_id);
@@ -0,0 +1,2 @@
+-- Variants made of an image count towards the storage quota of the user along with the original
+ALTER TABLE media ADD COLUMN IF NOT EXISTS variants_size BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- Emails with codes and links are not sent or resent once they expire,
+-- the bodies of sent emails are not kept so the outbox does not hold their secrets
+ALTER TABLE email_outbox ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE email_outbox SET html_body = '', text_body = '' WHERE status = 'sent';
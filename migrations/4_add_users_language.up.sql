@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS language VARCHAR(5) CHECK (language IN('uz', 'ru', 'en'));
@@ -0,0 +1 @@
+-- Hashed passwords can not be turned back into plain text
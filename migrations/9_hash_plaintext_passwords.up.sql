@@ -0,0 +1,5 @@
+-- Users created by admins used to get their password stored as is
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+UPDATE users SET password = crypt(password, gen_salt('bf', 10))
+WHERE password NOT LIKE '$2_$%' AND password NOT LIKE '$argon2id$%';
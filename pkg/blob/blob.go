@@ -0,0 +1,98 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/ibrat-muslim/blog-app/config"
+)
+
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+// URLPrefix is the stable path the app links uploaded files under, whatever the driver
+const URLPrefix = "/media/"
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("blob key is invalid")
+)
+
+// BlobStore keeps uploaded files under slash separated keys
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get returns ErrNotFound when there is no blob with the key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete succeeds when the blob is already gone
+	Delete(ctx context.Context, key string) error
+	// URL returns where clients can download the blob, presigned URLs expire
+	URL(ctx context.Context, key string) (string, error)
+}
+
+// NewBlobStore returns the store selected by the BLOB_DRIVER setting
+func NewBlobStore(cfg *config.Blob) (BlobStore, error) {
+	switch cfg.Driver {
+	case DriverLocal, "":
+		return NewLocalStore(cfg.LocalDir), nil
+	case DriverS3:
+		return NewS3Store(&S3Options{
+			Endpoint:   cfg.S3Endpoint,
+			Region:     cfg.S3Region,
+			Bucket:     cfg.S3Bucket,
+			AccessKey:  cfg.S3AccessKey,
+			SecretKey:  cfg.S3SecretKey,
+			PathStyle:  cfg.S3PathStyle,
+			PublicURL:  cfg.S3PublicURL,
+			PresignTTL: cfg.S3PresignTTL,
+		})
+	default:
+		return nil, fmt.Errorf("unknown blob driver %q", cfg.Driver)
+	}
+}
+
+// inlineTypes are safe to show in the browser, anything else is downloaded
+var inlineTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// ContentDisposition keeps uploaded files from being rendered as pages on our origin
+func ContentDisposition(contentType string) string {
+	if inlineTypes[contentType] {
+		return "inline"
+	}
+
+	return "attachment"
+}
+
+// KeyFromURL returns the key of a blob linked by a "/media/..." url
+func KeyFromURL(url *string) (string, bool) {
+	if url == nil || !strings.HasPrefix(*url, URLPrefix) {
+		return "", false
+	}
+
+	key, err := cleanKey(strings.TrimPrefix(*url, URLPrefix))
+	if err != nil {
+		return "", false
+	}
+
+	return key, true
+}
+
+// cleanKey keeps keys inside the store, ".." can not climb above the root
+func cleanKey(key string) (string, error) {
+	key = strings.TrimPrefix(path.Clean("/"+key), "/")
+	if key == "" {
+		return "", ErrInvalidKey
+	}
+
+	return key, nil
+}
@@ -0,0 +1,53 @@
+package blob
+
+import (
+	"testing"
+
+	"github.com/ibrat-muslim/blog-app/config"
+	"github.com/stretchr/testify/require"
+)
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestKeyFromURL(t *testing.T) {
+	key, ok := KeyFromURL(stringPtr("/media/photo.png"))
+	require.True(t, ok)
+	require.Equal(t, "photo.png", key)
+
+	key, ok = KeyFromURL(stringPtr("/media/variants/../photo.png"))
+	require.True(t, ok)
+	require.Equal(t, "photo.png", key)
+
+	key, ok = KeyFromURL(stringPtr("/media/../../etc/passwd"))
+	require.True(t, ok)
+	require.Equal(t, "etc/passwd", key)
+
+	_, ok = KeyFromURL(stringPtr("/media/.."))
+	require.False(t, ok)
+
+	_, ok = KeyFromURL(stringPtr("https://example.com/photo.png"))
+	require.False(t, ok)
+
+	_, ok = KeyFromURL(nil)
+	require.False(t, ok)
+}
+
+func TestContentDisposition(t *testing.T) {
+	require.Equal(t, "inline", ContentDisposition("image/png"))
+	require.Equal(t, "attachment", ContentDisposition("text/html"))
+	require.Equal(t, "attachment", ContentDisposition("image/svg+xml"))
+}
+
+func TestNewBlobStore(t *testing.T) {
+	store, err := NewBlobStore(&config.Blob{LocalDir: t.TempDir()})
+	require.NoError(t, err)
+	require.IsType(t, &localStore{}, store)
+
+	_, err = NewBlobStore(&config.Blob{Driver: DriverS3, S3Endpoint: "localhost:9000", S3Bucket: "media"})
+	require.Error(t, err)
+
+	_, err = NewBlobStore(&config.Blob{Driver: "ftp"})
+	require.Error(t, err)
+}
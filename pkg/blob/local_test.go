@@ -0,0 +1,50 @@
+package blob
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := NewLocalStore(dir)
+
+	err := store.Put(ctx, "variants/photo.png", strings.NewReader("image"), 5, "image/png")
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "variants", "photo.png"))
+	require.NoError(t, err)
+	require.Equal(t, "image", string(data))
+
+	body, err := store.Get(ctx, "variants/photo.png")
+	require.NoError(t, err)
+	data, err = io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	require.Equal(t, "image", string(data))
+
+	url, err := store.URL(ctx, "variants/photo.png")
+	require.NoError(t, err)
+	require.Equal(t, "/media/variants/photo.png", url)
+
+	// Keys can not reach outside the directory
+	err = store.Put(ctx, "../escape.png", strings.NewReader("image"), 5, "image/png")
+	require.NoError(t, err)
+	require.FileExists(t, filepath.Join(dir, "escape.png"))
+
+	require.NoError(t, store.Delete(ctx, "variants/photo.png"))
+	require.NoError(t, store.Delete(ctx, "variants/photo.png"))
+
+	_, err = store.Get(ctx, "variants/photo.png")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = store.Get(ctx, "/")
+	require.ErrorIs(t, err, ErrInvalidKey)
+}
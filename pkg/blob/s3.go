@@ -0,0 +1,216 @@
+package blob
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type S3Options struct {
+	// Endpoint is the base URL of the S3 compatible service, e.g. https://s3.amazonaws.com
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle addresses the bucket as the first path segment instead of a subdomain,
+	// MinIO and most self-hosted services need it
+	PathStyle bool
+	// PublicURL links objects of a public bucket or CDN, presigned URLs valid for PresignTTL are returned otherwise
+	PublicURL  string
+	PresignTTL time.Duration
+	Client     *http.Client
+}
+
+type s3Store struct {
+	endpoint   *url.URL
+	bucket     string
+	pathStyle  bool
+	publicURL  string
+	presignTTL time.Duration
+	client     *http.Client
+	signer     *signer
+	now        func() time.Time
+}
+
+// NewS3Store returns a store talking to an S3 compatible service
+func NewS3Store(options *S3Options) (BlobStore, error) {
+	endpoint, err := url.Parse(options.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3 endpoint: %w", err)
+	}
+
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", options.Endpoint)
+	}
+
+	if options.Bucket == "" {
+		return nil, errors.New("s3 bucket is not set")
+	}
+
+	client := options.Client
+	if client == nil {
+		client = &http.Client{Timeout: time.Minute}
+	}
+
+	return &s3Store{
+		endpoint:   endpoint,
+		bucket:     options.Bucket,
+		pathStyle:  options.PathStyle,
+		publicURL:  strings.TrimSuffix(options.PublicURL, "/"),
+		presignTTL: options.PresignTTL,
+		client:     client,
+		signer: &signer{
+			accessKey: options.AccessKey,
+			secretKey: options.SecretKey,
+			region:    options.Region,
+		},
+		now: time.Now,
+	}, nil
+}
+
+// objectURL addresses the object on the endpoint
+func (s *s3Store) objectURL(key string) *url.URL {
+	u := *s.endpoint
+
+	objectPath := "/" + key
+	if s.pathStyle {
+		objectPath = "/" + s.bucket + objectPath
+	} else {
+		u.Host = s.bucket + "." + u.Host
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/") + objectPath
+	u.RawPath = uriEncode(u.Path, false)
+
+	return &u
+}
+
+func (s *s3Store) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return http.NewRequestWithContext(ctx, method, s.objectURL(key).String(), body)
+}
+
+func (s *s3Store) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	s.signer.sign(req, s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+
+	return resp, nil
+}
+
+// Put uploads the body, size must be its length since S3 needs the Content-Length
+func (s *s3Store) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, body)
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	// S3 serves the object with the headers it was stored with
+	req.Header.Set("Content-Disposition", ContentDisposition(contentType))
+
+	resp, err := s.do(req, unsignedPayload)
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+func (s *s3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+func (s *s3Store) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+func (s *s3Store) URL(ctx context.Context, key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	if s.publicURL != "" {
+		return s.publicURL + "/" + uriEncode(key, false), nil
+	}
+
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return "", err
+	}
+
+	s.signer.presign(req, s.now(), s.presignTTL)
+
+	return req.URL.String(), nil
+}
+
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// responseError turns an S3 error response into an error, missing objects are ErrNotFound
+func responseError(resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+
+	var body s3Error
+	_ = xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body)
+
+	if body.Code == "" {
+		return fmt.Errorf("s3 request failed with status %d", resp.StatusCode)
+	}
+
+	return fmt.Errorf("s3 request failed with status %d: %s: %s", resp.StatusCode, body.Code, body.Message)
+}
@@ -0,0 +1,121 @@
+package blob_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibrat-muslim/blog-app/pkg/blob"
+	"github.com/ibrat-muslim/blog-app/pkg/blob/s3test"
+	"github.com/stretchr/testify/require"
+)
+
+func newS3Store(t *testing.T, server *s3test.Server, publicURL string) blob.BlobStore {
+	store, err := blob.NewS3Store(&blob.S3Options{
+		Endpoint:   server.URL,
+		Region:     s3test.Region,
+		Bucket:     s3test.Bucket,
+		AccessKey:  s3test.AccessKey,
+		SecretKey:  s3test.SecretKey,
+		PathStyle:  true,
+		PublicURL:  publicURL,
+		PresignTTL: time.Hour,
+	})
+	require.NoError(t, err)
+
+	return store
+}
+
+func TestS3Store(t *testing.T) {
+	server := s3test.NewServer()
+	defer server.Close()
+
+	ctx := context.Background()
+	store := newS3Store(t, server, "")
+
+	err := store.Put(ctx, "variants/my photo.png", strings.NewReader("image"), 5, "image/png")
+	require.NoError(t, err)
+
+	object := server.Object("variants/my photo.png")
+	require.NotNil(t, object)
+	require.Equal(t, "image", string(object.Body))
+	require.Equal(t, "image/png", object.ContentType)
+	require.Equal(t, "inline", object.ContentDisposition)
+
+	body, err := store.Get(ctx, "variants/my photo.png")
+	require.NoError(t, err)
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.NoError(t, body.Close())
+	require.Equal(t, "image", string(data))
+
+	require.NoError(t, store.Put(ctx, "empty.txt", strings.NewReader(""), 0, "text/plain"))
+	require.Equal(t, "attachment", server.Object("empty.txt").ContentDisposition)
+
+	require.NoError(t, store.Delete(ctx, "variants/my photo.png"))
+	require.Nil(t, server.Object("variants/my photo.png"))
+
+	_, err = store.Get(ctx, "variants/my photo.png")
+	require.ErrorIs(t, err, blob.ErrNotFound)
+}
+
+func TestS3StorePresignedURL(t *testing.T) {
+	server := s3test.NewServer()
+	defer server.Close()
+
+	ctx := context.Background()
+	store := newS3Store(t, server, "")
+
+	require.NoError(t, store.Put(ctx, "photo.png", strings.NewReader("image"), 5, "image/png"))
+
+	url, err := store.URL(ctx, "photo.png")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(url, server.URL+"/"+s3test.Bucket+"/photo.png?"))
+
+	// The presigned URL is usable without credentials
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "image", string(data))
+
+	resp, err = http.Get(strings.Replace(url, "X-Amz-Expires=3600", "X-Amz-Expires=7200", 1))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestS3StorePublicURL(t *testing.T) {
+	server := s3test.NewServer()
+	defer server.Close()
+
+	store := newS3Store(t, server, "https://cdn.example.com/")
+
+	url, err := store.URL(context.Background(), "variants/my photo.png")
+	require.NoError(t, err)
+	require.Equal(t, "https://cdn.example.com/variants/my%20photo.png", url)
+}
+
+func TestS3StoreWrongCredentials(t *testing.T) {
+	server := s3test.NewServer()
+	defer server.Close()
+
+	store, err := blob.NewS3Store(&blob.S3Options{
+		Endpoint:  server.URL,
+		Region:    s3test.Region,
+		Bucket:    s3test.Bucket,
+		AccessKey: s3test.AccessKey,
+		SecretKey: "wrong",
+		PathStyle: true,
+	})
+	require.NoError(t, err)
+
+	err = store.Put(context.Background(), "photo.png", strings.NewReader("image"), 5, "image/png")
+	require.ErrorContains(t, err, "SignatureDoesNotMatch")
+}
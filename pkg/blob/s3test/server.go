@@ -0,0 +1,213 @@
+// Package s3test provides a local S3 compatible server for tests, in the spirit of MinIO.
+// It keeps objects in memory, addresses buckets path style and checks Signature Version 4
+// of both signed and presigned requests.
+package s3test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AccessKey = "test-access-key"
+	SecretKey = "test-secret-key"
+	Region    = "us-east-1"
+	Bucket    = "test-bucket"
+)
+
+// Object is a stored object
+type Object struct {
+	Body               []byte
+	ContentType        string
+	ContentDisposition string
+}
+
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	objects map[string]*Object
+}
+
+// NewServer starts a server with an empty Bucket, it is closed with the test
+func NewServer() *Server {
+	s := &Server{objects: make(map[string]*Object)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Object returns the stored object with the key, nil when there is none
+func (s *Server) Object(key string) *Object {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.objects[key]
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	err := verify(r)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "SignatureDoesNotMatch", err.Error())
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != Bucket {
+		writeError(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		if r.ContentLength < 0 {
+			writeError(w, http.StatusLengthRequired, "MissingContentLength", "You must provide the Content-Length HTTP header")
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+			return
+		}
+
+		s.objects[key] = &Object{
+			Body:               body,
+			ContentType:        r.Header.Get("Content-Type"),
+			ContentDisposition: r.Header.Get("Content-Disposition"),
+		}
+	case http.MethodGet:
+		object, ok := s.objects[key]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist")
+			return
+		}
+
+		w.Header().Set("Content-Type", object.ContentType)
+		w.Header().Set("Content-Disposition", object.ContentDisposition)
+		w.Write(object.Body)
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "The specified method is not allowed")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, message)
+}
+
+// verify recomputes the signature of the request from what was received
+func verify(r *http.Request) error {
+	var credential, signedHeaders, signature, amzDate, payloadHash string
+
+	query := r.URL.Query()
+
+	if query.Get("X-Amz-Signature") != "" {
+		credential = query.Get("X-Amz-Credential")
+		signedHeaders = query.Get("X-Amz-SignedHeaders")
+		signature = query.Get("X-Amz-Signature")
+		amzDate = query.Get("X-Amz-Date")
+		payloadHash = "UNSIGNED-PAYLOAD"
+
+		date, err := time.Parse("20060102T150405Z", amzDate)
+		if err != nil {
+			return err
+		}
+
+		var expires int64
+		fmt.Sscan(query.Get("X-Amz-Expires"), &expires)
+		if time.Now().After(date.Add(time.Duration(expires) * time.Second)) {
+			return fmt.Errorf("request has expired")
+		}
+
+		query.Del("X-Amz-Signature")
+	} else {
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+		for _, part := range strings.Split(auth, ", ") {
+			name, value, _ := strings.Cut(part, "=")
+			switch name {
+			case "Credential":
+				credential = value
+			case "SignedHeaders":
+				signedHeaders = value
+			case "Signature":
+				signature = value
+			}
+		}
+		amzDate = r.Header.Get("X-Amz-Date")
+		payloadHash = r.Header.Get("X-Amz-Content-Sha256")
+	}
+
+	if amzDate == "" || len(amzDate) < 8 {
+		return fmt.Errorf("request is not signed")
+	}
+
+	scope := amzDate[:8] + "/" + Region + "/s3/aws4_request"
+	if credential != AccessKey+"/"+scope {
+		return fmt.Errorf("unexpected credential %q", credential)
+	}
+
+	var canonical strings.Builder
+	canonical.WriteString(r.Method + "\n" + r.URL.EscapedPath() + "\n")
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, escape(key)+"="+escape(query.Get(key)))
+	}
+	canonical.WriteString(strings.Join(pairs, "&") + "\n")
+
+	for _, name := range strings.Split(signedHeaders, ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonical.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	canonical.WriteString("\n" + signedHeaders + "\n" + payloadHash)
+
+	hash := sha256.Sum256([]byte(canonical.String()))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := sum([]byte("AWS4"+SecretKey), amzDate[:8])
+	key = sum(key, Region)
+	key = sum(key, "s3")
+	key = sum(key, "aws4_request")
+
+	if !hmac.Equal([]byte(hex.EncodeToString(sum(key, stringToSign))), []byte(signature)) {
+		return fmt.Errorf("the request signature does not match")
+	}
+
+	return nil
+}
+
+func sum(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func escape(value string) string {
+	return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+}
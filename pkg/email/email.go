@@ -1,62 +1,61 @@
 package email
 
 import (
-	"bytes"
 	"fmt"
-	"html/template"
-	"net/smtp"
 
 	"github.com/ibrat-muslim/blog-app/config"
 )
 
 type SendEmailRequest struct {
-	To      []string
-	Type    string
-	Body    map[string]string
-	Subject string
+	To     []string
+	Type   string
+	Body   map[string]string
+	Locale string
 }
 
 const (
 	VerificationEmail   = "verification_email"
 	ForgotPasswordEmail = "forgot_password_email"
+	AccountLockedEmail  = "account_locked_email"
+	MagicLinkEmail      = "magic_link_email"
+	EmailChangeEmail    = "email_change_email"
+	EmailChangeNotice   = "email_change_notice_email"
 )
 
-func SendEmail(cfg *config.Config, req *SendEmailRequest) error {
-	from := cfg.Smtp.Sender
-	to := req.To
-
-	password := cfg.Smtp.Password
+const (
+	DriverSMTP   = "smtp"
+	DriverFile   = "file"
+	DriverLog    = "log"
+	DriverMemory = "memory"
+)
 
-	var body bytes.Buffer
+// Mailer delivers a rendered message to its recipients
+type Mailer interface {
+	Send(msg *Message) error
+}
 
-	templatePath := getTemplatePath(req.Type)
-	t, err := template.ParseFiles(templatePath)
-	if err != nil {
-		return err
+// NewMailer returns the mailer selected by the MAIL_DRIVER setting
+func NewMailer(cfg *config.Config) (Mailer, error) {
+	switch cfg.Mail.Driver {
+	case DriverSMTP, "":
+		return NewSMTPMailer(&cfg.Smtp), nil
+	case DriverFile:
+		return NewFileMailer(cfg.Mail.Dir, cfg.Smtp.Sender, cfg.Smtp.FromName)
+	case DriverLog:
+		return NewLogMailer(), nil
+	case DriverMemory:
+		return NewMemoryMailer(), nil
 	}
 
-	t.Execute(&body, req.Body)
-
-	mime := "MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n"
-	subject := fmt.Sprintf("Subject: %s\n", req.Subject)
-	msg := []byte(subject + mime + body.String())
+	return nil, fmt.Errorf("unknown mail driver: %q", cfg.Mail.Driver)
+}
 
-	auth := smtp.PlainAuth("", from, password, "smtp.gmail.com")
-	err = smtp.SendMail("smtp.gmail.com:587", auth, from, to, msg)
+// SendEmail renders the requested template and hands it to the mailer
+func SendEmail(mailer Mailer, req *SendEmailRequest) error {
+	msg, err := NewMessage(req)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return mailer.Send(msg)
 }
-
-func getTemplatePath(emailType string) string {
-	switch emailType {
-	case VerificationEmail:
-		return "./templates/verification_email.html"
-	case ForgotPasswordEmail:
-		return "./templates/forgot_password_email.html"
-	}
-
-	return ""
-}
\ No newline at end of file
@@ -0,0 +1,86 @@
+package email
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMessage() *Message {
+	return &Message{
+		To:       []string{"user@example.com"},
+		Subject:  "Verification email",
+		HTMLBody: "<p>Verification Code: <b>123456</b></p>",
+		TextBody: "Verification Code: 123456",
+	}
+}
+
+func TestMessageBytes(t *testing.T) {
+	data, err := newTestMessage().Bytes("blog@example.com", "Blog")
+	require.NoError(t, err)
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, `"Blog" <blog@example.com>`, msg.Header.Get("From"))
+	require.Equal(t, "user@example.com", msg.Header.Get("To"))
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+
+	part, err := reader.NextPart()
+	require.NoError(t, err)
+	require.Contains(t, part.Header.Get("Content-Type"), "text/plain")
+
+	body, err := io.ReadAll(part)
+	require.NoError(t, err)
+	require.Equal(t, "Verification Code: 123456", string(body))
+
+	part, err = reader.NextPart()
+	require.NoError(t, err)
+	require.Contains(t, part.Header.Get("Content-Type"), "text/html")
+
+	_, err = reader.NextPart()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestMemoryMailer(t *testing.T) {
+	mailer := NewMemoryMailer()
+	require.Nil(t, mailer.Last())
+
+	err := mailer.Send(newTestMessage())
+	require.NoError(t, err)
+
+	require.Len(t, mailer.Messages(), 1)
+	require.Equal(t, "Verification email", mailer.Last().Subject)
+
+	mailer.Reset()
+	require.Empty(t, mailer.Messages())
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+
+	mailer, err := NewFileMailer(dir, "blog@example.com", "Blog")
+	require.NoError(t, err)
+
+	err = mailer.Send(newTestMessage())
+	require.NoError(t, err)
+
+	files, err := os.ReadDir(filepath.Join(dir, "new"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	tmpFiles, err := os.ReadDir(filepath.Join(dir, "tmp"))
+	require.NoError(t, err)
+	require.Empty(t, tmpFiles)
+}
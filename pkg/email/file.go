@@ -0,0 +1,58 @@
+package email
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type fileMailer struct {
+	dir      string
+	from     string
+	fromName string
+}
+
+// NewFileMailer returns a mailer that stores every message in a maildir,
+// so it can be opened with any mail client during local development
+func NewFileMailer(dir, from, fromName string) (Mailer, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		err := os.MkdirAll(filepath.Join(dir, sub), os.ModePerm)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &fileMailer{
+		dir:      dir,
+		from:     from,
+		fromName: fromName,
+	}, nil
+}
+
+func (f *fileMailer) Send(msg *Message) error {
+	data, err := msg.Bytes(f.from, f.fromName)
+	if err != nil {
+		return err
+	}
+
+	b := make([]byte, 8)
+	_, err = rand.Read(b)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d.%s.eml", time.Now().UnixNano(), hex.EncodeToString(b))
+
+	// Write into tmp first and rename, so readers never see a partial message
+	tmpPath := filepath.Join(f.dir, "tmp", name)
+
+	err = os.WriteFile(tmpPath, data, 0o644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, filepath.Join(f.dir, "new", name))
+}
@@ -0,0 +1,18 @@
+package email
+
+import (
+	"log"
+	"strings"
+)
+
+type logMailer struct{}
+
+// NewLogMailer returns a mailer that only writes messages to the log
+func NewLogMailer() Mailer {
+	return &logMailer{}
+}
+
+func (l *logMailer) Send(msg *Message) error {
+	log.Printf("email to %s: %s\n%s", strings.Join(msg.To, ", "), msg.Subject, msg.TextBody)
+	return nil
+}
@@ -0,0 +1,108 @@
+package email
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+
+	"github.com/ibrat-muslim/blog-app/config"
+)
+
+const (
+	TLSModeNone     = "none"
+	TLSModeStartTLS = "starttls"
+	TLSModeTLS      = "tls"
+)
+
+type smtpMailer struct {
+	cfg *config.Smtp
+}
+
+// NewSMTPMailer returns a mailer that delivers messages through an SMTP server
+func NewSMTPMailer(cfg *config.Smtp) Mailer {
+	return &smtpMailer{
+		cfg: cfg,
+	}
+}
+
+func (s *smtpMailer) Send(msg *Message) error {
+	data, err := msg.Bytes(s.cfg.Sender, s.cfg.FromName)
+	if err != nil {
+		return err
+	}
+
+	client, err := s.dial()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if s.cfg.Password != "" {
+		auth := smtp.PlainAuth("", s.cfg.Sender, s.cfg.Password, s.cfg.Host)
+
+		err = client.Auth(auth)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = client.Mail(s.cfg.Sender)
+	if err != nil {
+		return err
+	}
+
+	for _, to := range msg.To {
+		err = client.Rcpt(to)
+		if err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	if err != nil {
+		return err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+func (s *smtpMailer) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.cfg.Host, s.cfg.Port)
+	tlsConfig := &tls.Config{ServerName: s.cfg.Host}
+
+	switch s.cfg.TLSMode {
+	case TLSModeTLS:
+		conn, err := tls.Dial("tcp", addr, tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		return smtp.NewClient(conn, s.cfg.Host)
+	case TLSModeStartTLS, "":
+		client, err := smtp.Dial(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		err = client.StartTLS(tlsConfig)
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+		return client, nil
+	case TLSModeNone:
+		return smtp.Dial(addr)
+	}
+
+	return nil, fmt.Errorf("unknown smtp tls mode: %q", s.cfg.TLSMode)
+}
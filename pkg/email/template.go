@@ -0,0 +1,188 @@
+package email
+
+import (
+	"bytes"
+	"errors"
+	htmlTemplate "html/template"
+	"strconv"
+	"strings"
+	textTemplate "text/template"
+
+	"github.com/ibrat-muslim/blog-app/templates"
+)
+
+const (
+	LocaleEnglish = "en"
+	LocaleRussian = "ru"
+	LocaleUzbek   = "uz"
+
+	DefaultLocale = LocaleEnglish
+)
+
+const (
+	FormatHTML = "html"
+	FormatText = "text"
+)
+
+var (
+	ErrUnknownTemplate = errors.New("unknown email template")
+	ErrUnknownFormat   = errors.New("unknown email format")
+)
+
+var (
+	Templates = []string{VerificationEmail, ForgotPasswordEmail, AccountLockedEmail, MagicLinkEmail, EmailChangeEmail, EmailChangeNotice}
+	Locales   = []string{LocaleEnglish, LocaleRussian, LocaleUzbek}
+)
+
+// sampleData is used to preview templates without sending them
+var sampleData = map[string]map[string]string{
+	VerificationEmail:   {"code": "123456"},
+	ForgotPasswordEmail: {"code": "123456"},
+	AccountLockedEmail:  {"ip": "203.0.113.7", "minutes": "15"},
+	MagicLinkEmail:      {"link": "http://localhost:8000/v1/auth/magic-link/consume?token=sample", "minutes": "15"},
+	EmailChangeEmail:    {"code": "123456"},
+	EmailChangeNotice:   {"email": "new@example.com", "link": "http://localhost:8000/v1/auth/email-change/undo?token=sample", "days": "7"},
+}
+
+// NewMessage renders the subject, the html and the plain text version of the template
+func NewMessage(req *SendEmailRequest) (*Message, error) {
+	subject, err := Render(req.Type, req.Locale, FormatText, "subject", req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	htmlBody, err := Render(req.Type, req.Locale, FormatHTML, "layout", req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	textBody, err := Render(req.Type, req.Locale, FormatText, "layout", req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		To:       req.To,
+		Subject:  strings.TrimSpace(subject),
+		HTMLBody: htmlBody,
+		TextBody: textBody,
+	}, nil
+}
+
+// Preview renders the template in the given format with sample data
+func Preview(emailType, locale, format string) (string, error) {
+	return Render(emailType, locale, format, "layout", sampleData[emailType])
+}
+
+// Render executes the named block of the template in the shared layout
+func Render(emailType, locale, format, name string, body map[string]string) (string, error) {
+	if !contains(Templates, emailType) {
+		return "", ErrUnknownTemplate
+	}
+
+	locale = MatchLocale(locale)
+
+	data := map[string]string{"locale": locale}
+	for k, v := range body {
+		data[k] = v
+	}
+
+	var buf bytes.Buffer
+
+	switch format {
+	case FormatHTML:
+		t, err := htmlTemplate.ParseFS(templates.Email, "email/layout.html", "email/"+locale+"/"+emailType+".html")
+		if err != nil {
+			return "", err
+		}
+
+		err = t.ExecuteTemplate(&buf, name, data)
+		if err != nil {
+			return "", err
+		}
+	case FormatText:
+		t, err := textTemplate.ParseFS(templates.Email, "email/layout.txt", "email/"+locale+"/"+emailType+".txt")
+		if err != nil {
+			return "", err
+		}
+
+		err = t.ExecuteTemplate(&buf, name, data)
+		if err != nil {
+			return "", err
+		}
+	default:
+		return "", ErrUnknownFormat
+	}
+
+	return buf.String(), nil
+}
+
+// MatchLocale returns the first supported locale among the preferences,
+// each preference may be a plain language code or an Accept-Language header value
+func MatchLocale(preferences ...string) string {
+	for _, preference := range preferences {
+		for _, tag := range parseAcceptLanguage(preference) {
+			base := strings.ToLower(strings.SplitN(tag, "-", 2)[0])
+			if contains(Locales, base) {
+				return base
+			}
+		}
+	}
+
+	return DefaultLocale
+}
+
+// parseAcceptLanguage returns the language tags ordered by their quality value
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	tags := make([]weighted, 0)
+
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, _ = strconv.ParseFloat(param[2:], 64)
+			}
+		}
+
+		if q <= 0 {
+			continue
+		}
+
+		// Keep the header order for equal weights
+		i := len(tags)
+		for i > 0 && tags[i-1].q < q {
+			i--
+		}
+		tags = append(tags, weighted{})
+		copy(tags[i+1:], tags[i:])
+		tags[i] = weighted{tag: tag, q: q}
+	}
+
+	result := make([]string, 0, len(tags))
+	for _, t := range tags {
+		result = append(result, t.tag)
+	}
+
+	return result
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package email
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMessageAllTemplates(t *testing.T) {
+	for _, emailType := range Templates {
+		for _, locale := range Locales {
+			msg, err := NewMessage(&SendEmailRequest{
+				To:     []string{"user@example.com"},
+				Type:   emailType,
+				Body:   map[string]string{"code": "654321", "ip": "654321", "link": "654321", "minutes": "15", "email": "new@example.com", "days": "7"},
+				Locale: locale,
+			})
+			require.NoError(t, err, "%s/%s", locale, emailType)
+
+			require.NotEmpty(t, msg.Subject)
+			require.NotContains(t, msg.Subject, "\n")
+			require.Contains(t, msg.HTMLBody, "<b>654321</b>")
+			require.Contains(t, msg.HTMLBody, `lang="`+locale+`"`)
+			require.Contains(t, msg.TextBody, "654321")
+			require.NotContains(t, msg.TextBody, "<")
+		}
+	}
+}
+
+func TestNewMessageUnknownTemplate(t *testing.T) {
+	_, err := NewMessage(&SendEmailRequest{Type: "unknown"})
+	require.ErrorIs(t, err, ErrUnknownTemplate)
+}
+
+func TestPreview(t *testing.T) {
+	result, err := Preview(ForgotPasswordEmail, LocaleRussian, FormatText)
+	require.NoError(t, err)
+	require.Contains(t, result, "123456")
+	require.Contains(t, result, "пароля")
+
+	_, err = Preview(ForgotPasswordEmail, LocaleRussian, "pdf")
+	require.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestMatchLocale(t *testing.T) {
+	require.Equal(t, LocaleUzbek, MatchLocale("uz"))
+	require.Equal(t, LocaleRussian, MatchLocale("", "ru-RU,ru;q=0.9,en;q=0.8"))
+	require.Equal(t, LocaleEnglish, MatchLocale("", "de-DE,en;q=0.5,ru;q=0.3"))
+	require.Equal(t, LocaleUzbek, MatchLocale("", "en;q=0.4, uz-Latn-UZ;q=0.9"))
+	require.Equal(t, LocaleRussian, MatchLocale("de", "fr, ru;q=0.1"))
+	require.Equal(t, DefaultLocale, MatchLocale("", "de, fr;q=0"))
+	require.Equal(t, DefaultLocale, MatchLocale())
+}
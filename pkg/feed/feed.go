@@ -0,0 +1,72 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"time"
+)
+
+const (
+	FormatRSS  = "rss"
+	FormatAtom = "atom"
+)
+
+// Content types of the formats
+const (
+	RSSContentType  = "application/rss+xml; charset=utf-8"
+	AtomContentType = "application/atom+xml; charset=utf-8"
+)
+
+// Feed is rendered as RSS 2.0 or Atom, every link must be absolute
+type Feed struct {
+	Title       string
+	Description string
+	// Link is the page the feed follows and SelfLink where the feed itself is served
+	Link     string
+	SelfLink string
+	Updated  time.Time
+	Items    []*Item
+}
+
+// Item is identified by its ID, it must never change once the item is published
+type Item struct {
+	ID         string
+	Title      string
+	Link       string
+	Content    string
+	Author     string
+	Categories []string
+	Published  time.Time
+	Updated    time.Time
+}
+
+// Render writes the feed in the format, RSS for anything but Atom
+func Render(f *Feed, format string) ([]byte, error) {
+	var doc interface{}
+	if format == FormatAtom {
+		doc = atomDocument(f)
+	} else {
+		doc = rssDocument(f)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+
+	err := encoder.Encode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ContentType returns the content type of the format
+func ContentType(format string) string {
+	if format == FormatAtom {
+		return AtomContentType
+	}
+	return RSSContentType
+}
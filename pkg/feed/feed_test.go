@@ -0,0 +1,126 @@
+package feed
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testFeed() *Feed {
+	published := time.Date(2024, 3, 1, 10, 0, 0, 0, time.FixedZone("UZT", 5*60*60))
+
+	return &Feed{
+		Title:       "Blog",
+		Description: "Posts of the blog",
+		Link:        "https://blog.example.com/",
+		SelfLink:    "https://blog.example.com/feeds/posts.rss",
+		Updated:     published.Add(time.Hour),
+		Items: []*Item{
+			{
+				ID:         "https://blog.example.com/posts/1",
+				Title:      "Fish & <chips>",
+				Link:       "https://blog.example.com/posts/1",
+				Content:    "A <b>bold</b> claim",
+				Author:     "John Doe",
+				Categories: []string{"Food"},
+				Published:  published,
+				Updated:    published.Add(time.Hour),
+			},
+		},
+	}
+}
+
+func TestRenderRSS(t *testing.T) {
+	data, err := Render(testFeed(), FormatRSS)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"`)
+	require.Contains(t, string(data), `<atom:link href="https://blog.example.com/feeds/posts.rss" rel="self" type="application/rss+xml"></atom:link>`)
+	require.Contains(t, string(data), `<dc:creator>John Doe</dc:creator>`)
+	require.NotContains(t, string(data), "<b>")
+
+	var doc struct {
+		Channel struct {
+			Title         string `xml:"title"`
+			LastBuildDate string `xml:"lastBuildDate"`
+			Items         []struct {
+				Title       string `xml:"title"`
+				Description string `xml:"description"`
+				PubDate     string `xml:"pubDate"`
+				Category    string `xml:"category"`
+				GUID        struct {
+					Value       string `xml:",chardata"`
+					IsPermaLink bool   `xml:"isPermaLink,attr"`
+				} `xml:"guid"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	require.NoError(t, xml.Unmarshal(data, &doc))
+
+	require.Equal(t, "Blog", doc.Channel.Title)
+	require.Equal(t, "Fri, 01 Mar 2024 06:00:00 +0000", doc.Channel.LastBuildDate)
+	require.Len(t, doc.Channel.Items, 1)
+
+	item := doc.Channel.Items[0]
+	require.Equal(t, "Fish & <chips>", item.Title)
+	require.Equal(t, "A <b>bold</b> claim", item.Description)
+	require.Equal(t, "Fri, 01 Mar 2024 05:00:00 +0000", item.PubDate)
+	require.Equal(t, "Food", item.Category)
+	require.Equal(t, "https://blog.example.com/posts/1", item.GUID.Value)
+	require.True(t, item.GUID.IsPermaLink)
+}
+
+func TestRenderAtom(t *testing.T) {
+	f := testFeed()
+	f.SelfLink = "https://blog.example.com/feeds/posts.atom"
+
+	data, err := Render(f, FormatAtom)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `<feed xmlns="http://www.w3.org/2005/Atom">`)
+
+	var doc struct {
+		ID      string `xml:"id"`
+		Updated string `xml:"updated"`
+		Links   []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Entries []struct {
+			ID        string `xml:"id"`
+			Title     string `xml:"title"`
+			Published string `xml:"published"`
+			Updated   string `xml:"updated"`
+			Author    string `xml:"author>name"`
+			Content   struct {
+				Type  string `xml:"type,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"content"`
+			Category struct {
+				Term string `xml:"term,attr"`
+			} `xml:"category"`
+		} `xml:"entry"`
+	}
+	require.NoError(t, xml.Unmarshal(data, &doc))
+
+	require.Equal(t, f.SelfLink, doc.ID)
+	require.Equal(t, "2024-03-01T06:00:00Z", doc.Updated)
+	require.Len(t, doc.Links, 2)
+	require.Equal(t, "self", doc.Links[1].Rel)
+
+	require.Len(t, doc.Entries, 1)
+	entry := doc.Entries[0]
+	require.Equal(t, "https://blog.example.com/posts/1", entry.ID)
+	require.Equal(t, "Fish & <chips>", entry.Title)
+	require.Equal(t, "2024-03-01T05:00:00Z", entry.Published)
+	require.Equal(t, "2024-03-01T06:00:00Z", entry.Updated)
+	require.Equal(t, "John Doe", entry.Author)
+	require.Equal(t, "text", entry.Content.Type)
+	require.Equal(t, "A <b>bold</b> claim", entry.Content.Value)
+	require.Equal(t, "Food", entry.Category.Term)
+}
+
+func TestContentType(t *testing.T) {
+	require.Equal(t, AtomContentType, ContentType(FormatAtom))
+	require.Equal(t, RSSContentType, ContentType(FormatRSS))
+}
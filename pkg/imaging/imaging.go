@@ -0,0 +1,225 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // registers the webp decoder
+)
+
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatGIF  = "gif"
+	FormatWebP = "webp"
+)
+
+// maxPixels keeps a small file that declares huge dimensions from exhausting the memory
+const maxPixels = 50_000_000
+
+var (
+	ErrInvalidImage    = errors.New("image can not be decoded")
+	ErrTooManyPixels   = errors.New("image has too many pixels")
+	ErrUnknownFormat   = errors.New("unknown image format")
+	ErrInvalidVariants = errors.New("invalid image variants")
+)
+
+var extensions = map[string]string{
+	FormatJPEG: ".jpg",
+	FormatPNG:  ".png",
+	FormatGIF:  ".gif",
+	FormatWebP: ".webp",
+}
+
+var contentTypes = map[string]string{
+	FormatJPEG: "image/jpeg",
+	FormatPNG:  "image/png",
+	FormatGIF:  "image/gif",
+	FormatWebP: "image/webp",
+}
+
+// Extension returns the file extension of the format
+func Extension(format string) string {
+	return extensions[format]
+}
+
+// ContentType returns the MIME type of the format
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// Variant is a resized copy of an image, a zero side is not bounded.
+// A cropped variant fills the whole box and cuts what overflows it in the middle
+type Variant struct {
+	Name   string
+	Width  int
+	Height int
+	Crop   bool
+}
+
+// ParseVariants reads variants written as "name:WIDTHxHEIGHT[:crop]" separated by commas
+func ParseVariants(spec string) ([]Variant, error) {
+	variants := make([]Variant, 0)
+	names := make(map[string]bool)
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.Split(item, ":")
+		if len(parts) < 2 || len(parts) > 3 || (len(parts) == 3 && parts[2] != "crop") {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidVariants, item)
+		}
+
+		widthPart, heightPart, ok := strings.Cut(parts[1], "x")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidVariants, item)
+		}
+
+		width, err := strconv.Atoi(widthPart)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidVariants, item)
+		}
+
+		height, err := strconv.Atoi(heightPart)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidVariants, item)
+		}
+
+		variant := Variant{Name: parts[0], Width: width, Height: height, Crop: len(parts) == 3}
+
+		if variant.Name == "" || variant.Name == "original" || names[variant.Name] || strings.ContainsAny(variant.Name, "/.") ||
+			width < 0 || height < 0 || (width == 0 && height == 0) || (variant.Crop && (width == 0 || height == 0)) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidVariants, item)
+		}
+
+		names[variant.Name] = true
+		variants = append(variants, variant)
+	}
+
+	return variants, nil
+}
+
+// Decode reads a JPEG, PNG, GIF or WebP image and turns it upright, the metadata is dropped.
+// The first frame of an animation is used
+func Decode(data []byte) (image.Image, string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	if _, ok := extensions[format]; !ok {
+		return nil, "", fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+
+	if int64(config.Width)*int64(config.Height) > maxPixels {
+		return nil, "", ErrTooManyPixels
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	if format == FormatJPEG {
+		img = orient(img, jpegOrientation(data))
+	}
+
+	return img, format, nil
+}
+
+// Encode writes the image in the format, quality only applies to JPEG
+func Encode(w io.Writer, img image.Image, format string, quality int) error {
+	switch format {
+	case FormatJPEG:
+		return jpeg.Encode(w, flatten(img), &jpeg.Options{Quality: quality})
+	case FormatPNG:
+		return png.Encode(w, img)
+	case FormatGIF:
+		return gif.Encode(w, img, nil)
+	case FormatWebP:
+		return EncodeWebP(w, img)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+}
+
+// flatten puts transparent images on white, JPEG has no alpha channel
+func flatten(img image.Image) image.Image {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return img
+	}
+
+	bounds := img.Bounds()
+	dst := image.NewRGBA(bounds)
+	draw.Draw(dst, bounds, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Over)
+
+	return dst
+}
+
+// Resize makes the variant of the image, it is never scaled up
+func Resize(img image.Image, variant Variant) image.Image {
+	src := img.Bounds()
+	width, height := src.Dx(), src.Dy()
+
+	if variant.Crop {
+		// The largest part of the image with the aspect ratio of the box, in the middle
+		cropWidth, cropHeight := width, width*variant.Height/variant.Width
+		if cropHeight > height {
+			cropWidth, cropHeight = height*variant.Width/variant.Height, height
+		}
+		if cropWidth < 1 {
+			cropWidth = 1
+		}
+		if cropHeight < 1 {
+			cropHeight = 1
+		}
+
+		x := src.Min.X + (width-cropWidth)/2
+		y := src.Min.Y + (height-cropHeight)/2
+		src = image.Rect(x, y, x+cropWidth, y+cropHeight)
+		width, height = cropWidth, cropHeight
+	}
+
+	dstWidth, dstHeight := fit(width, height, variant.Width, variant.Height)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Src, nil)
+
+	return dst
+}
+
+// fit scales the size down into the box keeping the aspect ratio
+func fit(width, height, maxWidth, maxHeight int) (int, int) {
+	scale := 1.0
+	if maxWidth > 0 && width > maxWidth {
+		scale = float64(maxWidth) / float64(width)
+	}
+	if maxHeight > 0 && float64(height)*scale > float64(maxHeight) {
+		scale = float64(maxHeight) / float64(height)
+	}
+
+	dstWidth := int(float64(width)*scale + 0.5)
+	dstHeight := int(float64(height)*scale + 0.5)
+	if dstWidth < 1 {
+		dstWidth = 1
+	}
+	if dstHeight < 1 {
+		dstHeight = 1
+	}
+
+	return dstWidth, dstHeight
+}
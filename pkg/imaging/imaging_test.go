@@ -0,0 +1,127 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVariants(t *testing.T) {
+	variants, err := ParseVariants("thumb:320x320, medium:800x0,avatar:256x256:crop")
+	require.NoError(t, err)
+	require.Equal(t, []Variant{
+		{Name: "thumb", Width: 320, Height: 320},
+		{Name: "medium", Width: 800},
+		{Name: "avatar", Width: 256, Height: 256, Crop: true},
+	}, variants)
+
+	variants, err = ParseVariants("")
+	require.NoError(t, err)
+	require.Empty(t, variants)
+
+	for _, spec := range []string{"thumb", "thumb:320", "thumb:0x0", "thumb:10x0:crop", "thumb:1x1,thumb:2x2",
+		"original:1x1", "../x:1x1", "thumb:1x1:fit", "thumb:-1x1"} {
+		_, err = ParseVariants(spec)
+		require.ErrorIs(t, err, ErrInvalidVariants, spec)
+	}
+}
+
+func TestResize(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 400, 200))
+
+	require.Equal(t, image.Pt(100, 50), Resize(img, Variant{Width: 100, Height: 100}).Bounds().Size())
+	require.Equal(t, image.Pt(200, 100), Resize(img, Variant{Height: 100}).Bounds().Size())
+	require.Equal(t, image.Pt(64, 64), Resize(img, Variant{Width: 64, Height: 64, Crop: true}).Bounds().Size())
+	// Images are not scaled up
+	require.Equal(t, image.Pt(400, 200), Resize(img, Variant{Width: 1000}).Bounds().Size())
+	require.Equal(t, image.Pt(200, 200), Resize(img, Variant{Width: 256, Height: 256, Crop: true}).Bounds().Size())
+}
+
+// withOrientation inserts an EXIF segment with the orientation after the start of the JPEG
+func withOrientation(data []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:], orientationTag)
+	binary.BigEndian.PutUint16(entry[2:], 3)
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	tiff = append(append(tiff, entry...), 0, 0, 0, 0)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	header := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(header[2:], uint16(len(segment)+2))
+
+	result := append([]byte(nil), data[:2]...)
+	result = append(result, header...)
+	result = append(result, segment...)
+	return append(result, data[2:]...)
+}
+
+func TestDecodeOrientation(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 64; x++ {
+			// The left half is black and the right half is white
+			if x >= 32 {
+				img.Set(x, y, color.White)
+			} else {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}))
+
+	decoded, format, err := Decode(withOrientation(buf.Bytes(), 6))
+	require.NoError(t, err)
+	require.Equal(t, FormatJPEG, format)
+	require.Equal(t, image.Pt(32, 64), decoded.Bounds().Size())
+
+	// Turned clockwise the white half is at the bottom
+	r, _, _, _ := decoded.At(16, 60).RGBA()
+	require.Greater(t, r, uint32(0xf000))
+	r, _, _, _ = decoded.At(16, 4).RGBA()
+	require.Less(t, r, uint32(0x1000))
+
+	decoded, _, err = Decode(withOrientation(buf.Bytes(), 1))
+	require.NoError(t, err)
+	require.Equal(t, image.Pt(64, 32), decoded.Bounds().Size())
+
+	_, _, err = Decode([]byte("not an image"))
+	require.ErrorIs(t, err, ErrInvalidImage)
+}
+
+func TestStripWebPMetadata(t *testing.T) {
+	chunk := func(fourCC string, data []byte) []byte {
+		header := make([]byte, 8)
+		copy(header, fourCC)
+		binary.LittleEndian.PutUint32(header[4:], uint32(len(data)))
+		result := append(header, data...)
+		if len(data)%2 == 1 {
+			result = append(result, 0)
+		}
+		return result
+	}
+
+	body := chunk("VP8X", []byte{webpFlagEXIF | webpFlagXMP, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	body = append(body, chunk("VP8L", []byte{1, 2, 3})...)
+	body = append(body, chunk("EXIF", []byte("exif"))...)
+	body = append(body, chunk("XMP ", []byte("xmp"))...)
+
+	data := append([]byte("RIFF\x00\x00\x00\x00WEBP"), body...)
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+
+	stripped := stripWebPMetadata(data)
+
+	expected := append([]byte("RIFF\x00\x00\x00\x00WEBP"), chunk("VP8X", make([]byte, 10))...)
+	expected = append(expected, chunk("VP8L", []byte{1, 2, 3})...)
+	binary.LittleEndian.PutUint32(expected[4:], uint32(len(expected)-8))
+
+	require.Equal(t, expected, stripped)
+}
@@ -0,0 +1,55 @@
+package imaging
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrBusy = errors.New("too many images are being processed, please try again later")
+
+// Pool runs jobs on a fixed number of goroutines, jobs wait in a bounded queue
+type Pool struct {
+	jobs chan func()
+}
+
+func NewPool(workers, queueSize int) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+
+	p := &Pool{jobs: make(chan func(), queueSize)}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range p.jobs {
+				job()
+			}
+		}()
+	}
+
+	return p
+}
+
+// Do runs the job and waits for it, ErrBusy is returned at once when the queue is full.
+// A job whose context is done before its turn does not run
+func (p *Pool) Do(ctx context.Context, job func() error) error {
+	done := make(chan error, 1)
+
+	select {
+	case p.jobs <- func() {
+		if err := ctx.Err(); err != nil {
+			done <- err
+			return
+		}
+		done <- job()
+	}:
+	default:
+		return ErrBusy
+	}
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,195 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/ibrat-muslim/blog-app/pkg/blob"
+)
+
+// Processed images are stored as images/<id>/original.<ext> with the variants next to it
+const (
+	keyPrefix    = "images/"
+	originalName = "original"
+)
+
+type Processor struct {
+	blobs    blob.BlobStore
+	variants map[string][]Variant
+	format   string
+	quality  int
+	pool     *Pool
+}
+
+type ProcessorOptions struct {
+	Blobs blob.BlobStore
+	// Variants are the specs of the variants made for each upload purpose, see ParseVariants
+	Variants map[string]string
+	// Format of the variants, empty keeps the format of the original
+	Format    string
+	Quality   int
+	Workers   int
+	QueueSize int
+}
+
+type Result struct {
+	// Key of the original without its metadata and Size of what was stored there
+	Key    string
+	Size   int64
+	Width  int
+	Height int
+	// Variants are the keys of the variants by name and VariantsSize is what they take together
+	Variants     map[string]string
+	VariantsSize int64
+}
+
+func NewProcessor(options *ProcessorOptions) (*Processor, error) {
+	if options.Format != "" && Extension(options.Format) == "" {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, options.Format)
+	}
+
+	variants := make(map[string][]Variant)
+	for purpose, spec := range options.Variants {
+		parsed, err := ParseVariants(spec)
+		if err != nil {
+			return nil, fmt.Errorf("%s variants: %w", purpose, err)
+		}
+		variants[purpose] = parsed
+	}
+
+	return &Processor{
+		blobs:    options.Blobs,
+		variants: variants,
+		format:   options.Format,
+		quality:  options.Quality,
+		pool:     NewPool(options.Workers, options.QueueSize),
+	}, nil
+}
+
+// Process stores the upright original without its metadata and the variants of the purpose,
+// the work is done in the pool so it returns ErrBusy when too many images are waiting
+func (p *Processor) Process(ctx context.Context, purpose string, data []byte) (*Result, error) {
+	var result *Result
+
+	err := p.pool.Do(ctx, func() error {
+		var err error
+		result, err = p.process(ctx, purpose, data)
+		return err
+	})
+
+	return result, err
+}
+
+func (p *Processor) process(ctx context.Context, purpose string, data []byte) (*Result, error) {
+	img, format, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := keyPrefix + uuid.NewString() + "/"
+	bounds := img.Bounds()
+
+	result := &Result{
+		Key:      dir + originalName + Extension(format),
+		Width:    bounds.Dx(),
+		Height:   bounds.Dy(),
+		Variants: make(map[string]string),
+	}
+
+	// Animations would be lost and WebP would be recompressed, their metadata is dropped as it is instead
+	original := data
+	switch format {
+	case FormatWebP:
+		original = stripWebPMetadata(data)
+	case FormatJPEG, FormatPNG:
+		var buf bytes.Buffer
+		err = Encode(&buf, img, format, p.quality)
+		if err != nil {
+			return nil, err
+		}
+		original = buf.Bytes()
+	}
+
+	result.Size = int64(len(original))
+
+	err = p.blobs.Put(ctx, result.Key, bytes.NewReader(original), result.Size, ContentType(format))
+	if err != nil {
+		return nil, err
+	}
+
+	variantFormat := p.format
+	if variantFormat == "" {
+		variantFormat = format
+	}
+
+	for _, variant := range p.variants[purpose] {
+		var buf bytes.Buffer
+
+		err = Encode(&buf, Resize(img, variant), variantFormat, p.quality)
+		if err != nil {
+			return nil, err
+		}
+
+		key := dir + variant.Name + Extension(variantFormat)
+
+		size := int64(buf.Len())
+
+		err = p.blobs.Put(ctx, key, &buf, size, ContentType(variantFormat))
+		if err != nil {
+			return nil, err
+		}
+
+		result.Variants[variant.Name] = key
+		result.VariantsSize += size
+	}
+
+	return result, nil
+}
+
+// VariantKeys returns the keys of the variants of a processed original by name,
+// nil for anything else
+func (p *Processor) VariantKeys(key, purpose string) map[string]string {
+	dir, file := path.Split(key)
+	if !strings.HasPrefix(dir, keyPrefix) || strings.TrimSuffix(file, path.Ext(file)) != originalName {
+		return nil
+	}
+
+	variants := p.variants[purpose]
+	if len(variants) == 0 {
+		return nil
+	}
+
+	ext := path.Ext(file)
+	if p.format != "" {
+		ext = Extension(p.format)
+	}
+
+	keys := make(map[string]string, len(variants))
+	for _, variant := range variants {
+		keys[variant.Name] = dir + variant.Name + ext
+	}
+
+	return keys
+}
+
+// Keys returns the key with the keys of the variants any purpose could have made of it,
+// so an original can be deleted along with its copies
+func (p *Processor) Keys(key string) []string {
+	keys := []string{key}
+	seen := map[string]bool{key: true}
+
+	for purpose := range p.variants {
+		for _, variant := range p.VariantKeys(key, purpose) {
+			if !seen[variant] {
+				seen[variant] = true
+				keys = append(keys, variant)
+			}
+		}
+	}
+
+	return keys
+}
@@ -0,0 +1,111 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ibrat-muslim/blog-app/pkg/blob"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessor(t *testing.T) {
+	dir := t.TempDir()
+
+	processor, err := NewProcessor(&ProcessorOptions{
+		Blobs: blob.NewLocalStore(dir),
+		Variants: map[string]string{
+			"post_image": "thumb:100x100,large:1000x1000",
+			"avatar":     "square:50x50:crop",
+		},
+		Format:    FormatWebP,
+		Quality:   80,
+		Workers:   2,
+		QueueSize: 4,
+	})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 400, 300))))
+
+	result, err := processor.Process(context.Background(), "post_image", buf.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, 400, result.Width)
+	require.Equal(t, 300, result.Height)
+	require.Regexp(t, `^images/[0-9a-f-]+/original\.png$`, result.Key)
+	require.Len(t, result.Variants, 2)
+	require.Equal(t, processor.VariantKeys(result.Key, "post_image"), result.Variants)
+
+	thumb, err := os.Open(filepath.Join(dir, filepath.FromSlash(result.Variants["thumb"])))
+	require.NoError(t, err)
+	defer thumb.Close()
+
+	config, format, err := image.DecodeConfig(thumb)
+	require.NoError(t, err)
+	require.Equal(t, FormatWebP, format)
+	require.Equal(t, 100, config.Width)
+	require.Equal(t, 75, config.Height)
+
+	info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(result.Key)))
+	require.NoError(t, err)
+	require.Equal(t, info.Size(), result.Size)
+
+	var variantsSize int64
+	for _, key := range result.Variants {
+		info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(key)))
+		require.NoError(t, err)
+		variantsSize += info.Size()
+	}
+	require.Equal(t, variantsSize, result.VariantsSize)
+
+	require.Nil(t, processor.VariantKeys("photo.png", "post_image"))
+	require.Nil(t, processor.VariantKeys(result.Key, "attachment"))
+
+	imageDir := result.Key[:len(result.Key)-len("original.png")]
+	require.ElementsMatch(t, []string{result.Key, imageDir + "thumb.webp", imageDir + "large.webp", imageDir + "square.webp"}, processor.Keys(result.Key))
+	require.Equal(t, []string{"photo.png"}, processor.Keys("photo.png"))
+
+	_, err = processor.Process(context.Background(), "post_image", []byte("not an image"))
+	require.Error(t, err)
+
+	_, err = NewProcessor(&ProcessorOptions{Variants: map[string]string{"avatar": "square"}})
+	require.ErrorIs(t, err, ErrInvalidVariants)
+
+	_, err = NewProcessor(&ProcessorOptions{Format: "bmp"})
+	require.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestPoolBusy(t *testing.T) {
+	pool := NewPool(1, 1)
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	go pool.Do(context.Background(), func() error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	// The queue holds one job while the worker is busy
+	queued := make(chan error, 1)
+	go func() {
+		queued <- pool.Do(context.Background(), func() error { return io.EOF })
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(pool.jobs) == 1
+	}, time.Second, time.Millisecond)
+
+	err := pool.Do(context.Background(), func() error { return nil })
+	require.ErrorIs(t, err, ErrBusy)
+
+	close(release)
+	require.ErrorIs(t, <-queued, io.EOF)
+}
@@ -0,0 +1,479 @@
+package imaging
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"sort"
+)
+
+// A lossless WebP (VP8L) encoder, see https://developers.google.com/speed/webp/docs/webp_lossless_bitstream_specification.
+// It uses the subtract green and predictor transforms with one set of prefix codes and no backward references,
+// which is far from the best compression but keeps the encoder small
+
+const (
+	vp8lSignature = 0x2f
+	vp8lMaxSize   = 1 << 14
+
+	transformPredictor     = 0
+	transformSubtractGreen = 2
+
+	// predictorBits sets the 16x16 blocks that choose their own predictor
+	predictorBits = 4
+
+	maxCodeLength           = 15
+	maxCodeLengthCodeLength = 7
+)
+
+// codeLengthCodeOrder is the order the lengths of the code length code are written in
+var codeLengthCodeOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// alphabetSizes of the green, red, blue, alpha and distance codes without a color cache
+var alphabetSizes = [5]int{256 + 24, 256, 256, 256, 40}
+
+// candidatePredictors are tried on every block: left, top, select and clamped gradient
+var candidatePredictors = []int{1, 2, 11, 12}
+
+var ErrImageTooLarge = errors.New("image is too large for webp")
+
+// EncodeWebP writes the image as a lossless WebP
+func EncodeWebP(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 1 || height < 1 || width > vp8lMaxSize || height > vp8lMaxSize {
+		return ErrImageTooLarge
+	}
+
+	nrgba := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+
+	argb := make([]uint32, width*height)
+	alphaUsed := false
+	for i := range argb {
+		p := nrgba.Pix[i*4 : i*4+4]
+		argb[i] = uint32(p[3])<<24 | uint32(p[0])<<16 | uint32(p[1])<<8 | uint32(p[2])
+		if p[3] != 0xff {
+			alphaUsed = true
+		}
+	}
+
+	bw := &bitWriter{}
+	bw.write(vp8lSignature, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	if alphaUsed {
+		bw.write(1, 1)
+	} else {
+		bw.write(0, 1)
+	}
+	bw.write(0, 3)
+
+	subtractGreen(argb)
+	bw.write(1, 1)
+	bw.write(transformSubtractGreen, 2)
+
+	modes, residuals := predict(argb, width, height)
+	bw.write(1, 1)
+	bw.write(transformPredictor, 2)
+	bw.write(predictorBits-2, 3)
+	writeImage(bw, modes, false)
+
+	// No more transforms
+	bw.write(0, 1)
+	writeImage(bw, residuals, true)
+
+	data := bw.bytes()
+
+	chunkSize := len(data)
+	padding := chunkSize & 1
+
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(12+chunkSize+padding))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(chunkSize))
+
+	buf := bufio.NewWriter(w)
+	buf.Write(header)
+	buf.Write(data)
+	if padding == 1 {
+		buf.WriteByte(0)
+	}
+
+	return buf.Flush()
+}
+
+func subtractGreen(argb []uint32) {
+	for i, p := range argb {
+		green := (p >> 8) & 0xff
+		red := ((p >> 16) - green) & 0xff
+		blue := (p - green) & 0xff
+		argb[i] = p&0xff00ff00 | red<<16 | blue
+	}
+}
+
+// predict chooses a predictor for every block and returns the modes image with the residuals
+func predict(argb []uint32, width, height int) ([]uint32, []uint32) {
+	blockSize := 1 << predictorBits
+	blocksX := (width + blockSize - 1) / blockSize
+	blocksY := (height + blockSize - 1) / blockSize
+
+	modes := make([]uint32, blocksX*blocksY)
+	residuals := make([]uint32, len(argb))
+
+	for by := 0; by < blocksY; by++ {
+		for bx := 0; bx < blocksX; bx++ {
+			best, bestCost := candidatePredictors[0], -1
+			for _, mode := range candidatePredictors {
+				cost := 0
+				forBlock(bx, by, width, height, func(x, y int) {
+					cost += residualCost(sub(argb[y*width+x], prediction(argb, width, x, y, mode)))
+				})
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+
+			modes[by*blocksX+bx] = 0xff000000 | uint32(best)<<8
+			forBlock(bx, by, width, height, func(x, y int) {
+				residuals[y*width+x] = sub(argb[y*width+x], prediction(argb, width, x, y, best))
+			})
+		}
+	}
+
+	return modes, residuals
+}
+
+func forBlock(bx, by, width, height int, fn func(x, y int)) {
+	blockSize := 1 << predictorBits
+	for y := by * blockSize; y < (by+1)*blockSize && y < height; y++ {
+		for x := bx * blockSize; x < (bx+1)*blockSize && x < width; x++ {
+			fn(x, y)
+		}
+	}
+}
+
+// prediction follows the decoder, the first row and column ignore the mode of their block
+func prediction(argb []uint32, width, x, y, mode int) uint32 {
+	switch {
+	case x == 0 && y == 0:
+		return 0xff000000
+	case y == 0:
+		return argb[x-1]
+	case x == 0:
+		return argb[(y-1)*width]
+	}
+
+	left := argb[y*width+x-1]
+	top := argb[(y-1)*width+x]
+	topLeft := argb[(y-1)*width+x-1]
+
+	switch mode {
+	case 1:
+		return left
+	case 2:
+		return top
+	case 11:
+		return selectPredictor(left, top, topLeft)
+	default:
+		return clampAddSubtractFull(left, top, topLeft)
+	}
+}
+
+func selectPredictor(left, top, topLeft uint32) uint32 {
+	distanceLeft, distanceTop := 0, 0
+	for shift := 0; shift < 32; shift += 8 {
+		l := int(left >> shift & 0xff)
+		t := int(top >> shift & 0xff)
+		tl := int(topLeft >> shift & 0xff)
+		estimate := l + t - tl
+		distanceLeft += abs(estimate - l)
+		distanceTop += abs(estimate - t)
+	}
+
+	if distanceLeft < distanceTop {
+		return left
+	}
+	return top
+}
+
+func clampAddSubtractFull(a, b, c uint32) uint32 {
+	var result uint32
+	for shift := 0; shift < 32; shift += 8 {
+		v := int(a>>shift&0xff) + int(b>>shift&0xff) - int(c>>shift&0xff)
+		if v < 0 {
+			v = 0
+		} else if v > 0xff {
+			v = 0xff
+		}
+		result |= uint32(v) << shift
+	}
+	return result
+}
+
+// sub subtracts every channel modulo 256
+func sub(a, b uint32) uint32 {
+	var result uint32
+	for shift := 0; shift < 32; shift += 8 {
+		result |= ((a>>shift - b>>shift) & 0xff) << shift
+	}
+	return result
+}
+
+// residualCost estimates how well a residual compresses, small values either side of zero are cheap
+func residualCost(residual uint32) int {
+	cost := 0
+	for shift := 0; shift < 32; shift += 8 {
+		cost += abs(int(int8(residual >> shift)))
+	}
+	return cost
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// writeImage writes the prefix codes of the pixels followed by the pixels,
+// only the main image says it has no meta prefix codes
+func writeImage(bw *bitWriter, argb []uint32, main bool) {
+	// No color cache
+	bw.write(0, 1)
+	if main {
+		bw.write(0, 1)
+	}
+
+	var histograms [5][]int
+	for i := range histograms {
+		histograms[i] = make([]int, alphabetSizes[i])
+	}
+
+	for _, p := range argb {
+		histograms[0][p>>8&0xff]++
+		histograms[1][p>>16&0xff]++
+		histograms[2][p&0xff]++
+		histograms[3][p>>24]++
+	}
+
+	var codes [5][]prefixCode
+	for i, histogram := range histograms {
+		codes[i] = writePrefixCode(bw, histogram)
+	}
+
+	for _, p := range argb {
+		codes[0][p>>8&0xff].write(bw)
+		codes[1][p>>16&0xff].write(bw)
+		codes[2][p&0xff].write(bw)
+		codes[3][p>>24].write(bw)
+	}
+}
+
+type prefixCode struct {
+	code   uint32
+	length int
+}
+
+// write puts the code most significant bit first, as the decoder reads it
+func (c prefixCode) write(bw *bitWriter) {
+	for i := c.length - 1; i >= 0; i-- {
+		bw.write(c.code>>i&1, 1)
+	}
+}
+
+// writePrefixCode writes the code of the histogram and returns it by symbol
+func writePrefixCode(bw *bitWriter, histogram []int) []prefixCode {
+	used := make([]int, 0, 2)
+	for symbol, count := range histogram {
+		if count > 0 {
+			used = append(used, symbol)
+		}
+	}
+
+	// One or two symbols below 256 fit the simple code, a single symbol takes no bits
+	if len(used) <= 2 && (len(used) == 0 || used[len(used)-1] < 256) {
+		codes := make([]prefixCode, len(histogram))
+
+		bw.write(1, 1)
+		if len(used) == 0 {
+			used = append(used, 0)
+		}
+		bw.write(uint32(len(used)-1), 1)
+		bw.write(1, 1)
+		bw.write(uint32(used[0]), 8)
+		if len(used) == 2 {
+			bw.write(uint32(used[1]), 8)
+			codes[used[0]] = prefixCode{code: 0, length: 1}
+			codes[used[1]] = prefixCode{code: 1, length: 1}
+		}
+
+		return codes
+	}
+
+	lengths := codeLengths(histogram, maxCodeLength)
+
+	// The lengths are written as literals with a code of their own
+	lengthHistogram := make([]int, 19)
+	for _, length := range lengths {
+		lengthHistogram[length]++
+	}
+
+	distinct := 0
+	for _, count := range lengthHistogram {
+		if count > 0 {
+			distinct++
+		}
+	}
+	if distinct == 1 {
+		// A code needs two symbols to take a bit, a length that is never used keeps it complete
+		if lengthHistogram[0] == 0 {
+			lengthHistogram[0] = 1
+		} else {
+			lengthHistogram[1] = 1
+		}
+	}
+
+	lengthCodeLengths := codeLengths(lengthHistogram, maxCodeLengthCodeLength)
+
+	count := len(codeLengthCodeOrder)
+	for count > 4 && lengthCodeLengths[codeLengthCodeOrder[count-1]] == 0 {
+		count--
+	}
+
+	bw.write(0, 1)
+	bw.write(uint32(count-4), 4)
+	for _, symbol := range codeLengthCodeOrder[:count] {
+		bw.write(uint32(lengthCodeLengths[symbol]), 3)
+	}
+
+	// Every symbol has a length
+	bw.write(0, 1)
+
+	lengthCodes := canonicalCodes(lengthCodeLengths)
+	for _, length := range lengths {
+		lengthCodes[length].write(bw)
+	}
+
+	return canonicalCodes(lengths)
+}
+
+// codeLengths builds a Huffman code no longer than maxLength, the counts are
+// flattened until the code fits
+func codeLengths(histogram []int, maxLength int) []int {
+	counts := append([]int(nil), histogram...)
+
+	for {
+		lengths := huffmanLengths(counts)
+
+		longest := 0
+		for _, length := range lengths {
+			if length > longest {
+				longest = length
+			}
+		}
+		if longest <= maxLength {
+			return lengths
+		}
+
+		for i, count := range counts {
+			if count > 1 {
+				counts[i] = (count + 1) / 2
+			}
+		}
+	}
+}
+
+type huffmanNode struct {
+	count   int
+	symbols []int
+}
+
+// huffmanLengths returns the depth of every used symbol in a Huffman tree of the counts
+func huffmanLengths(counts []int) []int {
+	lengths := make([]int, len(counts))
+
+	nodes := make([]*huffmanNode, 0, len(counts))
+	for symbol, count := range counts {
+		if count > 0 {
+			nodes = append(nodes, &huffmanNode{count: count, symbols: []int{symbol}})
+		}
+	}
+
+	if len(nodes) == 1 {
+		lengths[nodes[0].symbols[0]] = 1
+		return lengths
+	}
+
+	for len(nodes) > 1 {
+		sort.SliceStable(nodes, func(i, j int) bool {
+			return nodes[i].count < nodes[j].count
+		})
+
+		merged := &huffmanNode{
+			count:   nodes[0].count + nodes[1].count,
+			symbols: append(append([]int(nil), nodes[0].symbols...), nodes[1].symbols...),
+		}
+		for _, symbol := range merged.symbols {
+			lengths[symbol]++
+		}
+
+		nodes = append(nodes[2:], merged)
+	}
+
+	return lengths
+}
+
+// canonicalCodes assigns codes in the order of length, then symbol
+func canonicalCodes(lengths []int) []prefixCode {
+	var lengthCounts [maxCodeLength + 1]uint32
+	for _, length := range lengths {
+		lengthCounts[length]++
+	}
+	lengthCounts[0] = 0
+
+	var next [maxCodeLength + 1]uint32
+	code := uint32(0)
+	for length := 1; length <= maxCodeLength; length++ {
+		code = (code + lengthCounts[length-1]) << 1
+		next[length] = code
+	}
+
+	codes := make([]prefixCode, len(lengths))
+	for symbol, length := range lengths {
+		if length > 0 {
+			codes[symbol] = prefixCode{code: next[length], length: length}
+			next[length]++
+		}
+	}
+
+	return codes
+}
+
+// bitWriter packs values least significant bit first
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (w *bitWriter) write(value uint32, nbits uint) {
+	w.acc |= uint64(value) << w.nbits
+	w.nbits += nbits
+	for w.nbits >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.nbits -= 8
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	if w.nbits > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc, w.nbits = 0, 0
+	}
+	return w.buf
+}
@@ -0,0 +1,62 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/webp"
+)
+
+func requireSameImage(t *testing.T, expected *image.NRGBA, actual image.Image) {
+	require.Equal(t, expected.Bounds().Size(), actual.Bounds().Size())
+
+	for y := 0; y < expected.Bounds().Dy(); y++ {
+		for x := 0; x < expected.Bounds().Dx(); x++ {
+			want := expected.NRGBAAt(x, y)
+			got := color.NRGBAModel.Convert(actual.At(actual.Bounds().Min.X+x, actual.Bounds().Min.Y+y)).(color.NRGBA)
+			require.Equal(t, want, got, "pixel %d,%d", x, y)
+		}
+	}
+}
+
+func TestEncodeWebP(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	gradient := image.NewNRGBA(image.Rect(0, 0, 37, 21))
+	for y := 0; y < 21; y++ {
+		for x := 0; x < 37; x++ {
+			gradient.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 7), G: uint8(y * 12), B: uint8(x + y), A: 0xff})
+		}
+	}
+
+	noise := image.NewNRGBA(image.Rect(0, 0, 50, 40))
+	random.Read(noise.Pix)
+
+	solid := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for i := range solid.Pix {
+		solid.Pix[i] = 0x80
+	}
+
+	single := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	single.SetNRGBA(0, 0, color.NRGBA{R: 1, G: 2, B: 3, A: 4})
+
+	for name, img := range map[string]*image.NRGBA{
+		"gradient": gradient,
+		"noise":    noise,
+		"solid":    solid,
+		"single":   single,
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, EncodeWebP(&buf, img))
+
+			decoded, err := webp.Decode(&buf)
+			require.NoError(t, err)
+			requireSameImage(t, img, decoded)
+		})
+	}
+}
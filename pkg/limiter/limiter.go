@@ -0,0 +1,119 @@
+package limiter
+
+import "time"
+
+// Store is the subset of the in-memory storage used for counting attempts
+type Store interface {
+	Set(key, value string, exp time.Duration) error
+	Incr(key string, exp time.Duration) (int64, error)
+	TTL(key string) (time.Duration, error)
+	Del(keys ...string) error
+}
+
+// Policy describes how failures are punished, zero values disable the related step
+type Policy struct {
+	// FreeAttempts is the number of failures allowed before delays kick in
+	FreeAttempts int64
+	// BaseDelay doubles with every failure after the free attempts
+	BaseDelay time.Duration
+	// MaxAttempts locks the identifier out for Lockout once reached
+	MaxAttempts int64
+	Window      time.Duration
+	Lockout     time.Duration
+}
+
+// Result describes the state after a failed attempt
+type Result struct {
+	Attempts   int64
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+type Limiter struct {
+	store  Store
+	prefix string
+	policy Policy
+}
+
+func New(store Store, prefix string, policy Policy) *Limiter {
+	return &Limiter{
+		store:  store,
+		prefix: prefix,
+		policy: policy,
+	}
+}
+
+// Blocked returns how long the identifier has to wait before the next attempt
+func (l *Limiter) Blocked(id string) (time.Duration, error) {
+	ttl, err := l.store.TTL(l.blockKey(id))
+	if err != nil {
+		return 0, err
+	}
+
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// Fail records a failed attempt and blocks the identifier when the policy requires it
+func (l *Limiter) Fail(id string) (*Result, error) {
+	attempts, err := l.store.Incr(l.attemptsKey(id), l.policy.Window)
+	if err != nil {
+		return nil, err
+	}
+
+	result := Result{Attempts: attempts}
+
+	if l.policy.MaxAttempts > 0 && attempts >= l.policy.MaxAttempts {
+		result.Locked = true
+		result.RetryAfter = l.policy.Lockout
+
+		// The counter starts from scratch once the lockout is over
+		err = l.store.Del(l.attemptsKey(id))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		result.RetryAfter = l.policy.Delay(attempts)
+	}
+
+	if result.RetryAfter > 0 {
+		err = l.store.Set(l.blockKey(id), "1", result.RetryAfter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &result, nil
+}
+
+// Reset forgets all failed attempts of the identifier
+func (l *Limiter) Reset(id string) error {
+	return l.store.Del(l.attemptsKey(id), l.blockKey(id))
+}
+
+// Delay returns the progressive delay after the given number of failures
+func (p Policy) Delay(attempts int64) time.Duration {
+	if p.BaseDelay <= 0 || attempts <= p.FreeAttempts {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < attempts; i++ {
+		delay *= 2
+		if p.Lockout > 0 && delay >= p.Lockout {
+			return p.Lockout
+		}
+	}
+
+	return delay
+}
+
+func (l *Limiter) attemptsKey(id string) string {
+	return l.prefix + "attempts_" + id
+}
+
+func (l *Limiter) blockKey(id string) string {
+	return l.prefix + "block_" + id
+}
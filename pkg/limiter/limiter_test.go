@@ -0,0 +1,105 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	values map[string]int64
+	ttls   map[string]time.Duration
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		values: make(map[string]int64),
+		ttls:   make(map[string]time.Duration),
+	}
+}
+
+func (m *memoryStore) Set(key, value string, exp time.Duration) error {
+	m.values[key] = 1
+	m.ttls[key] = exp
+	return nil
+}
+
+func (m *memoryStore) Incr(key string, exp time.Duration) (int64, error) {
+	m.values[key]++
+	if m.values[key] == 1 {
+		m.ttls[key] = exp
+	}
+	return m.values[key], nil
+}
+
+func (m *memoryStore) TTL(key string) (time.Duration, error) {
+	if _, ok := m.values[key]; !ok {
+		return -2, nil
+	}
+	return m.ttls[key], nil
+}
+
+func (m *memoryStore) Del(keys ...string) error {
+	for _, key := range keys {
+		delete(m.values, key)
+		delete(m.ttls, key)
+	}
+	return nil
+}
+
+func TestPolicyDelay(t *testing.T) {
+	policy := Policy{FreeAttempts: 2, BaseDelay: time.Second, Lockout: 10 * time.Second}
+
+	require.Zero(t, policy.Delay(1))
+	require.Zero(t, policy.Delay(2))
+	require.Equal(t, time.Second, policy.Delay(3))
+	require.Equal(t, 2*time.Second, policy.Delay(4))
+	require.Equal(t, 8*time.Second, policy.Delay(6))
+	require.Equal(t, 10*time.Second, policy.Delay(7))
+}
+
+func TestLimiter(t *testing.T) {
+	store := newMemoryStore()
+
+	l := New(store, "login_", Policy{
+		FreeAttempts: 1,
+		BaseDelay:    time.Second,
+		MaxAttempts:  3,
+		Window:       time.Minute,
+		Lockout:      time.Hour,
+	})
+
+	result, err := l.Fail("user@example.com")
+	require.NoError(t, err)
+	require.False(t, result.Locked)
+	require.Zero(t, result.RetryAfter)
+
+	retryAfter, err := l.Blocked("user@example.com")
+	require.NoError(t, err)
+	require.Zero(t, retryAfter)
+
+	result, err = l.Fail("user@example.com")
+	require.NoError(t, err)
+	require.Equal(t, time.Second, result.RetryAfter)
+
+	retryAfter, err = l.Blocked("user@example.com")
+	require.NoError(t, err)
+	require.Equal(t, time.Second, retryAfter)
+
+	result, err = l.Fail("user@example.com")
+	require.NoError(t, err)
+	require.True(t, result.Locked)
+	require.Equal(t, time.Hour, result.RetryAfter)
+
+	retryAfter, err = l.Blocked("user@example.com")
+	require.NoError(t, err)
+	require.Equal(t, time.Hour, retryAfter)
+
+	err = l.Reset("user@example.com")
+	require.NoError(t, err)
+
+	retryAfter, err = l.Blocked("user@example.com")
+	require.NoError(t, err)
+	require.Zero(t, retryAfter)
+}
@@ -0,0 +1,134 @@
+package oidc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ibrat-muslim/blog-app/pkg/oidc"
+	"github.com/ibrat-muslim/blog-app/pkg/oidc/oidctest"
+	"github.com/stretchr/testify/require"
+)
+
+const redirectURL = "http://localhost:8000/v1/auth/oidc/callback"
+
+func newProvider(t *testing.T) (*oidctest.Issuer, *oidc.Provider) {
+	issuer, err := oidctest.NewIssuer("blog-app", "secret")
+	require.NoError(t, err)
+	t.Cleanup(issuer.Close)
+
+	issuer.SetUser(oidctest.User{
+		Subject:       "42",
+		Email:         "john@example.com",
+		EmailVerified: true,
+		GivenName:     "John",
+		FamilyName:    "Doe",
+	})
+
+	provider := oidc.New(oidc.Config{
+		IssuerURL:    issuer.URL,
+		ClientID:     issuer.ClientID,
+		ClientSecret: issuer.ClientSecret,
+		RedirectURL:  redirectURL,
+	})
+
+	return issuer, provider
+}
+
+func authorize(t *testing.T, issuer *oidctest.Issuer, provider *oidc.Provider, nonce, verifier string) string {
+	authURL, err := provider.AuthCodeURL(context.Background(), "state", nonce, verifier)
+	require.NoError(t, err)
+
+	code, state, err := issuer.Authorize(authURL)
+	require.NoError(t, err)
+	require.Equal(t, "state", state)
+	require.NotEmpty(t, code)
+
+	return code
+}
+
+func TestLoginFlow(t *testing.T) {
+	issuer, provider := newProvider(t)
+	ctx := context.Background()
+
+	verifier, err := oidc.GenerateVerifier()
+	require.NoError(t, err)
+
+	code := authorize(t, issuer, provider, "nonce", verifier)
+
+	token, err := provider.Exchange(ctx, code, verifier)
+	require.NoError(t, err)
+
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, "nonce")
+	require.NoError(t, err)
+	require.Equal(t, "42", claims.Subject)
+	require.Equal(t, "john@example.com", claims.Email)
+	require.True(t, bool(claims.EmailVerified))
+	require.Equal(t, "John", claims.GivenName)
+}
+
+func TestExchangeRequiresVerifier(t *testing.T) {
+	issuer, provider := newProvider(t)
+
+	verifier, err := oidc.GenerateVerifier()
+	require.NoError(t, err)
+
+	code := authorize(t, issuer, provider, "nonce", verifier)
+
+	_, err = provider.Exchange(context.Background(), code, "wrong-verifier")
+	require.Error(t, err)
+}
+
+func TestVerifyIDTokenNonce(t *testing.T) {
+	issuer, provider := newProvider(t)
+	ctx := context.Background()
+
+	verifier, err := oidc.GenerateVerifier()
+	require.NoError(t, err)
+
+	code := authorize(t, issuer, provider, "nonce", verifier)
+
+	token, err := provider.Exchange(ctx, code, verifier)
+	require.NoError(t, err)
+
+	_, err = provider.VerifyIDToken(ctx, token.IDToken, "other")
+	require.ErrorIs(t, err, oidc.ErrNonceMismatch)
+}
+
+func TestVerifyIDTokenAudience(t *testing.T) {
+	issuer, provider := newProvider(t)
+	ctx := context.Background()
+
+	verifier, err := oidc.GenerateVerifier()
+	require.NoError(t, err)
+
+	code := authorize(t, issuer, provider, "nonce", verifier)
+
+	token, err := provider.Exchange(ctx, code, verifier)
+	require.NoError(t, err)
+
+	other := oidc.New(oidc.Config{
+		IssuerURL: issuer.URL,
+		ClientID:  "other-client",
+	})
+
+	_, err = other.VerifyIDToken(ctx, token.IDToken, "nonce")
+	require.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+}
+
+func TestVerifyIDTokenSignature(t *testing.T) {
+	issuer, provider := newProvider(t)
+	ctx := context.Background()
+
+	verifier, err := oidc.GenerateVerifier()
+	require.NoError(t, err)
+
+	code := authorize(t, issuer, provider, "nonce", verifier)
+
+	token, err := provider.Exchange(ctx, code, verifier)
+	require.NoError(t, err)
+
+	tampered := token.IDToken[:len(token.IDToken)-4] + "AAAA"
+
+	_, err = provider.VerifyIDToken(ctx, tampered, "nonce")
+	require.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+}
@@ -0,0 +1,107 @@
+package password
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+var (
+	ErrMismatch         = errors.New("password does not match")
+	ErrUnknownAlgorithm = errors.New("unknown password hashing algorithm")
+	ErrUnknownHash      = errors.New("unknown password hash format")
+)
+
+// Options configures hashing of new passwords and the policy they must satisfy,
+// hashes made with other settings are still verified and reported for a rehash
+type Options struct {
+	Algorithm  string
+	BcryptCost int
+	Argon2     Argon2Params
+	MinLength  int
+}
+
+// Service is the single place passwords are validated, hashed and verified
+type Service struct {
+	opts Options
+}
+
+func New(opts Options) (*Service, error) {
+	switch opts.Algorithm {
+	case AlgorithmBcrypt:
+		if opts.BcryptCost < bcrypt.MinCost || opts.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case AlgorithmArgon2id:
+		if opts.Argon2.Memory == 0 || opts.Argon2.Iterations == 0 || opts.Argon2.Parallelism == 0 {
+			return nil, errors.New("argon2id memory, iterations and parallelism must be positive")
+		}
+	default:
+		return nil, ErrUnknownAlgorithm
+	}
+
+	return &Service{opts: opts}, nil
+}
+
+// Hash returns the hash of the password with the configured algorithm
+func (s *Service) Hash(password string) (string, error) {
+	var (
+		hash string
+		err  error
+	)
+
+	switch s.opts.Algorithm {
+	case AlgorithmArgon2id:
+		hash, err = hashArgon2id(password, s.opts.Argon2)
+	default:
+		var data []byte
+		data, err = bcrypt.GenerateFromPassword([]byte(password), s.opts.BcryptCost)
+		hash = string(data)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	return hash, nil
+}
+
+// Verify checks the password against the hash, rehash is true when the hash was made
+// with another algorithm or cost and should be replaced while the password is known
+func (s *Service) Verify(password, hash string) (rehash bool, err error) {
+	if strings.HasPrefix(hash, "$"+AlgorithmArgon2id+"$") {
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false, err
+		}
+
+		other := argon2idKey(password, salt, params, uint32(len(key)))
+		if subtle.ConstantTimeCompare(key, other) != 1 {
+			return false, ErrMismatch
+		}
+
+		return s.opts.Algorithm != AlgorithmArgon2id || params != s.opts.Argon2, nil
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, ErrMismatch
+		}
+		return false, ErrUnknownHash
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return false, ErrUnknownHash
+	}
+
+	return s.opts.Algorithm != AlgorithmBcrypt || cost != s.opts.BcryptCost, nil
+}
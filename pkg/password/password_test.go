@@ -0,0 +1,97 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testArgon2 = Argon2Params{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+}
+
+func newService(t *testing.T, algorithm string) *Service {
+	s, err := New(Options{
+		Algorithm:  algorithm,
+		BcryptCost: bcrypt.MinCost,
+		Argon2:     testArgon2,
+		MinLength:  8,
+	})
+	require.NoError(t, err)
+
+	return s
+}
+
+func TestHashAndVerify(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmBcrypt, AlgorithmArgon2id} {
+		s := newService(t, algorithm)
+
+		hash, err := s.Hash("correct horse battery")
+		require.NoError(t, err)
+		require.NotContains(t, hash, "correct horse battery")
+
+		rehash, err := s.Verify("correct horse battery", hash)
+		require.NoError(t, err, algorithm)
+		require.False(t, rehash, algorithm)
+
+		_, err = s.Verify("wrong horse battery", hash)
+		require.ErrorIs(t, err, ErrMismatch, algorithm)
+	}
+}
+
+func TestArgon2idFormat(t *testing.T) {
+	hash, err := newService(t, AlgorithmArgon2id).Hash("correct horse battery")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+}
+
+func TestVerifyRehash(t *testing.T) {
+	bcryptService := newService(t, AlgorithmBcrypt)
+	argon2Service := newService(t, AlgorithmArgon2id)
+
+	bcryptHash, err := bcryptService.Hash("correct horse battery")
+	require.NoError(t, err)
+
+	rehash, err := argon2Service.Verify("correct horse battery", bcryptHash)
+	require.NoError(t, err)
+	require.True(t, rehash)
+
+	argon2Hash, err := argon2Service.Hash("correct horse battery")
+	require.NoError(t, err)
+
+	rehash, err = bcryptService.Verify("correct horse battery", argon2Hash)
+	require.NoError(t, err)
+	require.True(t, rehash)
+
+	costlier, err := New(Options{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost + 1})
+	require.NoError(t, err)
+
+	rehash, err = costlier.Verify("correct horse battery", bcryptHash)
+	require.NoError(t, err)
+	require.True(t, rehash)
+}
+
+func TestVerifyUnknownHash(t *testing.T) {
+	s := newService(t, AlgorithmBcrypt)
+
+	_, err := s.Verify("correct horse battery", "plaintext")
+	require.ErrorIs(t, err, ErrUnknownHash)
+
+	_, err = s.Verify("correct horse battery", "$argon2id$v=19$garbage")
+	require.ErrorIs(t, err, ErrUnknownHash)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Options{Algorithm: "md5"})
+	require.ErrorIs(t, err, ErrUnknownAlgorithm)
+
+	_, err = New(Options{Algorithm: AlgorithmBcrypt, BcryptCost: 100})
+	require.Error(t, err)
+
+	_, err = New(Options{Algorithm: AlgorithmArgon2id})
+	require.Error(t, err)
+}
@@ -0,0 +1,84 @@
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxLength is the longest password bcrypt takes into account, in bytes
+const MaxLength = 72
+
+// minPersonalLength keeps short names from rejecting every password that contains them
+const minPersonalLength = 4
+
+var (
+	ErrTooLong  = fmt.Errorf("password must be at most %d bytes long", MaxLength)
+	ErrPersonal = errors.New("password must not contain your name or email")
+	ErrCommon   = errors.New("password is too common, please choose another one")
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+var commonPasswords = parseCommonPasswords(commonPasswordsFile)
+
+func parseCommonPasswords(data string) map[string]struct{} {
+	result := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		result[strings.ToLower(line)] = struct{}{}
+	}
+
+	return result
+}
+
+// Validate checks the password against the policy, personal is the data of the user
+// such as the name and the email that the password must not be based on
+func (s *Service) Validate(password string, personal ...string) error {
+	if len([]rune(password)) < s.opts.MinLength {
+		return fmt.Errorf("password must be at least %d characters long", s.opts.MinLength)
+	}
+
+	if len(password) > MaxLength {
+		return ErrTooLong
+	}
+
+	lower := strings.ToLower(password)
+
+	if _, ok := commonPasswords[lower]; ok {
+		return ErrCommon
+	}
+
+	for _, value := range personal {
+		for _, part := range personalParts(value) {
+			if lower == part || (len(part) >= minPersonalLength && strings.Contains(lower, part)) {
+				return ErrPersonal
+			}
+		}
+	}
+
+	return nil
+}
+
+// personalParts returns the value and, for an email, its local part
+func personalParts(value string) []string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return nil
+	}
+
+	parts := []string{value}
+	if local, _, found := strings.Cut(value, "@"); found && local != "" {
+		parts = append(parts, local)
+	}
+
+	return parts
+}
@@ -0,0 +1,27 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	s := newService(t, AlgorithmBcrypt)
+
+	require.NoError(t, s.Validate("correct horse battery", "John", "Doe", "john.doe@example.com"))
+
+	require.Error(t, s.Validate("short", "John"))
+	require.ErrorIs(t, s.Validate(strings.Repeat("a", MaxLength+1)), ErrTooLong)
+
+	require.ErrorIs(t, s.Validate("Password123"), ErrCommon)
+	require.ErrorIs(t, s.Validate("QWERTYUIOP"), ErrCommon)
+
+	require.ErrorIs(t, s.Validate("john.doe@example.com", "john.doe@example.com"), ErrPersonal)
+	require.ErrorIs(t, s.Validate("JOHN.DOE2023", "john.doe@example.com"), ErrPersonal)
+	require.ErrorIs(t, s.Validate("maximilian99", "Maximilian"), ErrPersonal)
+
+	// Short names are only rejected as the whole password
+	require.NoError(t, s.Validate("bold horse battery", "Bo", ""))
+}
@@ -0,0 +1,24 @@
+package sitemap
+
+import "strings"
+
+// Robots writes a robots.txt that keeps every crawler out of the disallowed paths
+// and points them to the sitemap, nothing is disallowed when the list is empty
+func Robots(disallow []string, sitemapURL string) []byte {
+	var b strings.Builder
+
+	b.WriteString("User-agent: *\n")
+
+	if len(disallow) == 0 {
+		b.WriteString("Disallow:\n")
+	}
+	for _, path := range disallow {
+		b.WriteString("Disallow: " + path + "\n")
+	}
+
+	if sitemapURL != "" {
+		b.WriteString("\nSitemap: " + sitemapURL + "\n")
+	}
+
+	return []byte(b.String())
+}
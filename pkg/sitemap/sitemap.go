@@ -0,0 +1,82 @@
+package sitemap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"time"
+)
+
+// MaxURLs is the most URLs the protocol allows in one sitemap, more are split over an index
+const MaxURLs = 50000
+
+const ContentType = "application/xml; charset=utf-8"
+
+const namespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// URL is a page in a sitemap or a sitemap in an index, LastMod is left out when it is zero
+type URL struct {
+	Loc     string
+	LastMod time.Time
+}
+
+type urlSet struct {
+	XMLName xml.Name    `xml:"urlset"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	URLs    []*location `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name    `xml:"sitemapindex"`
+	XMLNS    string      `xml:"xmlns,attr"`
+	Sitemaps []*location `xml:"sitemap"`
+}
+
+type location struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// RenderURLSet writes a sitemap of the pages
+func RenderURLSet(urls []*URL) ([]byte, error) {
+	return render(&urlSet{
+		XMLNS: namespace,
+		URLs:  locations(urls),
+	})
+}
+
+// RenderIndex writes a sitemap index of the sitemaps
+func RenderIndex(sitemaps []*URL) ([]byte, error) {
+	return render(&sitemapIndex{
+		XMLNS:    namespace,
+		Sitemaps: locations(sitemaps),
+	})
+}
+
+func locations(urls []*URL) []*location {
+	result := make([]*location, 0, len(urls))
+
+	for _, u := range urls {
+		l := &location{Loc: u.Loc}
+		if !u.LastMod.IsZero() {
+			l.LastMod = u.LastMod.UTC().Format(time.RFC3339)
+		}
+		result = append(result, l)
+	}
+
+	return result
+}
+
+func render(doc interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+
+	err := encoder.Encode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
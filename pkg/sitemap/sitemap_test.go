@@ -0,0 +1,59 @@
+package sitemap
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderURLSet(t *testing.T) {
+	lastMod := time.Date(2024, 3, 1, 10, 0, 0, 0, time.FixedZone("UZT", 5*60*60))
+
+	data, err := RenderURLSet([]*URL{
+		{Loc: "https://blog.example.com/posts/1?a=1&b=2", LastMod: lastMod},
+		{Loc: "https://blog.example.com/categories/1"},
+	})
+	require.NoError(t, err)
+	require.Contains(t, string(data), `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	require.Contains(t, string(data), "<loc>https://blog.example.com/posts/1?a=1&amp;b=2</loc>")
+
+	var doc struct {
+		URLs []struct {
+			Loc     string `xml:"loc"`
+			LastMod string `xml:"lastmod"`
+		} `xml:"url"`
+	}
+	require.NoError(t, xml.Unmarshal(data, &doc))
+	require.Len(t, doc.URLs, 2)
+	require.Equal(t, "2024-03-01T05:00:00Z", doc.URLs[0].LastMod)
+	require.Empty(t, doc.URLs[1].LastMod)
+	require.NotContains(t, string(data), "<lastmod></lastmod>")
+}
+
+func TestRenderIndex(t *testing.T) {
+	data, err := RenderIndex([]*URL{
+		{Loc: "https://blog.example.com/sitemaps/1.xml"},
+		{Loc: "https://blog.example.com/sitemaps/2.xml"},
+	})
+	require.NoError(t, err)
+	require.Contains(t, string(data), `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+
+	var doc struct {
+		Sitemaps []struct {
+			Loc string `xml:"loc"`
+		} `xml:"sitemap"`
+	}
+	require.NoError(t, xml.Unmarshal(data, &doc))
+	require.Len(t, doc.Sitemaps, 2)
+	require.Equal(t, "https://blog.example.com/sitemaps/2.xml", doc.Sitemaps[1].Loc)
+}
+
+func TestRobots(t *testing.T) {
+	require.Equal(t,
+		"User-agent: *\nDisallow: /v1/\nDisallow: /swagger/\n\nSitemap: https://blog.example.com/sitemap.xml\n",
+		string(Robots([]string{"/v1/", "/swagger/"}, "https://blog.example.com/sitemap.xml")),
+	)
+	require.Equal(t, "User-agent: *\nDisallow:\n", string(Robots(nil, "")))
+}
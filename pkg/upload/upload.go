@@ -0,0 +1,84 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// What the uploaded file is for, each purpose allows its own types
+const (
+	PurposeAvatar     = "avatar"
+	PurposePostImage  = "post_image"
+	PurposeAttachment = "attachment"
+)
+
+// SniffLen is how many leading bytes of the file Validate looks at
+const SniffLen = 512
+
+var (
+	ErrUnknownPurpose    = errors.New("unknown upload purpose")
+	ErrTypeNotAllowed    = errors.New("file type is not allowed")
+	ErrExtensionMismatch = errors.New("file extension does not match its content")
+)
+
+// extensions lists the extensions a file of the type may have
+var extensions = map[string][]string{
+	"image/png":       {".png"},
+	"image/jpeg":      {".jpg", ".jpeg"},
+	"image/gif":       {".gif"},
+	"image/webp":      {".webp"},
+	"application/pdf": {".pdf"},
+	"text/plain":      {".txt"},
+	"application/zip": {".zip"},
+}
+
+var imageTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+var allowedTypes = map[string][]string{
+	PurposeAvatar:     imageTypes,
+	PurposePostImage:  imageTypes,
+	PurposeAttachment: append([]string{"application/pdf", "text/plain", "application/zip"}, imageTypes...),
+}
+
+// Validate sniffs the type of the file from its first bytes, it must be allowed for the purpose
+// and the extension of the file name must match it. The sniffed type is returned
+func Validate(purpose, filename string, head []byte) (string, error) {
+	allowed, ok := allowedTypes[purpose]
+	if !ok {
+		return "", ErrUnknownPurpose
+	}
+
+	if len(head) > SniffLen {
+		head = head[:SniffLen]
+	}
+
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "", err
+	}
+
+	if !contains(allowed, contentType) {
+		return "", fmt.Errorf("%w: %s", ErrTypeNotAllowed, contentType)
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	if !contains(extensions[contentType], ext) {
+		return "", fmt.Errorf("%w: %s is not %s", ErrExtensionMismatch, ext, contentType)
+	}
+
+	return contentType, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,48 @@
+package upload
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	png  = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	pdf  = []byte("%PDF-1.7\n")
+	html = []byte("<!DOCTYPE html><script>alert(1)</script>")
+)
+
+func TestValidate(t *testing.T) {
+	contentType, err := Validate(PurposeAvatar, "me.PNG", png)
+	require.NoError(t, err)
+	require.Equal(t, "image/png", contentType)
+
+	contentType, err = Validate(PurposeAttachment, "cv.pdf", pdf)
+	require.NoError(t, err)
+	require.Equal(t, "application/pdf", contentType)
+
+	contentType, err = Validate(PurposeAttachment, "notes.txt", []byte("hello"))
+	require.NoError(t, err)
+	require.Equal(t, "text/plain", contentType)
+}
+
+func TestValidateRejects(t *testing.T) {
+	_, err := Validate(PurposePostImage, "cv.pdf", pdf)
+	require.ErrorIs(t, err, ErrTypeNotAllowed)
+
+	_, err = Validate(PurposeAttachment, "page.html", html)
+	require.ErrorIs(t, err, ErrTypeNotAllowed)
+
+	// A renamed file is caught by its content
+	_, err = Validate(PurposeAttachment, "page.png", html)
+	require.ErrorIs(t, err, ErrTypeNotAllowed)
+
+	_, err = Validate(PurposeAvatar, "me.html", png)
+	require.ErrorIs(t, err, ErrExtensionMismatch)
+
+	_, err = Validate(PurposeAvatar, "me", png)
+	require.ErrorIs(t, err, ErrExtensionMismatch)
+
+	_, err = Validate("banner", "me.png", png)
+	require.ErrorIs(t, err, ErrUnknownPurpose)
+}
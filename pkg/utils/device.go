@@ -0,0 +1,60 @@
+package utils
+
+import "strings"
+
+type uaToken struct {
+	token string
+	name  string
+}
+
+// Order matters, several browsers mention the engines of others in their User-Agent
+var (
+	uaBrowsers = []uaToken{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"YaBrowser/", "Yandex Browser"},
+		{"SamsungBrowser/", "Samsung Internet"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"CriOS/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"PostmanRuntime/", "Postman"},
+		{"curl/", "curl"},
+		{"Go-http-client/", "Go HTTP client"},
+	}
+	uaSystems = []uaToken{
+		{"Windows", "Windows"},
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+// DeviceName returns a short human readable description of the User-Agent, e.g. "Chrome on Windows"
+func DeviceName(userAgent string) string {
+	browser := matchUAToken(userAgent, uaBrowsers)
+	system := matchUAToken(userAgent, uaSystems)
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return "Unknown browser on " + system
+	default:
+		return "Unknown device"
+	}
+}
+
+func matchUAToken(userAgent string, tokens []uaToken) string {
+	for _, t := range tokens {
+		if strings.Contains(userAgent, t.token) {
+			return t.name
+		}
+	}
+	return ""
+}
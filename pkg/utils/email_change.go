@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// emailChangePurpose separates email change signatures from other uses of the secret
+const emailChangePurpose = "email_change."
+
+// EmailChangeClaims is the signed content of the undo link sent to the old address
+type EmailChangeClaims struct {
+	ID        string    `json:"id"`
+	UserID    int64     `json:"user_id"`
+	OldEmail  string    `json:"old_email"`
+	NewEmail  string    `json:"new_email"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreateEmailChangeToken signs a token that allows the owner of the old address to undo the change
+func CreateEmailChangeToken(secret string, userID int64, oldEmail, newEmail string, duration time.Duration) (string, *EmailChangeClaims, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", nil, err
+	}
+
+	claims := &EmailChangeClaims{
+		ID:        id.String(),
+		UserID:    userID,
+		OldEmail:  oldEmail,
+		NewEmail:  newEmail,
+		ExpiresAt: time.Now().Add(duration),
+	}
+
+	token, err := createSignedToken(secret, emailChangePurpose, claims)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, claims, nil
+}
+
+// VerifyEmailChangeToken checks the signature and expiry of the token, single use is up to the caller
+func VerifyEmailChangeToken(secret, token string) (*EmailChangeClaims, error) {
+	var claims EmailChangeClaims
+
+	err := parseSignedToken(secret, emailChangePurpose, token, &claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(claims.ExpiresAt) {
+		return nil, ErrExpiredToken
+	}
+
+	return &claims, nil
+}
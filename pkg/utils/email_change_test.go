@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailChangeToken(t *testing.T) {
+	token, claims, err := CreateEmailChangeToken("secret", 7, "old@example.com", "new@example.com", time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, claims.ID)
+
+	result, err := VerifyEmailChangeToken("secret", token)
+	require.NoError(t, err)
+	require.Equal(t, claims.ID, result.ID)
+	require.Equal(t, int64(7), result.UserID)
+	require.Equal(t, "old@example.com", result.OldEmail)
+	require.Equal(t, "new@example.com", result.NewEmail)
+
+	_, err = VerifyEmailChangeToken("other", token)
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = VerifyEmailChangeToken("secret", "garbage")
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestEmailChangeTokenPurpose(t *testing.T) {
+	token, _, err := CreateMagicLinkToken("secret", "john@example.com", time.Minute)
+	require.NoError(t, err)
+
+	// A sign-in link must not be usable as an undo link
+	_, err = VerifyEmailChangeToken("secret", token)
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestEmailChangeTokenExpired(t *testing.T) {
+	token, _, err := CreateEmailChangeToken("secret", 7, "old@example.com", "new@example.com", -time.Minute)
+	require.NoError(t, err)
+
+	_, err = VerifyEmailChangeToken("secret", token)
+	require.ErrorIs(t, err, ErrExpiredToken)
+}
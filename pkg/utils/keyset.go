@@ -0,0 +1,250 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var (
+	ErrUnknownKeyID    = errors.New("unknown signing key id")
+	ErrNoSigningKey    = errors.New("active signing key has no private key")
+	ErrUnsupportedKey  = errors.New("unsupported key type")
+	ErrHS256NotAllowed = errors.New("HS256 tokens are not accepted")
+)
+
+// SigningKey is an asymmetric key, Private is nil for keys that only verify
+// tokens issued before a rotation
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// KeySet signs tokens with the active key and verifies them with any known key.
+//
+// Rotation: put the new key into the keys directory and deploy, so every instance
+// can verify it. Then point the active key id at it. Old keys keep verifying tokens
+// until they expire and can be removed once the longest token lifetime has passed.
+type KeySet struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+	// secret signs HS256 tokens when no asymmetric key is configured and verifies
+	// tokens issued before the switch to asymmetric keys
+	secret []byte
+}
+
+// NewHS256KeySet signs and verifies tokens with the shared secret only
+func NewHS256KeySet(secret string) *KeySet {
+	return &KeySet{
+		keys:   map[string]*SigningKey{},
+		secret: []byte(secret),
+	}
+}
+
+// LoadKeySet reads every *.pem file of the directory, the file name without the
+// extension is the key id. Files may hold a private key or, for retired keys, a public key.
+// legacySecret keeps HS256 tokens valid, pass an empty string to reject them.
+func LoadKeySet(dir, activeKeyID, legacySecret string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	ks := &KeySet{
+		keys:   make(map[string]*SigningKey, len(paths)),
+		secret: []byte(legacySecret),
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		id := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+		key, err := ParseSigningKey(id, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		ks.keys[id] = key
+	}
+
+	err = ks.SetActive(activeKeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ks, nil
+}
+
+// ParseSigningKey parses a PEM encoded Ed25519 or RSA key
+func ParseSigningKey(id string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var parsed interface{}
+	var err error
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKey, block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{ID: id}
+
+	switch k := parsed.(type) {
+	case ed25519.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodEdDSA, k
+	case *rsa.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodRS256, k, k.Public()
+	case *rsa.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodRS256, k
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, parsed)
+	}
+
+	return key, nil
+}
+
+// Add registers a key, it does not change the active key
+func (ks *KeySet) Add(key *SigningKey) {
+	ks.keys[key.ID] = key
+}
+
+// SetActive selects the key new tokens are signed with, an empty id falls back to HS256
+func (ks *KeySet) SetActive(id string) error {
+	if id == "" {
+		ks.active = nil
+		return nil
+	}
+
+	key, ok := ks.keys[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownKeyID, id)
+	}
+
+	if key.Private == nil {
+		return fmt.Errorf("%w: %s", ErrNoSigningKey, id)
+	}
+
+	ks.active = key
+	return nil
+}
+
+// Sign signs the claims with the active key and sets the kid header
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if ks.active == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.secret)
+	}
+
+	token := jwt.NewWithClaims(ks.active.Method, claims)
+	token.Header["kid"] = ks.active.ID
+
+	return token.SignedString(ks.active.Private)
+}
+
+// Keyfunc resolves the verification key from the kid header and refuses keys of another algorithm
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if len(ks.secret) == 0 {
+			return nil, ErrHS256NotAllowed
+		}
+		return ks.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKeyID, kid)
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, ErrInvalidToken
+	}
+
+	return key.Public, nil
+}
+
+// JSONWebKey is a public key in the JWKS format
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS returns the public keys, the shared HS256 secret is never published
+func (ks *KeySet) JWKS() *JSONWebKeySet {
+	set := &JSONWebKeySet{
+		Keys: make([]JSONWebKey, 0, len(ks.keys)),
+	}
+
+	for _, key := range ks.keys {
+		jwk := JSONWebKey{
+			Kid: key.ID,
+			Use: "sig",
+			Alg: key.Method.Alg(),
+		}
+
+		switch k := key.Public.(type) {
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(k)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(k.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+
+	return set
+}
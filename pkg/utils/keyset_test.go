@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeKey(t *testing.T, dir, id, blockType string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	err := os.WriteFile(filepath.Join(dir, id+".pem"), data, 0600)
+	require.NoError(t, err)
+}
+
+func newKeysDir(t *testing.T) string {
+	dir := t.TempDir()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+	writeKey(t, dir, "ed-2024", "PRIVATE KEY", der)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	writeKey(t, dir, "rsa-2023", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	_, retired, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err = x509.MarshalPKIXPublicKey(retired.Public())
+	require.NoError(t, err)
+	writeKey(t, dir, "ed-retired", "PUBLIC KEY", der)
+
+	return dir
+}
+
+func createTestToken(t *testing.T, keys *KeySet) string {
+	token, _, err := CreateToken(keys, &TokenParams{
+		UserID:   1,
+		Email:    "john@example.com",
+		UserType: "user",
+		Duration: time.Minute,
+	})
+	require.NoError(t, err)
+
+	return token
+}
+
+func TestKeySetRotation(t *testing.T) {
+	dir := newKeysDir(t)
+
+	keys, err := LoadKeySet(dir, "rsa-2023", "")
+	require.NoError(t, err)
+
+	oldToken := createTestToken(t, keys)
+
+	// Deploy with the new active key, tokens of the previous key stay valid
+	keys, err = LoadKeySet(dir, "ed-2024", "")
+	require.NoError(t, err)
+
+	newToken := createTestToken(t, keys)
+
+	for _, token := range []string{oldToken, newToken} {
+		payload, err := VerifyToken(keys, token)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), payload.UserID)
+	}
+
+	// Removing the old key ends its tokens
+	require.NoError(t, os.Remove(filepath.Join(dir, "rsa-2023.pem")))
+	keys, err = LoadKeySet(dir, "ed-2024", "")
+	require.NoError(t, err)
+
+	_, err = VerifyToken(keys, oldToken)
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = VerifyToken(keys, newToken)
+	require.NoError(t, err)
+}
+
+func TestKeySetActiveKey(t *testing.T) {
+	dir := newKeysDir(t)
+
+	_, err := LoadKeySet(dir, "missing", "")
+	require.ErrorIs(t, err, ErrUnknownKeyID)
+
+	_, err = LoadKeySet(dir, "ed-retired", "")
+	require.ErrorIs(t, err, ErrNoSigningKey)
+}
+
+func TestKeySetLegacyHS256(t *testing.T) {
+	legacy := createTestToken(t, NewHS256KeySet("secret"))
+
+	keys, err := LoadKeySet(newKeysDir(t), "ed-2024", "secret")
+	require.NoError(t, err)
+
+	_, err = VerifyToken(keys, legacy)
+	require.NoError(t, err)
+
+	keys, err = LoadKeySet(newKeysDir(t), "ed-2024", "")
+	require.NoError(t, err)
+
+	_, err = VerifyToken(keys, legacy)
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestKeySetJWKS(t *testing.T) {
+	keys, err := LoadKeySet(newKeysDir(t), "ed-2024", "secret")
+	require.NoError(t, err)
+
+	set := keys.JWKS()
+	require.Len(t, set.Keys, 3)
+
+	require.Equal(t, "ed-2024", set.Keys[0].Kid)
+	require.Equal(t, "OKP", set.Keys[0].Kty)
+	require.Equal(t, "EdDSA", set.Keys[0].Alg)
+	require.NotEmpty(t, set.Keys[0].X)
+
+	require.Equal(t, "rsa-2023", set.Keys[2].Kid)
+	require.Equal(t, "RSA", set.Keys[2].Kty)
+	require.Equal(t, "AQAB", set.Keys[2].E)
+}
+
+func TestImpersonationToken(t *testing.T) {
+	keys := NewHS256KeySet("secret")
+
+	token, _, err := CreateToken(keys, &TokenParams{
+		UserID:         1,
+		Email:          "john@example.com",
+		UserType:       "user",
+		Duration:       time.Minute,
+		ImpersonatorID: 2,
+	})
+	require.NoError(t, err)
+
+	payload, err := VerifyToken(keys, token)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), payload.UserID)
+	require.Equal(t, int64(2), payload.ImpersonatorID)
+	require.True(t, payload.Impersonated())
+
+	payload, err = VerifyToken(keys, createTestToken(t, keys))
+	require.NoError(t, err)
+	require.False(t, payload.Impersonated())
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMagicLinkToken(t *testing.T) {
+	token, claims, err := CreateMagicLinkToken("secret", "john@example.com", time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, claims.ID)
+
+	result, err := VerifyMagicLinkToken("secret", token)
+	require.NoError(t, err)
+	require.Equal(t, claims.ID, result.ID)
+	require.Equal(t, "john@example.com", result.Email)
+
+	_, err = VerifyMagicLinkToken("other", token)
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = VerifyMagicLinkToken("secret", token+"x")
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = VerifyMagicLinkToken("secret", "garbage")
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestMagicLinkTokenExpired(t *testing.T) {
+	token, _, err := CreateMagicLinkToken("secret", "john@example.com", -time.Minute)
+	require.NoError(t, err)
+
+	_, err = VerifyMagicLinkToken("secret", token)
+	require.ErrorIs(t, err, ErrExpiredToken)
+}
@@ -1,18 +0,0 @@
-package utils
-
-import (
-	"testing"
-
-	"github.com/stretchr/testify/require"
-)
-
-func TestPassword(t *testing.T) {
-	password := "123456789"
-
-	hashedPassword, err := HashPassword(password)
-	require.NoError(t, err)
-	require.NotEmpty(t, hashedPassword)
-
-	err = CheckPassword(password, hashedPassword)
-	require.NoError(t, err)
-}
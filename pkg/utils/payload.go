@@ -16,11 +16,18 @@ var (
 // Payload contains the payload data of the token
 type Payload struct {
 	ID        uuid.UUID `json:"id"`
+	SessionID uuid.UUID `json:"session_id"`
 	UserID    int64     `json:"user_id"`
 	Email     string    `json:"email"`
 	UserType  string    `json:"type"`
+	TwoFactor bool      `json:"two_factor"`
+	// Scopes is only set for personal access tokens
+	Scopes    []string  `json:"scopes,omitempty"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
+
+	// ImpersonatorID is only set for tokens a superadmin uses to act as the user
+	ImpersonatorID int64 `json:"impersonator_id,omitempty"`
 }
 
 // NewPayload creates a new token payload
@@ -32,15 +39,24 @@ func NewPayload(params *TokenParams) (*Payload, error) {
 
 	payload := &Payload{
 		ID:        tokenID,
+		SessionID: params.SessionID,
 		UserID:    params.UserID,
 		Email:     params.Email,
 		UserType:  params.UserType,
+		TwoFactor: params.TwoFactor,
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(params.Duration),
+
+		ImpersonatorID: params.ImpersonatorID,
 	}
 	return payload, nil
 }
 
+// Impersonated reports whether a superadmin is acting as the user
+func (payload *Payload) Impersonated() bool {
+	return payload.ImpersonatorID != 0
+}
+
 // Valid checks if the token payload is valid or not
 func (payload *Payload) Valid() error {
 	if time.Now().After(payload.ExpiredAt) {
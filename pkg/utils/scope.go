@@ -0,0 +1,52 @@
+package utils
+
+// Scopes limit what a personal access token can do, session tokens are not scoped
+const (
+	ScopeUsersRead       = "users:read"
+	ScopeUsersWrite      = "users:write"
+	ScopePostsWrite      = "posts:write"
+	ScopeCategoriesWrite = "categories:write"
+	ScopeCommentsRead    = "comments:read"
+	ScopeCommentsWrite   = "comments:write"
+	ScopeLikesRead       = "likes:read"
+	ScopeLikesWrite      = "likes:write"
+	ScopeFilesRead       = "files:read"
+	ScopeFilesWrite      = "files:write"
+)
+
+var Scopes = []string{
+	ScopeUsersRead,
+	ScopeUsersWrite,
+	ScopePostsWrite,
+	ScopeCategoriesWrite,
+	ScopeCommentsRead,
+	ScopeCommentsWrite,
+	ScopeLikesRead,
+	ScopeLikesWrite,
+	ScopeFilesRead,
+	ScopeFilesWrite,
+}
+
+// IsValidScope reports whether the scope is known
+func IsValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the token is allowed to act within the scope
+func (payload *Payload) HasScope(scope string) bool {
+	if payload.Scopes == nil {
+		return true
+	}
+
+	for _, s := range payload.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasScope(t *testing.T) {
+	session := &Payload{}
+	require.True(t, session.HasScope(ScopePostsWrite))
+
+	token := &Payload{Scopes: []string{ScopePostsWrite}}
+	require.True(t, token.HasScope(ScopePostsWrite))
+	require.False(t, token.HasScope(ScopeUsersWrite))
+
+	empty := &Payload{Scopes: []string{}}
+	require.False(t, empty.HasScope(ScopePostsWrite))
+
+	require.True(t, IsValidScope(ScopeLikesRead))
+	require.True(t, IsValidScope(ScopeCommentsRead))
+	require.False(t, IsValidScope("posts:admin"))
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// createSignedToken encodes the claims and signs them with the secret, the purpose
+// keeps a token issued for one flow from being accepted by another
+func createSignedToken(secret, purpose string, claims interface{}) (string, error) {
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+
+	return payload + "." + signToken(secret, purpose, payload), nil
+}
+
+// parseSignedToken checks the signature and decodes the claims, expiry is up to the caller
+func parseSignedToken(secret, purpose, token string, claims interface{}) error {
+	payload, signature, found := strings.Cut(token, ".")
+	if !found {
+		return ErrInvalidToken
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(signToken(secret, purpose, payload))) {
+		return ErrInvalidToken
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	err = json.Unmarshal(data, claims)
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	return nil
+}
+
+func signToken(secret, purpose, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -5,7 +5,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt"
-	"github.com/ibrat-muslim/blog-app/config"
+	"github.com/google/uuid"
 )
 
 type TokenParams struct {
@@ -14,31 +14,27 @@ type TokenParams struct {
 	Email    string
 	UserType string
 	Duration time.Duration
+	// TwoFactor marks tokens issued after the second factor has been checked
+	TwoFactor bool
+	SessionID uuid.UUID
+	// ImpersonatorID is the superadmin acting as the user
+	ImpersonatorID int64
 }
 
-// CreateToken creates a new token
-func CreateToken(cfg *config.Config, params *TokenParams) (string, *Payload, error) {
+// CreateToken creates a new token signed with the active key
+func CreateToken(keys *KeySet, params *TokenParams) (string, *Payload, error) {
 	payload, err := NewPayload(params)
 	if err != nil {
 		return "", payload, err
 	}
 
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
-	token, err := jwtToken.SignedString([]byte(cfg.AuthSecretKey))
+	token, err := keys.Sign(payload)
 	return token, payload, err
 }
 
 // VerifyToken checks if the token is valid or not
-func VerifyToken(cfg *config.Config, token string) (*Payload, error) {
-	keyFunc := func(token *jwt.Token) (interface{}, error) {
-		_, ok := token.Method.(*jwt.SigningMethodHMAC)
-		if !ok {
-			return nil, ErrInvalidToken
-		}
-		return []byte(cfg.AuthSecretKey), nil
-	}
-
-	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyFunc)
+func VerifyToken(keys *KeySet, token string) (*Payload, error) {
+	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keys.Keyfunc)
 	if err != nil {
 		verr, ok := err.(*jwt.ValidationError)
 		if ok && errors.Is(verr.Inner, ErrExpiredToken) {
@@ -0,0 +1,31 @@
+package web
+
+// Pagination links the neighbours of the page, the links are empty at the ends
+type Pagination struct {
+	Page    int32
+	Pages   int32
+	PrevURL string
+	NextURL string
+}
+
+// NewPagination counts the pages of limit items, link returns the URL of a page
+func NewPagination(page, limit, count int32, link func(page int32) string) *Pagination {
+	pages := (count + limit - 1) / limit
+	if pages < 1 {
+		pages = 1
+	}
+
+	p := &Pagination{
+		Page:  page,
+		Pages: pages,
+	}
+
+	if page > 1 {
+		p.PrevURL = link(page - 1)
+	}
+	if page < pages {
+		p.NextURL = link(page + 1)
+	}
+
+	return p
+}
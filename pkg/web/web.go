@@ -0,0 +1,225 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ibrat-muslim/blog-app/templates"
+)
+
+// Templates of the pages, home, category and author pages are lists of posts
+const (
+	PageList   = "list"
+	PageSearch = "search"
+	PagePost   = "post"
+	PageError  = "error"
+)
+
+const ContentType = "text/html; charset=utf-8"
+
+var Pages = []string{PageList, PageSearch, PagePost, PageError}
+
+var ErrUnknownPage = errors.New("unknown page")
+
+// Page is passed to every template, Content is a *PostList, a *PostDetail or an *ErrorDetail
+type Page struct {
+	Site       string
+	Meta       Meta
+	Pagination *Pagination
+	Content    interface{}
+}
+
+// Meta fills the head of the page, every link must be absolute
+type Meta struct {
+	Title       string
+	Description string
+	// URL is the canonical link and Image the preview for Open Graph and Twitter cards
+	URL     string
+	Image   string
+	Type    string
+	NoIndex bool
+	// RSS and Atom are the feeds of the page
+	RSS  string
+	Atom string
+}
+
+type PostList struct {
+	Heading     string
+	Description string
+	// Query is only set on the search page
+	Query string
+	Posts []*PostSummary
+}
+
+type PostSummary struct {
+	URL           string
+	Title         string
+	Excerpt       string
+	ImageURL      string
+	AuthorName    string
+	AuthorURL     string
+	CategoryTitle string
+	CategoryURL   string
+	Published     time.Time
+}
+
+type PostDetail struct {
+	Title         string
+	Body          string
+	ImageURL      string
+	ImageAlt      string
+	AuthorName    string
+	AuthorURL     string
+	CategoryTitle string
+	CategoryURL   string
+	Published     time.Time
+	Updated       *time.Time
+	Views         int32
+	Likes         int64
+	Dislikes      int64
+	Gallery       []*GalleryItem
+	Comments      []*Comment
+}
+
+type GalleryItem struct {
+	URL     string
+	Caption string
+	AltText string
+	Width   *int
+	Height  *int
+}
+
+type Comment struct {
+	AuthorName string
+	Body       string
+	Created    time.Time
+}
+
+type ErrorDetail struct {
+	Status  int
+	Message string
+}
+
+// Renderer parses the templates once, assets are linked with a hash of their content
+// so browsers can cache them until they change
+type Renderer struct {
+	pages  map[string]*template.Template
+	assets map[string]string
+}
+
+func NewRenderer() (*Renderer, error) {
+	r := &Renderer{
+		pages:  make(map[string]*template.Template, len(Pages)),
+		assets: make(map[string]string),
+	}
+
+	err := fs.WalkDir(templates.Web, "web/static", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		data, err := fs.ReadFile(templates.Web, path)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(data)
+		name := strings.TrimPrefix(path, "web/static/")
+		r.assets[name] = "/static/" + name + "?v=" + hex.EncodeToString(sum[:4])
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	funcs := template.FuncMap{
+		"asset":      r.asset,
+		"date":       formatDate,
+		"datetime":   formatDateTime,
+		"paragraphs": Paragraphs,
+	}
+
+	for _, page := range Pages {
+		t, err := template.New(page).Funcs(funcs).ParseFS(templates.Web, "web/layout.html", "web/partials.html", "web/pages/"+page+".html")
+		if err != nil {
+			return nil, err
+		}
+		r.pages[page] = t
+	}
+
+	return r, nil
+}
+
+// Render executes the layout with the content of the page
+func (r *Renderer) Render(w io.Writer, name string, page *Page) error {
+	t, ok := r.pages[name]
+	if !ok {
+		return ErrUnknownPage
+	}
+
+	return t.ExecuteTemplate(w, "layout", page)
+}
+
+func (r *Renderer) asset(name string) string {
+	if link, ok := r.assets[name]; ok {
+		return link
+	}
+	return "/static/" + name
+}
+
+// Static serves the embedded assets
+func Static() http.FileSystem {
+	static, err := fs.Sub(templates.Web, "web/static")
+	if err != nil {
+		panic(err)
+	}
+
+	return http.FS(static)
+}
+
+// Excerpt cuts the text at a word boundary to at most size characters
+func Excerpt(text string, size int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= size {
+		return text
+	}
+
+	runes := []rune(text)[:size]
+	cut := string(runes)
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+
+	return strings.TrimRight(cut, ".,;:!? ") + "…"
+}
+
+// Paragraphs splits the text on blank lines
+func Paragraphs(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	result := make([]string, 0)
+	for _, p := range strings.Split(text, "\n\n") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			result = append(result, p)
+		}
+	}
+
+	return result
+}
+
+func formatDate(t time.Time) string {
+	return t.Format("January 2, 2006")
+}
+
+func formatDateTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,130 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func render(t *testing.T, name string, page *Page) string {
+	r, err := NewRenderer()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, r.Render(&buf, name, page))
+
+	return buf.String()
+}
+
+func TestRenderPost(t *testing.T) {
+	updated := time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)
+	width := 640
+
+	html := render(t, PagePost, &Page{
+		Site: "Blog",
+		Meta: Meta{
+			Title:       "Fish & chips",
+			Description: "A <b>bold</b> claim",
+			URL:         "https://blog.example.com/posts/1",
+			Image:       "https://blog.example.com/media/images/1/original.png",
+			Type:        "article",
+		},
+		Content: &PostDetail{
+			Title:      "Fish & chips",
+			Body:       "First <script>alert(1)</script>\n\nSecond",
+			AuthorName: "John Doe",
+			AuthorURL:  "/authors/1",
+			Published:  time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+			Updated:    &updated,
+			Gallery: []*GalleryItem{
+				{URL: "/media/images/2/original.png", Caption: "A caption", AltText: "A photo", Width: &width},
+			},
+		},
+	})
+
+	require.Contains(t, html, "<title>Fish &amp; chips - Blog</title>")
+	require.Contains(t, html, `<meta property="og:type" content="article">`)
+	require.Contains(t, html, `<meta property="og:image" content="https://blog.example.com/media/images/1/original.png">`)
+	require.Contains(t, html, `<meta name="twitter:card" content="summary_large_image">`)
+	require.Contains(t, html, `<meta property="og:description" content="A &lt;b&gt;bold&lt;/b&gt; claim">`)
+	require.Contains(t, html, "<p>First &lt;script&gt;alert(1)&lt;/script&gt;</p>")
+	require.Contains(t, html, "<p>Second</p>")
+	require.Contains(t, html, "updated <time datetime=\"2024-03-02T10:00:00Z\">March 2, 2024</time>")
+	require.Contains(t, html, `alt="A photo" loading="lazy" width="640">`)
+	require.Contains(t, html, "<figcaption>A caption</figcaption>")
+	require.Contains(t, html, "There are no comments yet.")
+	require.Regexp(t, `href="/static/style.css\?v=[0-9a-f]{8}"`, html)
+}
+
+func TestRenderList(t *testing.T) {
+	link := func(page int32) string {
+		return fmt.Sprintf("https://blog.example.com/?page=%d", page)
+	}
+
+	html := render(t, PageList, &Page{
+		Site:       "Blog",
+		Meta:       Meta{Title: "Blog", Type: "website", RSS: "https://blog.example.com/feeds/posts.rss"},
+		Pagination: NewPagination(2, 10, 25, link),
+		Content: &PostList{
+			Heading: "Latest posts",
+			Posts: []*PostSummary{
+				{URL: "/posts/1", Title: "First", AuthorName: "John Doe", CategoryTitle: "Food"},
+			},
+		},
+	})
+
+	require.Contains(t, html, "<title>Blog</title>")
+	require.Contains(t, html, `<meta name="twitter:card" content="summary">`)
+	require.Contains(t, html, `<link rel="alternate" type="application/rss+xml" title="Blog" href="https://blog.example.com/feeds/posts.rss">`)
+	require.Contains(t, html, `<link rel="prev" href="https://blog.example.com/?page=1">`)
+	require.Contains(t, html, `<a href="/posts/1">First</a>`)
+	require.Contains(t, html, "Page 2 of 3")
+}
+
+func TestRenderSearch(t *testing.T) {
+	html := render(t, PageSearch, &Page{
+		Site:    "Blog",
+		Meta:    Meta{Title: "Search", NoIndex: true},
+		Content: &PostList{Heading: "Search", Query: `"quoted"`},
+	})
+
+	require.Contains(t, html, `<meta name="robots" content="noindex, follow">`)
+	require.Contains(t, html, `value="&#34;quoted&#34;"`)
+	require.Contains(t, html, "There are no posts yet.")
+
+	r, err := NewRenderer()
+	require.NoError(t, err)
+	require.ErrorIs(t, r.Render(io.Discard, "missing", &Page{}), ErrUnknownPage)
+}
+
+func TestNewPagination(t *testing.T) {
+	link := func(page int32) string {
+		return fmt.Sprint(page)
+	}
+
+	p := NewPagination(1, 10, 0, link)
+	require.Equal(t, &Pagination{Page: 1, Pages: 1}, p)
+
+	p = NewPagination(3, 10, 30, link)
+	require.Equal(t, &Pagination{Page: 3, Pages: 3, PrevURL: "2"}, p)
+}
+
+func TestExcerpt(t *testing.T) {
+	require.Equal(t, "Short text", Excerpt(" Short\n text ", 20))
+	require.Equal(t, "The quick brown…", Excerpt("The quick brown, fox jumps", 18))
+	require.Equal(t, "Привет…", Excerpt("Привет мир", 8))
+}
+
+func TestParagraphs(t *testing.T) {
+	require.Equal(t, []string{"One\nline", "Two"}, Paragraphs("One\nline\r\n\r\n\n\nTwo\n"))
+}
+
+func TestStatic(t *testing.T) {
+	f, err := Static().Open("style.css")
+	require.NoError(t, err)
+	defer f.Close()
+}
@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"event":"ping"}`)
+
+	signature := Sign("secret", 1700000000, body)
+	require.Regexp(t, `^sha256=[0-9a-f]{64}$`, signature)
+	require.True(t, Verify("secret", 1700000000, body, signature))
+	require.False(t, Verify("other", 1700000000, body, signature))
+	require.False(t, Verify("secret", 1700000001, body, signature))
+	require.False(t, Verify("secret", 1700000000, []byte(`{"event":"pong"}`), signature))
+}
+
+func TestSend(t *testing.T) {
+	var received *http.Request
+	var receivedBody []byte
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		receivedBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	body, err := NewPayload("post.created", map[string]int{"id": 1})
+	require.NoError(t, err)
+
+	status, err := Send(context.Background(), receiver.Client(), &Request{
+		URL:        receiver.URL,
+		Secret:     "secret",
+		Event:      "post.created",
+		DeliveryID: 42,
+		Body:       body,
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, status)
+
+	require.Equal(t, "post.created", received.Header.Get(EventHeader))
+	require.Equal(t, "42", received.Header.Get(DeliveryHeader))
+	require.Equal(t, "application/json", received.Header.Get("Content-Type"))
+	require.Equal(t, body, receivedBody)
+
+	timestamp, err := strconv.ParseInt(received.Header.Get(TimestampHeader), 10, 64)
+	require.NoError(t, err)
+	require.True(t, Verify("secret", timestamp, receivedBody, received.Header.Get(SignatureHeader)))
+
+	var payload struct {
+		Event string         `json:"event"`
+		Data  map[string]int `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(receivedBody, &payload))
+	require.Equal(t, "post.created", payload.Event)
+	require.Equal(t, 1, payload.Data["id"])
+}
+
+func TestSendUnexpectedStatus(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rebuild failed", http.StatusBadGateway)
+	}))
+	defer receiver.Close()
+
+	status, err := Send(context.Background(), receiver.Client(), &Request{URL: receiver.URL, Body: []byte("{}")})
+	require.ErrorIs(t, err, ErrUnexpectedStatus)
+	require.Contains(t, err.Error(), "rebuild failed")
+	require.Equal(t, http.StatusBadGateway, status)
+
+	receiver.Close()
+
+	status, err = Send(context.Background(), receiver.Client(), &Request{URL: receiver.URL, Body: []byte("{}")})
+	require.Error(t, err)
+	require.Zero(t, status)
+}
@@ -10,6 +10,10 @@ import (
 type InMemoryStorageI interface {
 	Set(key, value string, exp time.Duration) error
 	Get(key string) (string, error)
+	SetNX(key, value string, exp time.Duration) (bool, error)
+	Incr(key string, exp time.Duration) (int64, error)
+	TTL(key string) (time.Duration, error)
+	Del(keys ...string) error
 }
 
 type storageRedis struct {
@@ -36,4 +40,45 @@ func (r *storageRedis) Get(key string) (string, error) {
 		return "", err
 	}
 	return val, nil
-}
\ No newline at end of file
+}
+
+func (r *storageRedis) SetNX(key, value string, exp time.Duration) (bool, error) {
+	ok, err := r.client.SetNX(context.Background(), key, value, exp).Result()
+	if err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// Incr increments the counter and starts its expiration on the first increment
+func (r *storageRedis) Incr(key string, exp time.Duration) (int64, error) {
+	val, err := r.client.Incr(context.Background(), key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if val == 1 {
+		err = r.client.Expire(context.Background(), key, exp).Err()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return val, nil
+}
+
+// TTL returns the remaining time to live, it is negative for missing or persistent keys
+func (r *storageRedis) TTL(key string) (time.Duration, error) {
+	val, err := r.client.TTL(context.Background(), key).Result()
+	if err != nil {
+		return 0, err
+	}
+	return val, nil
+}
+
+func (r *storageRedis) Del(keys ...string) error {
+	err := r.client.Del(context.Background(), keys...).Err()
+	if err != nil {
+		return err
+	}
+	return nil
+}
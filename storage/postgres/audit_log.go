@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type auditLogRepo struct {
+	db *sqlx.DB
+}
+
+func NewAuditLog(db *sqlx.DB) repo.AuditLogStorageI {
+	return &auditLogRepo{
+		db: db,
+	}
+}
+
+const auditLogColumns = `
+	id,
+	actor_id,
+	impersonator_id,
+	action,
+	target_type,
+	target_id,
+	before,
+	after,
+	ip,
+	request_id,
+	created_at
+`
+
+func (ar *auditLogRepo) Create(entry *repo.AuditLog) (*repo.AuditLog, error) {
+	query := `
+		INSERT INTO audit_log (
+			actor_id,
+			impersonator_id,
+			action,
+			target_type,
+			target_id,
+			before,
+			after,
+			ip,
+			request_id
+		) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`
+
+	row := ar.db.QueryRow(
+		query,
+		entry.ActorID,
+		entry.ImpersonatorID,
+		entry.Action,
+		entry.TargetType,
+		entry.TargetID,
+		entry.Before,
+		entry.After,
+		entry.IP,
+		entry.RequestID,
+	)
+
+	err := row.Scan(
+		&entry.ID,
+		&entry.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (ar *auditLogRepo) GetAll(params *repo.GetAuditLogParams) (*repo.GetAuditLogResult, error) {
+	result := repo.GetAuditLogResult{
+		Entries: make([]*repo.AuditLog, 0),
+		Count:   0,
+	}
+
+	offset := (params.Page - 1) * params.Limit
+
+	limit := fmt.Sprintf(" LIMIT %d OFFSET %d ", params.Limit, offset)
+
+	filter := " WHERE true "
+	args := make([]interface{}, 0)
+
+	if params.ActorID != 0 {
+		args = append(args, params.ActorID)
+		filter += fmt.Sprintf(" AND actor_id = $%d ", len(args))
+	}
+
+	if params.ImpersonatorID != 0 {
+		args = append(args, params.ImpersonatorID)
+		filter += fmt.Sprintf(" AND impersonator_id = $%d ", len(args))
+	}
+
+	if params.TargetType != "" {
+		args = append(args, params.TargetType)
+		filter += fmt.Sprintf(" AND target_type = $%d ", len(args))
+	}
+
+	if params.TargetID != "" {
+		args = append(args, params.TargetID)
+		filter += fmt.Sprintf(" AND target_id = $%d ", len(args))
+	}
+
+	if params.From != nil {
+		args = append(args, *params.From)
+		filter += fmt.Sprintf(" AND created_at >= $%d ", len(args))
+	}
+
+	if params.To != nil {
+		args = append(args, *params.To)
+		filter += fmt.Sprintf(" AND created_at < $%d ", len(args))
+	}
+
+	query := `
+		SELECT ` + auditLogColumns + `
+		FROM audit_log
+		` + filter + `
+		ORDER BY created_at DESC, id DESC
+		` + limit
+
+	err := ar.db.Select(&result.Entries, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	queryCount := `SELECT count(1) FROM audit_log ` + filter
+
+	err = ar.db.Get(&result.Count, queryCount, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,79 @@
+package postgres_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	actorID := time.Now().UnixNano()
+	targetType := "category"
+	targetID := uuid.NewString()
+
+	entry, err := strg.AuditLog().Create(&repo.AuditLog{
+		ActorID:    &actorID,
+		Action:     "category.update",
+		TargetType: &targetType,
+		TargetID:   &targetID,
+		Before:     []byte(`{"title":"Old"}`),
+		After:      []byte(`{"title":"New"}`),
+		IP:         "127.0.0.1",
+		RequestID:  uuid.NewString(),
+	})
+	require.NoError(t, err)
+	require.NotZero(t, entry.ID)
+
+	impersonatorID := actorID + 1
+
+	_, err = strg.AuditLog().Create(&repo.AuditLog{
+		ActorID:        &actorID,
+		ImpersonatorID: &impersonatorID,
+		Action:         "GET /v1/posts",
+		IP:             "127.0.0.1",
+		RequestID:      uuid.NewString(),
+	})
+	require.NoError(t, err)
+
+	result, err := strg.AuditLog().GetAll(&repo.GetAuditLogParams{
+		Limit:   10,
+		Page:    1,
+		ActorID: actorID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(2), result.Count)
+
+	result, err = strg.AuditLog().GetAll(&repo.GetAuditLogParams{
+		Limit:          10,
+		Page:           1,
+		ImpersonatorID: impersonatorID,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Entries, 1)
+	require.Equal(t, actorID, *result.Entries[0].ActorID)
+
+	from := entry.CreatedAt.Add(-time.Minute)
+	result, err = strg.AuditLog().GetAll(&repo.GetAuditLogParams{
+		Limit:      10,
+		Page:       1,
+		TargetType: targetType,
+		TargetID:   targetID,
+		From:       &from,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Entries, 1)
+	require.JSONEq(t, `{"title":"Old"}`, string(result.Entries[0].Before))
+	require.JSONEq(t, `{"title":"New"}`, string(result.Entries[0].After))
+
+	result, err = strg.AuditLog().GetAll(&repo.GetAuditLogParams{
+		Limit:    10,
+		Page:     1,
+		TargetID: targetID,
+		To:       &from,
+	})
+	require.NoError(t, err)
+	require.Empty(t, result.Entries)
+}
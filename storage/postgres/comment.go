@@ -67,6 +67,10 @@ func (cmr *commentRepo) GetAll(params *repo.GetCommentsParams) (*repo.GetComment
 		filter += fmt.Sprintf(" AND c.user_id = %d ", params.UserID)
 	}
 
+	if params.HideBanned {
+		filter += " AND c.user_id NOT IN (SELECT id FROM users WHERE banned) "
+	}
+
 	query := `
 		SELECT
 			c.id,
@@ -0,0 +1,175 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type dataExportRepo struct {
+	db *sqlx.DB
+}
+
+func NewDataExport(db *sqlx.DB) repo.DataExportStorageI {
+	return &dataExportRepo{
+		db: db,
+	}
+}
+
+const dataExportColumns = `
+	id,
+	user_id,
+	status,
+	file_path,
+	attempts,
+	last_error,
+	next_attempt_at,
+	created_at,
+	completed_at,
+	expires_at
+`
+
+func (dr *dataExportRepo) Create(export *repo.DataExport) (*repo.DataExport, error) {
+	query := `
+		INSERT INTO data_exports (
+			id,
+			user_id
+		) VALUES($1, $2)
+		RETURNING status, attempts, next_attempt_at, created_at
+	`
+
+	row := dr.db.QueryRow(
+		query,
+		export.ID,
+		export.UserID,
+	)
+
+	err := row.Scan(
+		&export.Status,
+		&export.Attempts,
+		&export.NextAttemptAt,
+		&export.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+func (dr *dataExportRepo) GetLatest(userID int64) (*repo.DataExport, error) {
+	query := `
+		SELECT ` + dataExportColumns + `
+		FROM data_exports
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	var result repo.DataExport
+
+	err := dr.db.Get(&result, query, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (dr *dataExportRepo) ClaimPending(limit int32, lease time.Duration) ([]*repo.DataExport, error) {
+	query := `
+		UPDATE data_exports SET
+			next_attempt_at = $1
+		WHERE id IN (
+			SELECT id FROM data_exports
+			WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY next_attempt_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + dataExportColumns
+
+	result := make([]*repo.DataExport, 0)
+
+	err := dr.db.Select(&result, query, time.Now().Add(lease), limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (dr *dataExportRepo) MarkReady(id, filePath string, expiresAt time.Time) error {
+	query := `
+		UPDATE data_exports SET
+			status = 'ready',
+			attempts = attempts + 1,
+			file_path = $1,
+			last_error = NULL,
+			completed_at = CURRENT_TIMESTAMP,
+			expires_at = $2
+		WHERE id = $3
+	`
+
+	return dr.exec(query, filePath, expiresAt, id)
+}
+
+func (dr *dataExportRepo) MarkFailed(id, errMsg string, nextAttemptAt time.Time, failed bool) error {
+	status := repo.DataExportStatusPending
+	if failed {
+		status = repo.DataExportStatusFailed
+	}
+
+	query := `
+		UPDATE data_exports SET
+			status = $1,
+			attempts = attempts + 1,
+			last_error = $2,
+			next_attempt_at = $3
+		WHERE id = $4
+	`
+
+	return dr.exec(query, status, errMsg, nextAttemptAt, id)
+}
+
+func (dr *dataExportRepo) DeleteExpired() ([]*repo.DataExport, error) {
+	query := `
+		DELETE FROM data_exports
+		WHERE expires_at <= CURRENT_TIMESTAMP
+		RETURNING ` + dataExportColumns
+
+	result := make([]*repo.DataExport, 0)
+
+	err := dr.db.Select(&result, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (dr *dataExportRepo) exec(query string, args ...interface{}) error {
+	result, err := dr.db.Exec(query, args...)
+
+	if err != nil {
+		return err
+	}
+
+	rowsCount, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsCount == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package postgres_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataExportLifecycle(t *testing.T) {
+	user := createUser(t)
+	defer deleteUser(user.ID, t)
+
+	export, err := strg.DataExport().Create(&repo.DataExport{
+		ID:     uuid.NewString(),
+		UserID: user.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, repo.DataExportStatusPending, export.Status)
+
+	claimed, err := strg.DataExport().ClaimPending(100, time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, claimed)
+
+	err = strg.DataExport().MarkReady(export.ID, "/tmp/export.zip", time.Now().Add(-time.Second))
+	require.NoError(t, err)
+
+	latest, err := strg.DataExport().GetLatest(user.ID)
+	require.NoError(t, err)
+	require.Equal(t, repo.DataExportStatusReady, latest.Status)
+	require.Equal(t, "/tmp/export.zip", *latest.FilePath)
+
+	expired, err := strg.DataExport().DeleteExpired()
+	require.NoError(t, err)
+	require.NotEmpty(t, expired)
+}
@@ -0,0 +1,229 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type emailOutboxRepo struct {
+	db *sqlx.DB
+}
+
+func NewEmailOutbox(db *sqlx.DB) repo.EmailOutboxStorageI {
+	return &emailOutboxRepo{
+		db: db,
+	}
+}
+
+const emailOutboxColumns = `
+	id,
+	recipient,
+	subject,
+	html_body,
+	text_body,
+	status,
+	attempts,
+	last_error,
+	next_attempt_at,
+	created_at,
+	sent_at,
+	expires_at
+`
+
+func (er *emailOutboxRepo) Create(email *repo.EmailOutbox) (*repo.EmailOutbox, error) {
+	query := `
+		INSERT INTO email_outbox (
+			recipient,
+			subject,
+			html_body,
+			text_body,
+			expires_at
+		) VALUES($1, $2, $3, $4, $5)
+		RETURNING id, status, attempts, next_attempt_at, created_at
+	`
+
+	row := er.db.QueryRow(
+		query,
+		email.Recipient,
+		email.Subject,
+		email.HTMLBody,
+		email.TextBody,
+		email.ExpiresAt,
+	)
+
+	err := row.Scan(
+		&email.ID,
+		&email.Status,
+		&email.Attempts,
+		&email.NextAttemptAt,
+		&email.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return email, nil
+}
+
+func (er *emailOutboxRepo) Get(id int64) (*repo.EmailOutbox, error) {
+	query := `SELECT ` + emailOutboxColumns + ` FROM email_outbox WHERE id = $1`
+
+	var result repo.EmailOutbox
+
+	err := er.db.Get(&result, query, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (er *emailOutboxRepo) GetAll(params *repo.GetEmailOutboxParams) (*repo.GetEmailOutboxResult, error) {
+	result := repo.GetEmailOutboxResult{
+		Emails: make([]*repo.EmailOutbox, 0),
+		Count:  0,
+	}
+
+	offset := (params.Page - 1) * params.Limit
+
+	limit := fmt.Sprintf(" LIMIT %d OFFSET %d ", params.Limit, offset)
+
+	filter := " WHERE true "
+	args := make([]interface{}, 0)
+
+	if params.Status != "" {
+		args = append(args, params.Status)
+		filter += fmt.Sprintf(" AND status = $%d ", len(args))
+	}
+
+	query := `
+		SELECT ` + emailOutboxColumns + `
+		FROM email_outbox
+		` + filter + `
+		ORDER BY created_at DESC
+		` + limit
+
+	err := er.db.Select(&result.Emails, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	queryCount := `SELECT count(1) FROM email_outbox ` + filter
+
+	err = er.db.Get(&result.Count, queryCount, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (er *emailOutboxRepo) ClaimPending(limit int32, lease time.Duration) ([]*repo.EmailOutbox, error) {
+	query := `
+		UPDATE email_outbox SET
+			next_attempt_at = $1
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY next_attempt_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + emailOutboxColumns
+
+	result := make([]*repo.EmailOutbox, 0)
+
+	err := er.db.Select(&result, query, time.Now().Add(lease), limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (er *emailOutboxRepo) MarkSent(id int64) error {
+	query := `
+		UPDATE email_outbox SET
+			status = 'sent',
+			attempts = attempts + 1,
+			sent_at = CURRENT_TIMESTAMP,
+			last_error = NULL,
+			html_body = '',
+			text_body = ''
+		WHERE id = $1
+	`
+
+	return er.exec(query, id)
+}
+
+func (er *emailOutboxRepo) MarkFailed(id int64, errMsg string, nextAttemptAt time.Time, dead bool) error {
+	status := repo.EmailStatusPending
+	if dead {
+		status = repo.EmailStatusDead
+	}
+
+	query := `
+		UPDATE email_outbox SET
+			status = $1,
+			attempts = attempts + 1,
+			last_error = $2,
+			next_attempt_at = $3
+		WHERE id = $4
+	`
+
+	return er.exec(query, status, errMsg, nextAttemptAt, id)
+}
+
+func (er *emailOutboxRepo) MarkExpired(id int64) error {
+	query := `
+		UPDATE email_outbox SET
+			status = 'dead',
+			last_error = 'expired before it was sent',
+			html_body = '',
+			text_body = ''
+		WHERE id = $1
+	`
+
+	return er.exec(query, id)
+}
+
+func (er *emailOutboxRepo) Resend(id int64) error {
+	query := `
+		UPDATE email_outbox SET
+			status = 'pending',
+			attempts = 0,
+			next_attempt_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status <> 'sent' AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+	`
+
+	return er.exec(query, id)
+}
+
+func (er *emailOutboxRepo) exec(query string, args ...interface{}) error {
+	result, err := er.db.Exec(query, args...)
+
+	if err != nil {
+		return err
+	}
+
+	rowsCount, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsCount == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package postgres_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/bxcodec/faker/v4"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func createEmailOutbox(t *testing.T) *repo.EmailOutbox {
+	email, err := strg.EmailOutbox().Create(&repo.EmailOutbox{
+		Recipient: faker.Email(),
+		Subject:   faker.Sentence(),
+		HTMLBody:  faker.Paragraph(),
+		TextBody:  faker.Paragraph(),
+	})
+
+	require.NoError(t, err)
+	require.NotEmpty(t, email)
+	require.Equal(t, repo.EmailStatusPending, email.Status)
+
+	return email
+}
+
+func TestCreateEmailOutbox(t *testing.T) {
+	createEmailOutbox(t)
+}
+
+func TestGetAllEmailOutbox(t *testing.T) {
+	createEmailOutbox(t)
+
+	emails, err := strg.EmailOutbox().GetAll(&repo.GetEmailOutboxParams{
+		Limit:  10,
+		Page:   1,
+		Status: repo.EmailStatusPending,
+	})
+
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(emails.Emails), 1)
+	require.GreaterOrEqual(t, int(emails.Count), 1)
+}
+
+func TestEmailOutboxLifecycle(t *testing.T) {
+	e := createEmailOutbox(t)
+
+	err := strg.EmailOutbox().MarkFailed(e.ID, "connection refused", time.Now(), true)
+	require.NoError(t, err)
+
+	email, err := strg.EmailOutbox().Get(e.ID)
+	require.NoError(t, err)
+	require.Equal(t, repo.EmailStatusDead, email.Status)
+	require.EqualValues(t, 1, email.Attempts)
+
+	err = strg.EmailOutbox().Resend(e.ID)
+	require.NoError(t, err)
+
+	err = strg.EmailOutbox().MarkSent(e.ID)
+	require.NoError(t, err)
+
+	email, err = strg.EmailOutbox().Get(e.ID)
+	require.NoError(t, err)
+	require.Equal(t, repo.EmailStatusSent, email.Status)
+	require.NotNil(t, email.SentAt)
+	require.Empty(t, email.HTMLBody)
+	require.Empty(t, email.TextBody)
+
+	// The bodies of sent emails are gone
+	err = strg.EmailOutbox().Resend(e.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestEmailOutboxExpired(t *testing.T) {
+	expiresAt := time.Now().Add(-time.Minute)
+
+	e, err := strg.EmailOutbox().Create(&repo.EmailOutbox{
+		Recipient: faker.Email(),
+		Subject:   faker.Sentence(),
+		HTMLBody:  faker.Paragraph(),
+		TextBody:  faker.Paragraph(),
+		ExpiresAt: &expiresAt,
+	})
+	require.NoError(t, err)
+
+	err = strg.EmailOutbox().MarkExpired(e.ID)
+	require.NoError(t, err)
+
+	email, err := strg.EmailOutbox().Get(e.ID)
+	require.NoError(t, err)
+	require.Equal(t, repo.EmailStatusDead, email.Status)
+	require.Empty(t, email.TextBody)
+
+	err = strg.EmailOutbox().Resend(e.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -107,3 +107,26 @@ func (l *likeRepo) GetLikesDislikesCount(postID int64) (*repo.LikesDislikesCount
 
 	return &result, nil
 }
+
+func (l *likeRepo) GetAllByUser(userID int64) ([]*repo.Like, error) {
+	query := `
+		SELECT
+			id,
+			post_id,
+			user_id,
+			status
+		FROM likes
+		WHERE user_id = $1
+		ORDER BY id
+	`
+
+	result := make([]*repo.Like, 0)
+
+	err := l.db.Select(&result, query, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
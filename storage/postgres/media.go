@@ -0,0 +1,281 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type mediaRepo struct {
+	db *sqlx.DB
+}
+
+func NewMedia(db *sqlx.DB) repo.MediaStorageI {
+	return &mediaRepo{
+		db: db,
+	}
+}
+
+const mediaColumns = `
+	id,
+	user_id,
+	path,
+	purpose,
+	mime_type,
+	size,
+	variants_size,
+	sha256,
+	width,
+	height,
+	created_at,
+	last_uploaded_at
+`
+
+// mediaLinked matches files that posts, galleries or profiles link,
+// posts and profiles link "/media/" followed by the path
+const mediaLinked = `(
+	EXISTS (SELECT 1 FROM post_media WHERE post_media.media_id = media.id) OR
+	EXISTS (SELECT 1 FROM posts WHERE posts.image_url = '/media/' || media.path) OR
+	EXISTS (SELECT 1 FROM users WHERE users.profile_image_url = '/media/' || media.path)
+)`
+
+const mediaUsage = `SELECT COALESCE(SUM(size + variants_size), 0) FROM media WHERE user_id = $1`
+
+func (mr *mediaRepo) Create(media *repo.Media, quota int64) (*repo.Media, error) {
+	tx, err := mr.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if quota > 0 {
+		// The user's row is locked so concurrent uploads can not all fit in the same free space
+		_, err = tx.Exec(`SELECT id FROM users WHERE id = $1 FOR UPDATE`, media.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		var usage int64
+
+		err = tx.QueryRow(mediaUsage, media.UserID).Scan(&usage)
+		if err != nil {
+			return nil, err
+		}
+
+		if usage+media.Size+media.VariantsSize > quota {
+			return nil, repo.ErrQuotaExceeded
+		}
+	}
+
+	query := `
+		INSERT INTO media (
+			user_id,
+			path,
+			purpose,
+			mime_type,
+			size,
+			variants_size,
+			sha256,
+			width,
+			height
+		) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT DO NOTHING
+		RETURNING id, created_at, last_uploaded_at
+	`
+
+	row := tx.QueryRow(
+		query,
+		media.UserID,
+		media.Path,
+		media.Purpose,
+		media.MimeType,
+		media.Size,
+		media.VariantsSize,
+		media.SHA256,
+		media.Width,
+		media.Height,
+	)
+
+	err = row.Scan(
+		&media.ID,
+		&media.CreatedAt,
+		&media.LastUploadedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return media, nil
+}
+
+func (mr *mediaRepo) Reuse(userID int64, sha256, purpose string) (*repo.Media, error) {
+	query := `
+		UPDATE media SET
+			last_uploaded_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND sha256 = $2 AND purpose = $3
+		RETURNING ` + mediaColumns
+
+	var result repo.Media
+
+	err := mr.db.Get(&result, query, userID, sha256, purpose)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (mr *mediaRepo) Get(id, userID int64) (*repo.Media, error) {
+	query := `
+		SELECT ` + mediaColumns + `
+		FROM media
+		WHERE id = $1 AND user_id = $2
+	`
+
+	var result repo.Media
+
+	err := mr.db.Get(&result, query, id, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (mr *mediaRepo) GetByIDs(ids []int64) ([]*repo.Media, error) {
+	query := `
+		SELECT ` + mediaColumns + `
+		FROM media
+		WHERE id = ANY($1)
+	`
+
+	result := make([]*repo.Media, 0)
+
+	err := mr.db.Select(&result, query, pq.Array(ids))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (mr *mediaRepo) GetAll(params *repo.GetMediaParams) (*repo.GetMediaResult, error) {
+	result := repo.GetMediaResult{
+		Media: make([]*repo.Media, 0),
+		Count: 0,
+	}
+
+	offset := (params.Page - 1) * params.Limit
+
+	limit := fmt.Sprintf(" LIMIT %d OFFSET %d ", params.Limit, offset)
+
+	filter := " WHERE true "
+	args := make([]interface{}, 0)
+
+	if params.UserID != 0 {
+		args = append(args, params.UserID)
+		filter += fmt.Sprintf(" AND user_id = $%d ", len(args))
+	}
+
+	query := `
+		SELECT ` + mediaColumns + `
+		FROM media
+		` + filter + `
+		ORDER BY created_at DESC, id DESC
+		` + limit
+
+	err := mr.db.Select(&result.Media, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	queryCount := `SELECT count(1) FROM media ` + filter
+
+	err = mr.db.Get(&result.Count, queryCount, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (mr *mediaRepo) Usage(userID int64) (int64, error) {
+	var usage int64
+
+	err := mr.db.Get(&usage, mediaUsage, userID)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return usage, nil
+}
+
+func (mr *mediaRepo) IsLinked(path string) (bool, error) {
+	query := `SELECT ` + mediaLinked + ` FROM media WHERE path = $1`
+
+	var linked bool
+
+	err := mr.db.Get(&linked, query, path)
+
+	if err != nil {
+		return false, err
+	}
+
+	return linked, nil
+}
+
+func (mr *mediaRepo) GetOrphans(uploadedBefore time.Time, limit int32) ([]*repo.Media, error) {
+	query := `
+		SELECT ` + mediaColumns + `
+		FROM media
+		WHERE last_uploaded_at < $1 AND NOT ` + mediaLinked + `
+		ORDER BY last_uploaded_at
+		LIMIT $2
+	`
+
+	result := make([]*repo.Media, 0)
+
+	err := mr.db.Select(&result, query, uploadedBefore, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (mr *mediaRepo) Delete(id int64) error {
+	result, err := mr.db.Exec(`DELETE FROM media WHERE id = $1`, id)
+
+	if err != nil {
+		return err
+	}
+
+	rowsCount, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsCount == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package postgres_test
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func createMedia(t *testing.T, userID int64) *repo.Media {
+	width, height := 640, 480
+
+	media, err := strg.Media().Create(&repo.Media{
+		UserID:   &userID,
+		Path:     "images/" + uuid.NewString() + "/original.png",
+		Purpose:  "post_image",
+		MimeType: "image/png",
+		Size:     1024,
+		SHA256:   fmt.Sprintf("%x", sha256.Sum256([]byte(uuid.NewString()))),
+		Width:    &width,
+		Height:   &height,
+	}, 0)
+	require.NoError(t, err)
+	require.NotZero(t, media.ID)
+
+	return media
+}
+
+func TestMediaLifecycle(t *testing.T) {
+	user := createUser(t)
+	defer deleteUser(user.ID, t)
+
+	media := createMedia(t, user.ID)
+	hash := media.SHA256
+
+	// The same content for the same purpose is stored once
+	_, err := strg.Media().Create(&repo.Media{
+		UserID:   &user.ID,
+		Path:     "images/" + uuid.NewString() + "/original.png",
+		Purpose:  media.Purpose,
+		MimeType: media.MimeType,
+		Size:     media.Size,
+		SHA256:   hash,
+	}, 0)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	reused, err := strg.Media().Reuse(user.ID, hash, media.Purpose)
+	require.NoError(t, err)
+	require.Equal(t, media.ID, reused.ID)
+	require.False(t, reused.LastUploadedAt.Before(media.LastUploadedAt))
+
+	_, err = strg.Media().Get(media.ID, user.ID+1)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	result, err := strg.Media().GetAll(&repo.GetMediaParams{Limit: 10, Page: 1, UserID: user.ID})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), result.Count)
+	require.Equal(t, media.Path, result.Media[0].Path)
+
+	usage, err := strg.Media().Usage(user.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1024), usage)
+
+	linked, err := strg.Media().IsLinked(media.Path)
+	require.NoError(t, err)
+	require.False(t, linked)
+
+	orphans, err := strg.Media().GetOrphans(time.Now().Add(time.Minute), 1000)
+	require.NoError(t, err)
+	require.Contains(t, mediaIDs(orphans), media.ID)
+
+	// A linked file is not an orphan
+	imageURL := "/media/" + media.Path
+	user.ProfileImageUrl = &imageURL
+	require.NoError(t, strg.User().Update(user))
+
+	linked, err = strg.Media().IsLinked(media.Path)
+	require.NoError(t, err)
+	require.True(t, linked)
+
+	orphans, err = strg.Media().GetOrphans(time.Now().Add(time.Minute), 1000)
+	require.NoError(t, err)
+	require.NotContains(t, mediaIDs(orphans), media.ID)
+
+	require.NoError(t, strg.Media().Delete(media.ID))
+	require.ErrorIs(t, strg.Media().Delete(media.ID), sql.ErrNoRows)
+}
+
+func TestMediaQuota(t *testing.T) {
+	user := createUser(t)
+	defer deleteUser(user.ID, t)
+
+	media := createMedia(t, user.ID)
+	defer strg.Media().Delete(media.ID)
+
+	newMedia := func(size, variantsSize int64) *repo.Media {
+		return &repo.Media{
+			UserID:       &user.ID,
+			Path:         "images/" + uuid.NewString() + "/original.png",
+			Purpose:      "post_image",
+			MimeType:     "image/png",
+			Size:         size,
+			VariantsSize: variantsSize,
+			SHA256:       fmt.Sprintf("%x", sha256.Sum256([]byte(uuid.NewString()))),
+		}
+	}
+
+	// The variants count towards the quota
+	_, err := strg.Media().Create(newMedia(512, 600), 2048)
+	require.ErrorIs(t, err, repo.ErrQuotaExceeded)
+
+	created, err := strg.Media().Create(newMedia(512, 512), 2048)
+	require.NoError(t, err)
+	defer strg.Media().Delete(created.ID)
+
+	usage, err := strg.Media().Usage(user.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(2048), usage)
+
+	_, err = strg.Media().Create(newMedia(1, 0), 2048)
+	require.ErrorIs(t, err, repo.ErrQuotaExceeded)
+}
+
+func mediaIDs(media []*repo.Media) []int64 {
+	ids := make([]int64, 0, len(media))
+	for _, m := range media {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type moderationActionRepo struct {
+	db *sqlx.DB
+}
+
+func NewModerationAction(db *sqlx.DB) repo.ModerationActionStorageI {
+	return &moderationActionRepo{
+		db: db,
+	}
+}
+
+const moderationActionColumns = `
+	id,
+	user_id,
+	moderator_id,
+	action,
+	reason,
+	suspended_until,
+	created_at
+`
+
+func (mr *moderationActionRepo) Create(action *repo.ModerationAction) (*repo.ModerationAction, error) {
+	query := `
+		INSERT INTO moderation_actions (
+			user_id,
+			moderator_id,
+			action,
+			reason,
+			suspended_until
+		) VALUES($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	row := mr.db.QueryRow(
+		query,
+		action.UserID,
+		action.ModeratorID,
+		action.Action,
+		action.Reason,
+		action.SuspendedUntil,
+	)
+
+	err := row.Scan(
+		&action.ID,
+		&action.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return action, nil
+}
+
+func (mr *moderationActionRepo) GetAll(params *repo.GetModerationActionsParams) (*repo.GetModerationActionsResult, error) {
+	result := repo.GetModerationActionsResult{
+		Actions: make([]*repo.ModerationAction, 0),
+		Count:   0,
+	}
+
+	offset := (params.Page - 1) * params.Limit
+
+	limit := fmt.Sprintf(" LIMIT %d OFFSET %d ", params.Limit, offset)
+
+	filter := " WHERE true "
+	args := make([]interface{}, 0)
+
+	if params.UserID != 0 {
+		args = append(args, params.UserID)
+		filter += fmt.Sprintf(" AND user_id = $%d ", len(args))
+	}
+
+	query := `
+		SELECT ` + moderationActionColumns + `
+		FROM moderation_actions
+		` + filter + `
+		ORDER BY created_at DESC, id DESC
+		` + limit
+
+	err := mr.db.Select(&result.Actions, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	queryCount := `SELECT count(1) FROM moderation_actions ` + filter
+
+	err = mr.db.Get(&result.Count, queryCount, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
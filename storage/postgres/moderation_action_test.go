@@ -0,0 +1,51 @@
+package postgres_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModerationActions(t *testing.T) {
+	user := createUser(t)
+	defer deleteUser(user.ID, t)
+
+	moderator := createUser(t)
+
+	reason := "harassment"
+	until := time.Now().Add(24 * time.Hour)
+
+	suspend, err := strg.ModerationAction().Create(&repo.ModerationAction{
+		UserID:         user.ID,
+		ModeratorID:    &moderator.ID,
+		Action:         repo.ModerationActionSuspend,
+		Reason:         &reason,
+		SuspendedUntil: &until,
+	})
+	require.NoError(t, err)
+	require.NotZero(t, suspend.ID)
+
+	_, err = strg.ModerationAction().Create(&repo.ModerationAction{
+		UserID:      user.ID,
+		ModeratorID: &moderator.ID,
+		Action:      repo.ModerationActionUnsuspend,
+	})
+	require.NoError(t, err)
+
+	// The history outlives the moderator
+	deleteUser(moderator.ID, t)
+
+	result, err := strg.ModerationAction().GetAll(&repo.GetModerationActionsParams{
+		Limit:  10,
+		Page:   1,
+		UserID: user.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(2), result.Count)
+	require.Equal(t, repo.ModerationActionUnsuspend, result.Actions[0].Action)
+	require.Equal(t, repo.ModerationActionSuspend, result.Actions[1].Action)
+	require.Nil(t, result.Actions[1].ModeratorID)
+	require.Equal(t, reason, *result.Actions[1].Reason)
+}
@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type personalAccessTokenRepo struct {
+	db *sqlx.DB
+}
+
+func NewPersonalAccessToken(db *sqlx.DB) repo.PersonalAccessTokenStorageI {
+	return &personalAccessTokenRepo{
+		db: db,
+	}
+}
+
+const personalAccessTokenColumns = `
+	id,
+	user_id,
+	name,
+	token_hash,
+	token_prefix,
+	scopes,
+	expires_at,
+	last_used_at,
+	last_used_ip,
+	revoked_at,
+	created_at
+`
+
+func (pr *personalAccessTokenRepo) Create(token *repo.PersonalAccessToken) (*repo.PersonalAccessToken, error) {
+	query := `
+		INSERT INTO personal_access_tokens (
+			user_id,
+			name,
+			token_hash,
+			token_prefix,
+			scopes,
+			expires_at
+		) VALUES($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	row := pr.db.QueryRow(
+		query,
+		token.UserID,
+		token.Name,
+		token.TokenHash,
+		token.TokenPrefix,
+		token.Scopes,
+		token.ExpiresAt,
+	)
+
+	err := row.Scan(
+		&token.ID,
+		&token.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (pr *personalAccessTokenRepo) GetByHash(tokenHash string) (*repo.PersonalAccessToken, error) {
+	query := `SELECT ` + personalAccessTokenColumns + ` FROM personal_access_tokens
+		WHERE token_hash = $1 AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)`
+
+	var result repo.PersonalAccessToken
+
+	err := pr.db.Get(&result, query, tokenHash)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (pr *personalAccessTokenRepo) GetAll(userID int64) ([]*repo.PersonalAccessToken, error) {
+	query := `SELECT ` + personalAccessTokenColumns + ` FROM personal_access_tokens
+		WHERE user_id = $1 ORDER BY created_at DESC`
+
+	result := make([]*repo.PersonalAccessToken, 0)
+
+	err := pr.db.Select(&result, query, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (pr *personalAccessTokenRepo) Revoke(id, userID int64) error {
+	query := `
+		UPDATE personal_access_tokens SET
+			revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+
+	result, err := pr.db.Exec(query, id, userID)
+
+	if err != nil {
+		return err
+	}
+
+	rowsCount, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsCount == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (pr *personalAccessTokenRepo) Touch(id int64, ip string) error {
+	query := `
+		UPDATE personal_access_tokens SET
+			last_used_at = CURRENT_TIMESTAMP,
+			last_used_ip = $2
+		WHERE id = $1
+	`
+
+	_, err := pr.db.Exec(query, id, ip)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
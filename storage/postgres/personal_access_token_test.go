@@ -0,0 +1,44 @@
+package postgres_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/bxcodec/faker/v4"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersonalAccessTokenLifecycle(t *testing.T) {
+	user := createUser(t)
+	defer deleteUser(user.ID, t)
+
+	token, err := strg.PersonalAccessToken().Create(&repo.PersonalAccessToken{
+		UserID:      user.ID,
+		Name:        faker.Word(),
+		TokenHash:   faker.UUIDDigit(),
+		TokenPrefix: "bpat_" + faker.Word(),
+		Scopes:      []string{"posts:write"},
+	})
+	require.NoError(t, err)
+	require.NotZero(t, token.ID)
+
+	found, err := strg.PersonalAccessToken().GetByHash(token.TokenHash)
+	require.NoError(t, err)
+	require.Equal(t, token.ID, found.ID)
+	require.Equal(t, []string{"posts:write"}, []string(found.Scopes))
+
+	err = strg.PersonalAccessToken().Touch(token.ID, "127.0.0.1")
+	require.NoError(t, err)
+
+	tokens, err := strg.PersonalAccessToken().GetAll(user.ID)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	require.NotNil(t, tokens[0].LastUsedAt)
+
+	err = strg.PersonalAccessToken().Revoke(token.ID, user.ID)
+	require.NoError(t, err)
+
+	_, err = strg.PersonalAccessToken().GetByHash(token.TokenHash)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/ibrat-muslim/blog-app/storage/repo"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type postRepo struct {
@@ -19,27 +20,35 @@ func NewPost(db *sqlx.DB) repo.PostStorageI {
 }
 
 func (pr *postRepo) Create(post *repo.Post) (*repo.Post, error) {
+	tx, err := pr.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	query := `
 		INSERT INTO posts (
 			title,
 			description,
 			image_url,
 			user_id,
-			category_id
-		) VALUES($1, $2, $3, $4, $5)
+			category_id,
+			cover_media_id
+		) VALUES($1, $2, $3, $4, $5, $6)
 		RETURNING id, created_at
 	`
 
-	row := pr.db.QueryRow(
+	row := tx.QueryRow(
 		query,
 		post.Title,
 		post.Description,
 		post.ImageUrl,
 		post.UserID,
 		post.CategoryID,
+		post.CoverMediaID,
 	)
 
-	err := row.Scan(
+	err = row.Scan(
 		&post.ID,
 		&post.CreatedAt,
 	)
@@ -48,9 +57,51 @@ func (pr *postRepo) Create(post *repo.Post) (*repo.Post, error) {
 		return nil, err
 	}
 
+	err = insertPostMedia(tx, post)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
 	return post, nil
 }
 
+func insertPostMedia(tx *sql.Tx, post *repo.Post) error {
+	query := `
+		INSERT INTO post_media (
+			post_id,
+			media_id,
+			position,
+			caption,
+			alt_text
+		) VALUES($1, $2, $3, $4, $5)
+	`
+
+	for i, media := range post.Media {
+		media.PostID = post.ID
+		media.Position = int32(i)
+
+		_, err := tx.Exec(
+			query,
+			media.PostID,
+			media.MediaID,
+			media.Position,
+			media.Caption,
+			media.AltText,
+		)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (pr *postRepo) Get(id int64) (*repo.Post, error) {
 	queryView := `UPDATE posts SET views_count = views_count + 1 WHERE id = $1`
 	
@@ -69,7 +120,8 @@ func (pr *postRepo) Get(id int64) (*repo.Post, error) {
 			category_id,
 			created_at,
 			updated_at,
-			views_count
+			views_count,
+			cover_media_id
 		FROM posts
 		WHERE id = $1
 	`
@@ -110,6 +162,10 @@ func (pr *postRepo) GetAll(params *repo.GetPostsParams) (*repo.GetPostsResult, e
 		filter += fmt.Sprintf(" AND category_id = %d ", params.CategoryID,)	
 	}
 
+	if params.HideBanned {
+		filter += " AND user_id NOT IN (SELECT id FROM users WHERE banned) "
+	}
+
 	orderBy := " ORDER BY created_at DESC "
 
 	if params.SortByDate != "" {
@@ -126,7 +182,8 @@ func (pr *postRepo) GetAll(params *repo.GetPostsParams) (*repo.GetPostsResult, e
 			category_id,
 			created_at,
 			updated_at,
-			views_count
+			views_count,
+			cover_media_id
 		FROM posts
 		` + filter + orderBy + limit
 
@@ -147,24 +204,74 @@ func (pr *postRepo) GetAll(params *repo.GetPostsParams) (*repo.GetPostsResult, e
 	return &result, nil
 }
 
+func (pr *postRepo) GetMedia(postIDs []int64) ([]*repo.PostMedia, error) {
+	query := `
+		SELECT
+			pm.post_id,
+			pm.media_id,
+			pm.position,
+			pm.caption,
+			pm.alt_text,
+			m.path,
+			m.purpose,
+			m.mime_type,
+			m.width,
+			m.height
+		FROM post_media pm
+		INNER JOIN media m ON m.id = pm.media_id
+		WHERE pm.post_id = ANY($1)
+		ORDER BY pm.post_id, pm.position
+	`
+
+	result := make([]*repo.PostMedia, 0)
+
+	err := pr.db.Select(&result, query, pq.Array(postIDs))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (pr *postRepo) GetAuthorID(id int64) (int64, error) {
+	var userID int64
+
+	err := pr.db.Get(&userID, `SELECT user_id FROM posts WHERE id = $1`, id)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
 func (pr *postRepo) Update(post *repo.Post) error {
+	tx, err := pr.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE posts SET
 			title = $1,
 			description = $2,
 			image_url = $3,
 			category_id = $4,
-			updated_at = $5
-		WHERE id = $6
+			updated_at = $5,
+			cover_media_id = $6
+		WHERE id = $7
 	`
 
-	result, err := pr.db.Exec(
+	result, err := tx.Exec(
 		query,
 		post.Title,
 		post.Description,
 		post.ImageUrl,
 		post.CategoryID,
 		post.UpdatedAt,
+		post.CoverMediaID,
 		post.ID,
 	)
 
@@ -182,7 +289,17 @@ func (pr *postRepo) Update(post *repo.Post) error {
 		return sql.ErrNoRows
 	}	
 
-	return nil
+	_, err = tx.Exec(`DELETE FROM post_media WHERE post_id = $1`, post.ID)
+	if err != nil {
+		return err
+	}
+
+	err = insertPostMedia(tx, post)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (pr *postRepo) Delete(id int64) error {
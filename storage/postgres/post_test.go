@@ -78,4 +78,52 @@ func TestUpdatePost(t *testing.T) {
 func TestDeletePost(t *testing.T) {
 	p := createPost(t)
 	deletePost(p.ID, t)
-}
\ No newline at end of file
+}
+
+func TestPostGallery(t *testing.T) {
+	p := createPost(t)
+
+	first := createMedia(t, p.UserID)
+	second := createMedia(t, p.UserID)
+
+	caption := faker.Sentence()
+
+	p.CoverMediaID = &second.ID
+	p.Media = []*repo.PostMedia{
+		{MediaID: second.ID, Caption: &caption},
+		{MediaID: first.ID},
+	}
+
+	err := strg.Post().Update(p)
+	require.NoError(t, err)
+
+	gallery, err := strg.Post().GetMedia([]int64{p.ID})
+	require.NoError(t, err)
+	require.Len(t, gallery, 2)
+	require.Equal(t, second.ID, gallery[0].MediaID)
+	require.Equal(t, caption, *gallery[0].Caption)
+	require.Equal(t, second.Path, gallery[0].Path)
+	require.Equal(t, first.ID, gallery[1].MediaID)
+
+	post, err := strg.Post().Get(p.ID)
+	require.NoError(t, err)
+	require.Equal(t, second.ID, *post.CoverMediaID)
+
+	authorID, err := strg.Post().GetAuthorID(p.ID)
+	require.NoError(t, err)
+	require.Equal(t, p.UserID, authorID)
+
+	// Media in a gallery is linked
+	linked, err := strg.Media().IsLinked(first.Path)
+	require.NoError(t, err)
+	require.True(t, linked)
+
+	deletePost(p.ID, t)
+
+	gallery, err = strg.Post().GetMedia([]int64{p.ID})
+	require.NoError(t, err)
+	require.Empty(t, gallery)
+
+	require.NoError(t, strg.Media().Delete(first.ID))
+	require.NoError(t, strg.Media().Delete(second.ID))
+}
@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type recoveryCodeRepo struct {
+	db *sqlx.DB
+}
+
+func NewRecoveryCode(db *sqlx.DB) repo.RecoveryCodeStorageI {
+	return &recoveryCodeRepo{
+		db: db,
+	}
+}
+
+func (rr *recoveryCodeRepo) Replace(userID int64, codeHashes []string) error {
+	tx, err := rr.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO recovery_codes (
+			user_id,
+			code_hash
+		) VALUES($1, $2)
+	`
+
+	for _, hash := range codeHashes {
+		_, err = tx.Exec(query, userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (rr *recoveryCodeRepo) Use(userID int64, codeHash string) error {
+	query := `
+		UPDATE recovery_codes SET
+			used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	result, err := rr.db.Exec(query, userID, codeHash)
+
+	if err != nil {
+		return err
+	}
+
+	rowsCount, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsCount == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (rr *recoveryCodeRepo) DeleteAll(userID int64) error {
+	_, err := rr.db.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package postgres_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/bxcodec/faker/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoveryCodes(t *testing.T) {
+	user := createUser(t)
+	defer deleteUser(user.ID, t)
+
+	hashes := []string{faker.UUIDDigit(), faker.UUIDDigit()}
+
+	err := strg.RecoveryCode().Replace(user.ID, hashes)
+	require.NoError(t, err)
+
+	err = strg.RecoveryCode().Use(user.ID, hashes[0])
+	require.NoError(t, err)
+
+	err = strg.RecoveryCode().Use(user.ID, hashes[0])
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	err = strg.RecoveryCode().DeleteAll(user.ID)
+	require.NoError(t, err)
+
+	err = strg.RecoveryCode().Use(user.ID, hashes[1])
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type sessionRepo struct {
+	db *sqlx.DB
+}
+
+func NewSession(db *sqlx.DB) repo.SessionStorageI {
+	return &sessionRepo{
+		db: db,
+	}
+}
+
+const sessionColumns = `
+	id,
+	user_id,
+	device_name,
+	user_agent,
+	ip,
+	created_at,
+	last_seen_at,
+	expires_at,
+	revoked_at
+`
+
+func (sr *sessionRepo) Create(session *repo.Session) (*repo.Session, error) {
+	query := `
+		INSERT INTO user_sessions (
+			id,
+			user_id,
+			device_name,
+			user_agent,
+			ip,
+			expires_at
+		) VALUES($1, $2, $3, $4, $5, $6)
+		RETURNING created_at, last_seen_at
+	`
+
+	row := sr.db.QueryRow(
+		query,
+		session.ID,
+		session.UserID,
+		session.DeviceName,
+		session.UserAgent,
+		session.IP,
+		session.ExpiresAt,
+	)
+
+	err := row.Scan(
+		&session.CreatedAt,
+		&session.LastSeenAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (sr *sessionRepo) GetActive(id string) (*repo.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM user_sessions
+		WHERE id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP`
+
+	var result repo.Session
+
+	err := sr.db.Get(&result, query, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (sr *sessionRepo) GetAll(userID int64) ([]*repo.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM user_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		ORDER BY last_seen_at DESC`
+
+	result := make([]*repo.Session, 0)
+
+	err := sr.db.Select(&result, query, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (sr *sessionRepo) Touch(id, ip string) error {
+	query := `
+		UPDATE user_sessions SET
+			last_seen_at = CURRENT_TIMESTAMP,
+			ip = $2
+		WHERE id = $1
+	`
+
+	_, err := sr.db.Exec(query, id, ip)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (sr *sessionRepo) Revoke(id string, userID int64) error {
+	query := `
+		UPDATE user_sessions SET
+			revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+
+	result, err := sr.db.Exec(query, id, userID)
+
+	if err != nil {
+		return err
+	}
+
+	rowsCount, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsCount == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (sr *sessionRepo) RevokeAll(userID int64, exceptID string) ([]string, error) {
+	query := `
+		UPDATE user_sessions SET
+			revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND id::text <> $2 AND revoked_at IS NULL
+		RETURNING id
+	`
+
+	ids := make([]string, 0)
+
+	err := sr.db.Select(&ids, query, userID, exceptID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
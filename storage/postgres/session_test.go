@@ -0,0 +1,59 @@
+package postgres_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func createSession(t *testing.T, userID int64) *repo.Session {
+	session, err := strg.Session().Create(&repo.Session{
+		ID:         uuid.NewString(),
+		UserID:     userID,
+		DeviceName: "Chrome on Windows",
+		UserAgent:  "Mozilla/5.0",
+		IP:         "127.0.0.1",
+		ExpiresAt:  time.Now().Add(time.Hour),
+	})
+
+	require.NoError(t, err)
+	require.NotEmpty(t, session)
+
+	return session
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	user := createUser(t)
+	defer deleteUser(user.ID, t)
+
+	current := createSession(t, user.ID)
+	other := createSession(t, user.ID)
+
+	sessions, err := strg.Session().GetAll(user.ID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+
+	err = strg.Session().Touch(current.ID, "10.0.0.1")
+	require.NoError(t, err)
+
+	session, err := strg.Session().GetActive(current.ID)
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.1", session.IP)
+
+	ids, err := strg.Session().RevokeAll(user.ID, current.ID)
+	require.NoError(t, err)
+	require.Equal(t, []string{other.ID}, ids)
+
+	_, err = strg.Session().GetActive(other.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	err = strg.Session().Revoke(current.ID, user.ID)
+	require.NoError(t, err)
+
+	err = strg.Session().Revoke(current.ID, user.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type sitemapRepo struct {
+	db *sqlx.DB
+}
+
+func NewSitemap(db *sqlx.DB) repo.SitemapStorageI {
+	return &sitemapRepo{
+		db: db,
+	}
+}
+
+// sitemapPages takes whether banned content is hidden and the e-mail of the deleted user
+// placeholder, it has no page of its own. A category without posts changed when it was created
+const sitemapPages = `
+	WITH visible_posts AS (
+		SELECT id, user_id, category_id, COALESCE(updated_at, created_at) AS last_mod
+		FROM posts
+		WHERE NOT $1 OR user_id NOT IN (SELECT id FROM users WHERE banned)
+	), pages AS (
+		SELECT 1 AS position, '` + repo.SitemapPagePost + `' AS kind, id, last_mod
+		FROM visible_posts
+
+		UNION ALL
+
+		SELECT 2, '` + repo.SitemapPageCategory + `', c.id, COALESCE(MAX(p.last_mod), c.created_at)
+		FROM categories c
+		LEFT JOIN visible_posts p ON p.category_id = c.id
+		GROUP BY c.id
+
+		UNION ALL
+
+		SELECT 3, '` + repo.SitemapPageAuthor + `', p.user_id, MAX(p.last_mod)
+		FROM visible_posts p
+		INNER JOIN users u ON u.id = p.user_id
+		WHERE u.email <> $2
+		GROUP BY p.user_id
+	)
+`
+
+func (sr *sitemapRepo) GetAll(params *repo.GetSitemapParams) (*repo.GetSitemapResult, error) {
+	result := repo.GetSitemapResult{
+		Pages: make([]*repo.SitemapPage, 0),
+		Count: 0,
+	}
+
+	offset := (params.Page - 1) * params.Limit
+
+	limit := fmt.Sprintf(" LIMIT %d OFFSET %d ", params.Limit, offset)
+
+	query := sitemapPages + `
+		SELECT kind, id, last_mod
+		FROM pages
+		ORDER BY position, id
+		` + limit
+
+	err := sr.db.Select(&result.Pages, query, params.HideBanned, repo.DeletedUserEmail)
+
+	if err != nil {
+		return nil, err
+	}
+
+	queryCount := sitemapPages + `SELECT count(1) FROM pages`
+
+	err = sr.db.Get(&result.Count, queryCount, params.HideBanned, repo.DeletedUserEmail)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
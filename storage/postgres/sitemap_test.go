@@ -0,0 +1,53 @@
+package postgres_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+// getSitemapPages keys every page by its kind and ID
+func getSitemapPages(t *testing.T, hideBanned bool) map[string]*repo.SitemapPage {
+	result, err := strg.Sitemap().GetAll(&repo.GetSitemapParams{
+		Limit:      1000000,
+		Page:       1,
+		HideBanned: hideBanned,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Pages, int(result.Count))
+
+	pages := make(map[string]*repo.SitemapPage)
+	for _, page := range result.Pages {
+		pages[fmt.Sprintf("%s/%d", page.Kind, page.ID)] = page
+	}
+
+	return pages
+}
+
+func TestSitemap(t *testing.T) {
+	post := createPost(t)
+	defer deletePost(post.ID, t)
+
+	postKey := fmt.Sprintf("%s/%d", repo.SitemapPagePost, post.ID)
+	categoryKey := fmt.Sprintf("%s/%d", repo.SitemapPageCategory, post.CategoryID)
+	authorKey := fmt.Sprintf("%s/%d", repo.SitemapPageAuthor, post.UserID)
+
+	pages := getSitemapPages(t, false)
+
+	require.NotNil(t, pages[postKey])
+	require.WithinDuration(t, post.CreatedAt, pages[postKey].LastMod, 0)
+	require.NotNil(t, pages[categoryKey])
+	require.WithinDuration(t, post.CreatedAt, pages[categoryKey].LastMod, 0)
+	require.NotNil(t, pages[authorKey])
+
+	reason := "spam"
+	require.NoError(t, strg.User().SetBan(post.UserID, &reason))
+
+	// The category stays without the posts of the banned author
+	pages = getSitemapPages(t, true)
+	require.Nil(t, pages[postKey])
+	require.Nil(t, pages[authorKey])
+	require.NotNil(t, pages[categoryKey])
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/ibrat-muslim/blog-app/storage/repo"
 	"github.com/jmoiron/sqlx"
@@ -29,8 +30,9 @@ func (ur *userRepo) Create(user *repo.User) (*repo.User, error) {
 			password,
 			username,
 			profile_image_url,
-			type
-		) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			type,
+			language
+		) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING id, created_at
 	`
 
@@ -45,6 +47,7 @@ func (ur *userRepo) Create(user *repo.User) (*repo.User, error) {
 		user.Username,
 		user.ProfileImageUrl,
 		user.Type,
+		user.Language,
 	)
 
 	err := row.Scan(
@@ -72,7 +75,15 @@ func (ur *userRepo) Get(id int64) (*repo.User, error) {
 			username,
 			profile_image_url,
 			type,
-			created_at
+			language,
+			totp_secret,
+			totp_enabled,
+			created_at,
+			deletion_scheduled_at,
+			banned,
+			ban_reason,
+			suspended_until,
+			suspension_reason
 		FROM users
 		WHERE id = $1
 	`
@@ -101,7 +112,15 @@ func (ur *userRepo) GetByEmail(email string) (*repo.User, error) {
 			username,
 			profile_image_url,
 			type,
-			created_at
+			language,
+			totp_secret,
+			totp_enabled,
+			created_at,
+			deletion_scheduled_at,
+			banned,
+			ban_reason,
+			suspended_until,
+			suspension_reason
 		FROM users
 		WHERE email = $1
 	`
@@ -150,7 +169,15 @@ func (ur *userRepo) GetAll(params *repo.GetUsersParams) (*repo.GetUsersResult, e
 			username,
 			profile_image_url,
 			type,
-			created_at
+			language,
+			totp_secret,
+			totp_enabled,
+			created_at,
+			deletion_scheduled_at,
+			banned,
+			ban_reason,
+			suspended_until,
+			suspension_reason
 		FROM users
 		` + filter + `
 		ORDER BY created_at DESC
@@ -179,12 +206,12 @@ func (ur *userRepo) Update(user *repo.User) error {
 			first_name = $1,
 			last_name = $2,
 			phone_number = $3,
-			email = $4,
-			gender = $5,
-			password = $6,
-			username = $7,
-			profile_image_url = $8,
-			type = $9
+			gender = $4,
+			password = $5,
+			username = $6,
+			profile_image_url = $7,
+			type = $8,
+			language = $9
 		WHERE id = $10
 	`
 
@@ -193,12 +220,12 @@ func (ur *userRepo) Update(user *repo.User) error {
 		user.FirstName,
 		user.LastName,
 		user.PhoneNumber,
-		user.Email,
 		user.Gender,
 		user.Password,
 		user.Username,
 		user.ProfileImageUrl,
 		user.Type,
+		user.Language,
 		user.ID,
 	)
 
@@ -256,3 +283,212 @@ func (ur *userRepo) UpdatePassword(req *repo.UpdatePassword) error {
 
 	return nil
 }
+
+func (ur *userRepo) UpdateEmail(req *repo.UpdateEmail) error {
+	query := `UPDATE users SET email = $1 WHERE id = $2`
+
+	result, err := ur.db.Exec(
+		query,
+		req.Email,
+		req.UserID,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	rowsCount, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsCount == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (ur *userRepo) UpdateTwoFactor(req *repo.UpdateTwoFactor) error {
+	query := `UPDATE users SET totp_secret = $1, totp_enabled = $2 WHERE id = $3`
+
+	result, err := ur.db.Exec(
+		query,
+		req.Secret,
+		req.Enabled,
+		req.UserID,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	rowsCount, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsCount == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (ur *userRepo) ScheduleDeletion(userID int64, at *time.Time) error {
+	query := `UPDATE users SET deletion_scheduled_at = $1 WHERE id = $2`
+
+	result, err := ur.db.Exec(query, at, userID)
+
+	if err != nil {
+		return err
+	}
+
+	rowsCount, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsCount == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (ur *userRepo) GetDueForDeletion(limit int32) ([]*repo.User, error) {
+	query := `
+		SELECT
+			id,
+			first_name,
+			last_name,
+			phone_number,
+			email,
+			gender,
+			password,
+			username,
+			profile_image_url,
+			type,
+			language,
+			totp_secret,
+			totp_enabled,
+			created_at,
+			deletion_scheduled_at,
+			banned,
+			ban_reason,
+			suspended_until,
+			suspension_reason
+		FROM users
+		WHERE deletion_scheduled_at <= CURRENT_TIMESTAMP
+		ORDER BY deletion_scheduled_at
+		LIMIT $1
+	`
+
+	result := make([]*repo.User, 0)
+
+	err := ur.db.Select(&result, query, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (ur *userRepo) DeleteAccount(userID int64, removeContent bool) error {
+	tx, err := ur.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	queries := []string{
+		`DELETE FROM likes WHERE user_id = $1`,
+		`UPDATE comments SET user_id = (SELECT id FROM users WHERE email = $2) WHERE user_id = $1`,
+		`UPDATE posts SET user_id = (SELECT id FROM users WHERE email = $2) WHERE user_id = $1`,
+	}
+	args := []interface{}{userID, repo.DeletedUserEmail}
+
+	if removeContent {
+		queries = []string{
+			`DELETE FROM likes WHERE user_id = $1 OR post_id IN (SELECT id FROM posts WHERE user_id = $1)`,
+			`DELETE FROM comments WHERE user_id = $1 OR post_id IN (SELECT id FROM posts WHERE user_id = $1)`,
+			`DELETE FROM posts WHERE user_id = $1`,
+		}
+		args = args[:1]
+	}
+
+	for _, query := range queries {
+		_, err = tx.Exec(query, args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.Exec(`DELETE FROM users WHERE id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsCount, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsCount == 0 {
+		return sql.ErrNoRows
+	}
+
+	return tx.Commit()
+}
+
+func (ur *userRepo) SetBan(userID int64, reason *string) error {
+	query := `UPDATE users SET banned = $1, ban_reason = $2 WHERE id = $3`
+
+	result, err := ur.db.Exec(query, reason != nil, reason, userID)
+
+	if err != nil {
+		return err
+	}
+
+	rowsCount, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsCount == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (ur *userRepo) SetSuspension(userID int64, until *time.Time, reason *string) error {
+	query := `UPDATE users SET suspended_until = $1, suspension_reason = $2 WHERE id = $3`
+
+	if until == nil {
+		reason = nil
+	}
+
+	result, err := ur.db.Exec(query, until, reason, userID)
+
+	if err != nil {
+		return err
+	}
+
+	rowsCount, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsCount == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type userIdentityRepo struct {
+	db *sqlx.DB
+}
+
+func NewUserIdentity(db *sqlx.DB) repo.UserIdentityStorageI {
+	return &userIdentityRepo{
+		db: db,
+	}
+}
+
+func (ur *userIdentityRepo) Create(identity *repo.UserIdentity) (*repo.UserIdentity, error) {
+	query := `
+		INSERT INTO user_identities (
+			user_id,
+			provider,
+			subject,
+			email,
+			last_login_at
+		) VALUES($1, $2, $3, $4, CURRENT_TIMESTAMP)
+		RETURNING id, created_at, last_login_at
+	`
+
+	row := ur.db.QueryRow(
+		query,
+		identity.UserID,
+		identity.Provider,
+		identity.Subject,
+		identity.Email,
+	)
+
+	err := row.Scan(
+		&identity.ID,
+		&identity.CreatedAt,
+		&identity.LastLoginAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return identity, nil
+}
+
+func (ur *userIdentityRepo) GetBySubject(provider, subject string) (*repo.UserIdentity, error) {
+	query := `
+		SELECT
+			id,
+			user_id,
+			provider,
+			subject,
+			email,
+			created_at,
+			last_login_at
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2
+	`
+
+	var result repo.UserIdentity
+
+	err := ur.db.Get(&result, query, provider, subject)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (ur *userIdentityRepo) UpdateLastLogin(id int64) error {
+	query := `UPDATE user_identities SET last_login_at = CURRENT_TIMESTAMP WHERE id = $1`
+
+	result, err := ur.db.Exec(query, id)
+
+	if err != nil {
+		return err
+	}
+
+	rowsCount, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsCount == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
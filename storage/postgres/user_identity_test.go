@@ -0,0 +1,30 @@
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/bxcodec/faker/v4"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserIdentity(t *testing.T) {
+	user := createUser(t)
+	defer deleteUser(user.ID, t)
+
+	identity, err := strg.UserIdentity().Create(&repo.UserIdentity{
+		UserID:   user.ID,
+		Provider: "oidc",
+		Subject:  faker.UUIDDigit(),
+		Email:    &user.Email,
+	})
+	require.NoError(t, err)
+	require.NotZero(t, identity.ID)
+
+	found, err := strg.UserIdentity().GetBySubject(identity.Provider, identity.Subject)
+	require.NoError(t, err)
+	require.Equal(t, user.ID, found.UserID)
+
+	err = strg.UserIdentity().UpdateLastLogin(identity.ID)
+	require.NoError(t, err)
+}
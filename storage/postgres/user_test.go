@@ -1,7 +1,9 @@
 package postgres_test
 
 import (
+	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/bxcodec/faker/v4"
 	"github.com/ibrat-muslim/blog-app/storage/repo"
@@ -64,7 +66,6 @@ func TestUpdateUser(t *testing.T) {
 
 	u.FirstName = faker.FirstName()
 	u.LastName = faker.LastName()
-	u.Email = faker.Email()
 	u.Password = faker.Password()
 	u.Type = repo.UserTypeUser
 
@@ -74,7 +75,145 @@ func TestUpdateUser(t *testing.T) {
 	deleteUser(u.ID, t)
 }
 
+// Email changes need confirmation, so Update must store every other field and keep the email
+func TestUpdateUserKeepsEmail(t *testing.T) {
+	u := createUser(t)
+
+	user, err := strg.User().GetByEmail(u.Email)
+	require.NoError(t, err)
+	require.Equal(t, u.ID, user.ID)
+
+	email := u.Email
+	firstName := faker.FirstName()
+
+	u.FirstName = firstName
+	u.Email = faker.Email()
+
+	err = strg.User().Update(u)
+	require.NoError(t, err)
+
+	user, err = strg.User().Get(u.ID)
+	require.NoError(t, err)
+	require.Equal(t, firstName, user.FirstName)
+	require.Equal(t, email, user.Email)
+
+	deleteUser(u.ID, t)
+}
+
+func TestUpdateUserEmail(t *testing.T) {
+	u := createUser(t)
+
+	email := faker.Email()
+
+	err := strg.User().UpdateEmail(&repo.UpdateEmail{
+		UserID: u.ID,
+		Email:  email,
+	})
+	require.NoError(t, err)
+
+	user, err := strg.User().GetByEmail(email)
+	require.NoError(t, err)
+	require.Equal(t, u.ID, user.ID)
+
+	deleteUser(u.ID, t)
+}
+
 func TestDeleteUser(t *testing.T) {
 	u := createUser(t)
 	deleteUser(u.ID, t)
 }
+
+func TestScheduleDeletion(t *testing.T) {
+	u := createUser(t)
+	defer deleteUser(u.ID, t)
+
+	at := time.Now().Add(-time.Minute)
+
+	err := strg.User().ScheduleDeletion(u.ID, &at)
+	require.NoError(t, err)
+
+	users, err := strg.User().GetDueForDeletion(1000)
+	require.NoError(t, err)
+	require.True(t, containsUser(users, u.ID))
+
+	err = strg.User().ScheduleDeletion(u.ID, nil)
+	require.NoError(t, err)
+
+	users, err = strg.User().GetDueForDeletion(1000)
+	require.NoError(t, err)
+	require.False(t, containsUser(users, u.ID))
+}
+
+func containsUser(users []*repo.User, id int64) bool {
+	for _, user := range users {
+		if user.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDeleteAccountReassignsContent(t *testing.T) {
+	p := createPost(t)
+	defer deletePost(p.ID, t)
+
+	err := strg.User().DeleteAccount(p.UserID, false)
+	require.NoError(t, err)
+
+	placeholder, err := strg.User().GetByEmail(repo.DeletedUserEmail)
+	require.NoError(t, err)
+
+	post, err := strg.Post().Get(p.ID)
+	require.NoError(t, err)
+	require.Equal(t, placeholder.ID, post.UserID)
+}
+
+func TestDeleteAccountRemovesContent(t *testing.T) {
+	p := createPost(t)
+
+	err := strg.User().DeleteAccount(p.UserID, true)
+	require.NoError(t, err)
+
+	_, err = strg.Post().Get(p.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = strg.User().Get(p.UserID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestBanAndSuspendUser(t *testing.T) {
+	u := createUser(t)
+
+	reason := "spam"
+	err := strg.User().SetBan(u.ID, &reason)
+	require.NoError(t, err)
+
+	until := time.Now().Add(time.Hour).Truncate(time.Second)
+	err = strg.User().SetSuspension(u.ID, &until, &reason)
+	require.NoError(t, err)
+
+	user, err := strg.User().Get(u.ID)
+	require.NoError(t, err)
+	require.True(t, user.Banned)
+	require.Equal(t, reason, *user.BanReason)
+	require.True(t, until.Equal(*user.SuspendedUntil))
+	require.Equal(t, reason, *user.SuspensionReason)
+
+	err = strg.User().SetBan(u.ID, nil)
+	require.NoError(t, err)
+
+	err = strg.User().SetSuspension(u.ID, nil, &reason)
+	require.NoError(t, err)
+
+	user, err = strg.User().Get(u.ID)
+	require.NoError(t, err)
+	require.False(t, user.Banned)
+	require.Nil(t, user.BanReason)
+	require.Nil(t, user.SuspendedUntil)
+	require.Nil(t, user.SuspensionReason)
+
+	err = strg.User().SetBan(-1, &reason)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	deleteUser(u.ID, t)
+}
@@ -0,0 +1,364 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/jmoiron/sqlx"
+)
+
+type webhookRepo struct {
+	db *sqlx.DB
+}
+
+func NewWebhook(db *sqlx.DB) repo.WebhookStorageI {
+	return &webhookRepo{
+		db: db,
+	}
+}
+
+const webhookColumns = `
+	id,
+	url,
+	secret,
+	events,
+	active,
+	created_by,
+	created_at,
+	updated_at
+`
+
+func (wr *webhookRepo) Create(webhook *repo.Webhook) (*repo.Webhook, error) {
+	query := `
+		INSERT INTO webhooks (
+			url,
+			secret,
+			events,
+			active,
+			created_by
+		) VALUES($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	row := wr.db.QueryRow(
+		query,
+		webhook.URL,
+		webhook.Secret,
+		webhook.Events,
+		webhook.Active,
+		webhook.CreatedBy,
+	)
+
+	err := row.Scan(
+		&webhook.ID,
+		&webhook.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+func (wr *webhookRepo) Get(id int64) (*repo.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1`
+
+	var result repo.Webhook
+
+	err := wr.db.Get(&result, query, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (wr *webhookRepo) GetAll(params *repo.GetWebhooksParams) (*repo.GetWebhooksResult, error) {
+	result := repo.GetWebhooksResult{
+		Webhooks: make([]*repo.Webhook, 0),
+		Count:    0,
+	}
+
+	offset := (params.Page - 1) * params.Limit
+
+	limit := fmt.Sprintf(" LIMIT %d OFFSET %d ", params.Limit, offset)
+
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		ORDER BY created_at DESC, id DESC
+		` + limit
+
+	err := wr.db.Select(&result.Webhooks, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = wr.db.Get(&result.Count, `SELECT count(1) FROM webhooks`)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (wr *webhookRepo) Update(webhook *repo.Webhook) error {
+	query := `
+		UPDATE webhooks SET
+			url = $1,
+			secret = $2,
+			events = $3,
+			active = $4,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $5
+		RETURNING updated_at
+	`
+
+	err := wr.db.QueryRow(
+		query,
+		webhook.URL,
+		webhook.Secret,
+		webhook.Events,
+		webhook.Active,
+		webhook.ID,
+	).Scan(&webhook.UpdatedAt)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (wr *webhookRepo) Delete(id int64) error {
+	result, err := wr.db.Exec(`DELETE FROM webhooks WHERE id = $1`, id)
+
+	if err != nil {
+		return err
+	}
+
+	rowsCount, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsCount == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+type webhookDeliveryRepo struct {
+	db *sqlx.DB
+}
+
+func NewWebhookDelivery(db *sqlx.DB) repo.WebhookDeliveryStorageI {
+	return &webhookDeliveryRepo{
+		db: db,
+	}
+}
+
+const webhookDeliveryColumns = `
+	id,
+	webhook_id,
+	event,
+	payload,
+	status,
+	attempts,
+	response_code,
+	last_error,
+	next_attempt_at,
+	created_at,
+	delivered_at
+`
+
+func (dr *webhookDeliveryRepo) Enqueue(event string, payload []byte) (int64, error) {
+	query := `
+		INSERT INTO webhook_deliveries (
+			webhook_id,
+			event,
+			payload
+		)
+		SELECT id, $1, $2 FROM webhooks
+		WHERE active AND $1 = ANY(events)
+	`
+
+	result, err := dr.db.Exec(query, event, payload)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (dr *webhookDeliveryRepo) Create(delivery *repo.WebhookDelivery) (*repo.WebhookDelivery, error) {
+	query := `
+		INSERT INTO webhook_deliveries (
+			webhook_id,
+			event,
+			payload,
+			next_attempt_at
+		) VALUES($1, $2, $3, $4)
+		RETURNING id, status, attempts, created_at
+	`
+
+	row := dr.db.QueryRow(
+		query,
+		delivery.WebhookID,
+		delivery.Event,
+		delivery.Payload,
+		delivery.NextAttemptAt,
+	)
+
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+func (dr *webhookDeliveryRepo) GetAll(params *repo.GetWebhookDeliveriesParams) (*repo.GetWebhookDeliveriesResult, error) {
+	result := repo.GetWebhookDeliveriesResult{
+		Deliveries: make([]*repo.WebhookDelivery, 0),
+		Count:      0,
+	}
+
+	offset := (params.Page - 1) * params.Limit
+
+	limit := fmt.Sprintf(" LIMIT %d OFFSET %d ", params.Limit, offset)
+
+	filter := " WHERE true "
+	args := make([]interface{}, 0)
+
+	if params.WebhookID != 0 {
+		args = append(args, params.WebhookID)
+		filter += fmt.Sprintf(" AND webhook_id = $%d ", len(args))
+	}
+
+	if params.Status != "" {
+		args = append(args, params.Status)
+		filter += fmt.Sprintf(" AND status = $%d ", len(args))
+	}
+
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries
+		` + filter + `
+		ORDER BY created_at DESC, id DESC
+		` + limit
+
+	err := dr.db.Select(&result.Deliveries, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	queryCount := `SELECT count(1) FROM webhook_deliveries ` + filter
+
+	err = dr.db.Get(&result.Count, queryCount, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (dr *webhookDeliveryRepo) ClaimPending(limit int32, lease time.Duration) ([]*repo.WebhookDelivery, error) {
+	query := `
+		WITH claimed AS (
+			UPDATE webhook_deliveries SET
+				next_attempt_at = $1
+			WHERE id IN (
+				SELECT id FROM webhook_deliveries
+				WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+				ORDER BY next_attempt_at
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING ` + webhookDeliveryColumns + `
+		)
+		SELECT claimed.*, w.url, w.secret
+		FROM claimed
+		INNER JOIN webhooks w ON w.id = claimed.webhook_id
+	`
+
+	result := make([]*repo.WebhookDelivery, 0)
+
+	err := dr.db.Select(&result, query, time.Now().Add(lease), limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (dr *webhookDeliveryRepo) MarkDelivered(id int64, responseCode int) error {
+	query := `
+		UPDATE webhook_deliveries SET
+			status = 'delivered',
+			attempts = attempts + 1,
+			response_code = $1,
+			delivered_at = CURRENT_TIMESTAMP,
+			last_error = NULL
+		WHERE id = $2
+	`
+
+	return dr.exec(query, responseCode, id)
+}
+
+func (dr *webhookDeliveryRepo) MarkFailed(id int64, responseCode *int, errMsg string, nextAttemptAt time.Time, dead bool) error {
+	status := repo.WebhookDeliveryStatusPending
+	if dead {
+		status = repo.WebhookDeliveryStatusDead
+	}
+
+	query := `
+		UPDATE webhook_deliveries SET
+			status = $1,
+			attempts = attempts + 1,
+			response_code = $2,
+			last_error = $3,
+			next_attempt_at = $4
+		WHERE id = $5
+	`
+
+	return dr.exec(query, status, responseCode, errMsg, nextAttemptAt, id)
+}
+
+func (dr *webhookDeliveryRepo) exec(query string, args ...interface{}) error {
+	result, err := dr.db.Exec(query, args...)
+
+	if err != nil {
+		return err
+	}
+
+	rowsCount, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsCount == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
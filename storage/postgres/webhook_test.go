@@ -0,0 +1,100 @@
+package postgres_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bxcodec/faker/v4"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+func createWebhook(t *testing.T) *repo.Webhook {
+	webhook, err := strg.Webhook().Create(&repo.Webhook{
+		URL:    faker.URL(),
+		Secret: faker.Password(),
+		Events: []string{repo.WebhookEventPostCreated, repo.WebhookEventCommentCreated},
+		Active: true,
+	})
+
+	require.NoError(t, err)
+	require.NotEmpty(t, webhook)
+
+	return webhook
+}
+
+func TestUpdateWebhook(t *testing.T) {
+	w := createWebhook(t)
+
+	updatedAt := time.Now()
+	w.Active = false
+	w.Events = []string{repo.WebhookEventPostDeleted}
+	w.UpdatedAt = &updatedAt
+
+	err := strg.Webhook().Update(w)
+	require.NoError(t, err)
+
+	webhook, err := strg.Webhook().Get(w.ID)
+	require.NoError(t, err)
+	require.False(t, webhook.Active)
+	require.Equal(t, []string{repo.WebhookEventPostDeleted}, []string(webhook.Events))
+
+	err = strg.Webhook().Delete(w.ID)
+	require.NoError(t, err)
+
+	_, err = strg.Webhook().Get(w.ID)
+	require.Error(t, err)
+}
+
+func TestWebhookDeliveryLifecycle(t *testing.T) {
+	w := createWebhook(t)
+
+	count, err := strg.WebhookDelivery().Enqueue(repo.WebhookEventPostCreated, []byte(`{"event":"post.created"}`))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, count, int64(1))
+
+	deliveries, err := strg.WebhookDelivery().GetAll(&repo.GetWebhookDeliveriesParams{
+		Limit:     10,
+		Page:      1,
+		WebhookID: w.ID,
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries.Deliveries, 1)
+
+	d := deliveries.Deliveries[0]
+	require.Equal(t, repo.WebhookDeliveryStatusPending, d.Status)
+
+	code := 500
+	err = strg.WebhookDelivery().MarkFailed(d.ID, &code, "internal server error", time.Now(), false)
+	require.NoError(t, err)
+
+	err = strg.WebhookDelivery().MarkDelivered(d.ID, 200)
+	require.NoError(t, err)
+
+	deliveries, err = strg.WebhookDelivery().GetAll(&repo.GetWebhookDeliveriesParams{
+		Limit:     10,
+		Page:      1,
+		WebhookID: w.ID,
+		Status:    repo.WebhookDeliveryStatusDelivered,
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries.Deliveries, 1)
+	require.EqualValues(t, 2, deliveries.Deliveries[0].Attempts)
+	require.EqualValues(t, 200, *deliveries.Deliveries[0].ResponseCode)
+	require.NotNil(t, deliveries.Deliveries[0].DeliveredAt)
+
+	// Leased deliveries are not claimed before the lease ends
+	leased, err := strg.WebhookDelivery().Create(&repo.WebhookDelivery{
+		WebhookID:     w.ID,
+		Event:         repo.WebhookEventPing,
+		Payload:       []byte(`{"event":"ping"}`),
+		NextAttemptAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	claimed, err := strg.WebhookDelivery().ClaimPending(100, time.Minute)
+	require.NoError(t, err)
+	for _, c := range claimed {
+		require.NotEqual(t, leased.ID, c.ID)
+	}
+}
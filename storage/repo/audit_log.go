@@ -0,0 +1,41 @@
+package repo
+
+import "time"
+
+// AuditLog.ImpersonatorID is the superadmin who acted as the actor
+type AuditLog struct {
+	ID             int64   `db:"id"`
+	ActorID        *int64  `db:"actor_id"`
+	ImpersonatorID *int64  `db:"impersonator_id"`
+	Action         string  `db:"action"`
+	TargetType     *string `db:"target_type"`
+	TargetID       *string `db:"target_id"`
+	// Before and After hold the JSON state of the target, nil when there is none
+	Before    []byte    `db:"before"`
+	After     []byte    `db:"after"`
+	IP        string    `db:"ip"`
+	RequestID string    `db:"request_id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type GetAuditLogParams struct {
+	Limit          int32      `db:"limit"`
+	Page           int32      `db:"page"`
+	ActorID        int64      `db:"actor_id"`
+	ImpersonatorID int64      `db:"impersonator_id"`
+	TargetType     string     `db:"target_type"`
+	TargetID       string     `db:"target_id"`
+	From           *time.Time `db:"from"`
+	To             *time.Time `db:"to"`
+}
+
+type GetAuditLogResult struct {
+	Entries []*AuditLog `db:"entries"`
+	Count   int32       `db:"count"`
+}
+
+// AuditLogStorageI has no update or delete, the log is append-only
+type AuditLogStorageI interface {
+	Create(entry *AuditLog) (*AuditLog, error)
+	GetAll(params *GetAuditLogParams) (*GetAuditLogResult, error)
+}
@@ -22,6 +22,8 @@ type GetCommentsParams struct {
 	Page   int32 `db:"page"`
 	PostID int64 `db:"post_id"`
 	UserID int64 `db:"user_id"`
+	// HideBanned leaves out the comments of banned users
+	HideBanned bool `db:"hide_banned"`
 }
 
 type GetCommentsResult struct {
@@ -0,0 +1,33 @@
+package repo
+
+import "time"
+
+const (
+	DataExportStatusPending = "pending"
+	DataExportStatusReady   = "ready"
+	DataExportStatusFailed  = "failed"
+)
+
+type DataExport struct {
+	ID            string     `db:"id"`
+	UserID        int64      `db:"user_id"`
+	Status        string     `db:"status"`
+	FilePath      *string    `db:"file_path"`
+	Attempts      int32      `db:"attempts"`
+	LastError     *string    `db:"last_error"`
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	CreatedAt     time.Time  `db:"created_at"`
+	CompletedAt   *time.Time `db:"completed_at"`
+	ExpiresAt     *time.Time `db:"expires_at"`
+}
+
+type DataExportStorageI interface {
+	Create(export *DataExport) (*DataExport, error)
+	GetLatest(userID int64) (*DataExport, error)
+	// ClaimPending locks due exports for lease, so a crashed worker's batch is picked up again later
+	ClaimPending(limit int32, lease time.Duration) ([]*DataExport, error)
+	MarkReady(id, filePath string, expiresAt time.Time) error
+	MarkFailed(id, errMsg string, nextAttemptAt time.Time, failed bool) error
+	// DeleteExpired removes expired exports and returns them so their files can be removed too
+	DeleteExpired() ([]*DataExport, error)
+}
@@ -0,0 +1,52 @@
+package repo
+
+import "time"
+
+const (
+	EmailStatusPending = "pending"
+	EmailStatusSent    = "sent"
+	EmailStatusDead    = "dead"
+)
+
+// EmailOutbox keeps the bodies until the email is sent, ExpiresAt is set for emails
+// with codes or links that stop working, they are neither sent nor resent afterwards
+type EmailOutbox struct {
+	ID            int64      `db:"id"`
+	Recipient     string     `db:"recipient"`
+	Subject       string     `db:"subject"`
+	HTMLBody      string     `db:"html_body"`
+	TextBody      string     `db:"text_body"`
+	Status        string     `db:"status"`
+	Attempts      int32      `db:"attempts"`
+	LastError     *string    `db:"last_error"`
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	CreatedAt     time.Time  `db:"created_at"`
+	SentAt        *time.Time `db:"sent_at"`
+	ExpiresAt     *time.Time `db:"expires_at"`
+}
+
+type GetEmailOutboxParams struct {
+	Limit  int32  `db:"limit"`
+	Page   int32  `db:"page"`
+	Status string `db:"status"`
+}
+
+type GetEmailOutboxResult struct {
+	Emails []*EmailOutbox `db:"emails"`
+	Count  int32          `db:"count"`
+}
+
+type EmailOutboxStorageI interface {
+	Create(email *EmailOutbox) (*EmailOutbox, error)
+	Get(id int64) (*EmailOutbox, error)
+	GetAll(params *GetEmailOutboxParams) (*GetEmailOutboxResult, error)
+	// ClaimPending locks due emails for lease, so a crashed worker's batch is picked up again later
+	ClaimPending(limit int32, lease time.Duration) ([]*EmailOutbox, error)
+	// MarkSent clears the bodies
+	MarkSent(id int64) error
+	MarkFailed(id int64, errMsg string, nextAttemptAt time.Time, dead bool) error
+	// MarkExpired moves the email to dead letter and clears the bodies
+	MarkExpired(id int64) error
+	// Resend returns sql.ErrNoRows for sent and expired emails too
+	Resend(id int64) error
+}
@@ -16,4 +16,5 @@ type LikeStorageI interface {
 	CreateOrUpdate(like *Like) error
 	Get(postID, userID int64) (*Like, error)
 	GetLikesDislikesCount(postID int64) (*LikesDislikesCountsResult, error)
+	GetAllByUser(userID int64) ([]*Like, error)
 }
@@ -0,0 +1,57 @@
+package repo
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// Media is an uploaded file, Path is its key in the blob store and Size is what it takes there,
+// VariantsSize is what the variants made of an image take. UserID is nil once the owner's account is deleted
+type Media struct {
+	ID             int64     `db:"id"`
+	UserID         *int64    `db:"user_id"`
+	Path           string    `db:"path"`
+	Purpose        string    `db:"purpose"`
+	MimeType       string    `db:"mime_type"`
+	Size           int64     `db:"size"`
+	VariantsSize   int64     `db:"variants_size"`
+	SHA256         string    `db:"sha256"`
+	Width          *int      `db:"width"`
+	Height         *int      `db:"height"`
+	CreatedAt      time.Time `db:"created_at"`
+	LastUploadedAt time.Time `db:"last_uploaded_at"`
+}
+
+type GetMediaParams struct {
+	Limit  int32 `db:"limit"`
+	Page   int32 `db:"page"`
+	UserID int64 `db:"user_id"`
+}
+
+type GetMediaResult struct {
+	Media []*Media `db:"media"`
+	Count int32    `db:"count"`
+}
+
+type MediaStorageI interface {
+	// Create returns sql.ErrNoRows when the user has already uploaded the content for the purpose
+	// and ErrQuotaExceeded when the file does not fit in the quota of the user, 0 is no quota
+	Create(media *Media, quota int64) (*Media, error)
+	// Reuse returns the file the user has uploaded with the content for the purpose,
+	// it counts as a new upload so the cleanup job does not remove it before it is linked
+	Reuse(userID int64, sha256, purpose string) (*Media, error)
+	// Get returns files of the user only, sql.ErrNoRows otherwise
+	Get(id, userID int64) (*Media, error)
+	// GetByIDs skips the IDs that do not exist
+	GetByIDs(ids []int64) ([]*Media, error)
+	GetAll(params *GetMediaParams) (*GetMediaResult, error)
+	// Usage returns how many bytes the files of the user take with their variants
+	Usage(userID int64) (int64, error)
+	// IsLinked reports whether a post, a gallery or a profile links the file
+	IsLinked(path string) (bool, error)
+	// GetOrphans returns files uploaded last before the time that no post, gallery or profile links
+	GetOrphans(uploadedBefore time.Time, limit int32) ([]*Media, error)
+	Delete(id int64) error
+}
@@ -0,0 +1,36 @@
+package repo
+
+import "time"
+
+const (
+	ModerationActionBan       = "ban"
+	ModerationActionUnban     = "unban"
+	ModerationActionSuspend   = "suspend"
+	ModerationActionUnsuspend = "unsuspend"
+)
+
+type ModerationAction struct {
+	ID             int64      `db:"id"`
+	UserID         int64      `db:"user_id"`
+	ModeratorID    *int64     `db:"moderator_id"`
+	Action         string     `db:"action"`
+	Reason         *string    `db:"reason"`
+	SuspendedUntil *time.Time `db:"suspended_until"`
+	CreatedAt      time.Time  `db:"created_at"`
+}
+
+type GetModerationActionsParams struct {
+	Limit  int32 `db:"limit"`
+	Page   int32 `db:"page"`
+	UserID int64 `db:"user_id"`
+}
+
+type GetModerationActionsResult struct {
+	Actions []*ModerationAction `db:"actions"`
+	Count   int32               `db:"count"`
+}
+
+type ModerationActionStorageI interface {
+	Create(action *ModerationAction) (*ModerationAction, error)
+	GetAll(params *GetModerationActionsParams) (*GetModerationActionsResult, error)
+}
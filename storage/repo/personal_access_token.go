@@ -0,0 +1,30 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type PersonalAccessToken struct {
+	ID          int64          `db:"id"`
+	UserID      int64          `db:"user_id"`
+	Name        string         `db:"name"`
+	TokenHash   string         `db:"token_hash"`
+	TokenPrefix string         `db:"token_prefix"`
+	Scopes      pq.StringArray `db:"scopes"`
+	ExpiresAt   *time.Time     `db:"expires_at"`
+	LastUsedAt  *time.Time     `db:"last_used_at"`
+	LastUsedIP  *string        `db:"last_used_ip"`
+	RevokedAt   *time.Time     `db:"revoked_at"`
+	CreatedAt   time.Time      `db:"created_at"`
+}
+
+type PersonalAccessTokenStorageI interface {
+	Create(token *PersonalAccessToken) (*PersonalAccessToken, error)
+	// GetByHash returns active tokens only, sql.ErrNoRows otherwise
+	GetByHash(tokenHash string) (*PersonalAccessToken, error)
+	GetAll(userID int64) ([]*PersonalAccessToken, error)
+	Revoke(id, userID int64) error
+	Touch(id int64, ip string) error
+}
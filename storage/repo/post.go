@@ -16,6 +16,23 @@ type Post struct {
 		LikesCount    int64 `db:"likes_count"`
 		DisLikesCount int64 `db:"dislikes_count"`
 	}
+	// CoverMediaID is an item of the gallery, Media is only written by Create and Update
+	CoverMediaID *int64       `db:"cover_media_id"`
+	Media        []*PostMedia `db:"-"`
+}
+
+// PostMedia is an item of the gallery of a post, the upload is joined when it is read
+type PostMedia struct {
+	PostID   int64   `db:"post_id"`
+	MediaID  int64   `db:"media_id"`
+	Position int32   `db:"position"`
+	Caption  *string `db:"caption"`
+	AltText  *string `db:"alt_text"`
+	Path     string  `db:"path"`
+	Purpose  string  `db:"purpose"`
+	MimeType string  `db:"mime_type"`
+	Width    *int    `db:"width"`
+	Height   *int    `db:"height"`
 }
 
 type GetPostsParams struct {
@@ -25,6 +42,8 @@ type GetPostsParams struct {
 	UserID     int64  `db:"user_id"`
 	CategoryID int64  `db:"category_id"`
 	SortByDate string `db:"sort_by_date"`
+	// HideBanned leaves out the posts of banned users
+	HideBanned bool `db:"hide_banned"`
 }
 
 type GetPostsResult struct {
@@ -33,9 +52,14 @@ type GetPostsResult struct {
 }
 
 type PostStorageI interface {
+	// Create and Update replace the gallery with the media of the post
 	Create(post *Post) (*Post, error)
 	Get(id int64) (*Post, error)
 	GetAll(params *GetPostsParams) (*GetPostsResult, error)
+	// GetMedia returns the galleries of the posts in order
+	GetMedia(postIDs []int64) ([]*PostMedia, error)
+	// GetAuthorID does not count as a view
+	GetAuthorID(id int64) (int64, error)
 	Update(post *Post) error
 	Delete(id int64) error
 }
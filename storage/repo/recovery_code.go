@@ -0,0 +1,19 @@
+package repo
+
+import "time"
+
+type RecoveryCode struct {
+	ID        int64      `db:"id"`
+	UserID    int64      `db:"user_id"`
+	CodeHash  string     `db:"code_hash"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+type RecoveryCodeStorageI interface {
+	// Replace drops the previous codes of the user and stores the new hashes
+	Replace(userID int64, codeHashes []string) error
+	// Use marks an unused code as used, it returns sql.ErrNoRows when there is no such code
+	Use(userID int64, codeHash string) error
+	DeleteAll(userID int64) error
+}
@@ -0,0 +1,26 @@
+package repo
+
+import "time"
+
+type Session struct {
+	ID         string     `db:"id"`
+	UserID     int64      `db:"user_id"`
+	DeviceName string     `db:"device_name"`
+	UserAgent  string     `db:"user_agent"`
+	IP         string     `db:"ip"`
+	CreatedAt  time.Time  `db:"created_at"`
+	LastSeenAt time.Time  `db:"last_seen_at"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+}
+
+type SessionStorageI interface {
+	Create(session *Session) (*Session, error)
+	// GetActive returns sql.ErrNoRows for revoked and expired sessions
+	GetActive(id string) (*Session, error)
+	GetAll(userID int64) ([]*Session, error)
+	Touch(id, ip string) error
+	Revoke(id string, userID int64) error
+	// RevokeAll revokes the active sessions of the user except one and returns their ids
+	RevokeAll(userID int64, exceptID string) ([]string, error)
+}
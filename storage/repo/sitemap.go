@@ -0,0 +1,35 @@
+package repo
+
+import "time"
+
+// Kinds of the public pages
+const (
+	SitemapPagePost     = "post"
+	SitemapPageCategory = "category"
+	SitemapPageAuthor   = "author"
+)
+
+// SitemapPage is a public page of the site, LastMod is when the latest of its posts changed.
+// Posts come first, then categories and then authors
+type SitemapPage struct {
+	Kind    string    `db:"kind"`
+	ID      int64     `db:"id"`
+	LastMod time.Time `db:"last_mod"`
+}
+
+type GetSitemapParams struct {
+	Limit int32 `db:"limit"`
+	Page  int32 `db:"page"`
+	// HideBanned leaves out banned authors and their posts
+	HideBanned bool `db:"hide_banned"`
+}
+
+type GetSitemapResult struct {
+	Pages []*SitemapPage `db:"pages"`
+	Count int32          `db:"count"`
+}
+
+// SitemapStorageI lists every post and category, authors are the users with posts
+type SitemapStorageI interface {
+	GetAll(params *GetSitemapParams) (*GetSitemapResult, error)
+}
@@ -7,6 +7,9 @@ const (
 	UserTypeUser       = "user"
 )
 
+// DeletedUserEmail belongs to the placeholder user that keeps the content of deleted accounts
+const DeletedUserEmail = "deleted-user@blog.invalid"
+
 type User struct {
 	ID              int64     `db:"id"`
 	FirstName       string    `db:"first_name"`
@@ -18,7 +21,17 @@ type User struct {
 	Username        *string   `db:"username"`
 	ProfileImageUrl *string   `db:"profile_image_url"`
 	Type            string    `db:"type"`
+	Language        *string   `db:"language"`
+	TotpSecret      *string   `db:"totp_secret"`
+	TotpEnabled     bool      `db:"totp_enabled"`
 	CreatedAt       time.Time `db:"created_at"`
+
+	DeletionScheduledAt *time.Time `db:"deletion_scheduled_at"`
+
+	Banned           bool       `db:"banned"`
+	BanReason        *string    `db:"ban_reason"`
+	SuspendedUntil   *time.Time `db:"suspended_until"`
+	SuspensionReason *string    `db:"suspension_reason"`
 }
 
 type GetUsersParams struct {
@@ -37,6 +50,17 @@ type UpdatePassword struct {
 	Password string `db:"password"`
 }
 
+type UpdateEmail struct {
+	UserID int64  `db:"user_id"`
+	Email  string `db:"email"`
+}
+
+type UpdateTwoFactor struct {
+	UserID  int64   `db:"user_id"`
+	Secret  *string `db:"totp_secret"`
+	Enabled bool    `db:"totp_enabled"`
+}
+
 type UserStorageI interface {
 	Create(user *User) (*User, error)
 	Get(id int64) (*User, error)
@@ -45,4 +69,16 @@ type UserStorageI interface {
 	Update(user *User) error
 	Delete(id int64) error
 	UpdatePassword(req *UpdatePassword) error
+	UpdateEmail(req *UpdateEmail) error
+	UpdateTwoFactor(req *UpdateTwoFactor) error
+	// ScheduleDeletion sets when the account is deleted, nil cancels the deletion
+	ScheduleDeletion(userID int64, at *time.Time) error
+	GetDueForDeletion(limit int32) ([]*User, error)
+	// SetBan bans the user for the reason, nil lifts the ban
+	SetBan(userID int64, reason *string) error
+	// SetSuspension suspends the user until the given time, nil lifts the suspension
+	SetSuspension(userID int64, until *time.Time, reason *string) error
+	// DeleteAccount deletes the user with the likes, the posts and the comments are
+	// deleted too when removeContent is set or reassigned to the placeholder user
+	DeleteAccount(userID int64, removeContent bool) error
 }
@@ -0,0 +1,20 @@
+package repo
+
+import "time"
+
+// UserIdentity links a user to an account at an external identity provider
+type UserIdentity struct {
+	ID          int64      `db:"id"`
+	UserID      int64      `db:"user_id"`
+	Provider    string     `db:"provider"`
+	Subject     string     `db:"subject"`
+	Email       *string    `db:"email"`
+	CreatedAt   time.Time  `db:"created_at"`
+	LastLoginAt *time.Time `db:"last_login_at"`
+}
+
+type UserIdentityStorageI interface {
+	Create(identity *UserIdentity) (*UserIdentity, error)
+	GetBySubject(provider, subject string) (*UserIdentity, error)
+	UpdateLastLogin(id int64) error
+}
@@ -0,0 +1,96 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Events webhooks can subscribe to
+const (
+	WebhookEventPostCreated    = "post.created"
+	WebhookEventPostUpdated    = "post.updated"
+	WebhookEventPostDeleted    = "post.deleted"
+	WebhookEventCommentCreated = "comment.created"
+	// WebhookEventPing is only sent by the test endpoint, it needs no subscription
+	WebhookEventPing = "ping"
+)
+
+const (
+	WebhookDeliveryStatusPending   = "pending"
+	WebhookDeliveryStatusDelivered = "delivered"
+	WebhookDeliveryStatusDead      = "dead"
+)
+
+// Webhook.Secret signs the deliveries, it is kept in plain text to do so
+type Webhook struct {
+	ID        int64          `db:"id"`
+	URL       string         `db:"url"`
+	Secret    string         `db:"secret"`
+	Events    pq.StringArray `db:"events"`
+	Active    bool           `db:"active"`
+	CreatedBy *int64         `db:"created_by"`
+	CreatedAt time.Time      `db:"created_at"`
+	UpdatedAt *time.Time     `db:"updated_at"`
+}
+
+type GetWebhooksParams struct {
+	Limit int32 `db:"limit"`
+	Page  int32 `db:"page"`
+}
+
+type GetWebhooksResult struct {
+	Webhooks []*Webhook `db:"webhooks"`
+	Count    int32      `db:"count"`
+}
+
+type WebhookStorageI interface {
+	Create(webhook *Webhook) (*Webhook, error)
+	Get(id int64) (*Webhook, error)
+	GetAll(params *GetWebhooksParams) (*GetWebhooksResult, error)
+	Update(webhook *Webhook) error
+	Delete(id int64) error
+}
+
+// WebhookDelivery.ResponseCode is the status of the latest attempt, nil when the receiver was not reached
+type WebhookDelivery struct {
+	ID            int64      `db:"id"`
+	WebhookID     int64      `db:"webhook_id"`
+	Event         string     `db:"event"`
+	Payload       []byte     `db:"payload"`
+	Status        string     `db:"status"`
+	Attempts      int32      `db:"attempts"`
+	ResponseCode  *int       `db:"response_code"`
+	LastError     *string    `db:"last_error"`
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	CreatedAt     time.Time  `db:"created_at"`
+	DeliveredAt   *time.Time `db:"delivered_at"`
+	// URL and Secret of the webhook are only read by ClaimPending
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+}
+
+type GetWebhookDeliveriesParams struct {
+	Limit     int32  `db:"limit"`
+	Page      int32  `db:"page"`
+	WebhookID int64  `db:"webhook_id"`
+	Status    string `db:"status"`
+}
+
+type GetWebhookDeliveriesResult struct {
+	Deliveries []*WebhookDelivery `db:"deliveries"`
+	Count      int32              `db:"count"`
+}
+
+type WebhookDeliveryStorageI interface {
+	// Enqueue creates a delivery of the event for every active webhook subscribed to it
+	// and returns how many were created
+	Enqueue(event string, payload []byte) (int64, error)
+	// Create is due at NextAttemptAt, a time in the future leases the delivery to the caller
+	Create(delivery *WebhookDelivery) (*WebhookDelivery, error)
+	GetAll(params *GetWebhookDeliveriesParams) (*GetWebhookDeliveriesResult, error)
+	// ClaimPending locks due deliveries for lease, so a crashed worker's batch is picked up again later
+	ClaimPending(limit int32, lease time.Duration) ([]*WebhookDelivery, error)
+	MarkDelivered(id int64, responseCode int) error
+	MarkFailed(id int64, responseCode *int, errMsg string, nextAttemptAt time.Time, dead bool) error
+}
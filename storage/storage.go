@@ -12,23 +12,59 @@ type StorageI interface {
 	Post() repo.PostStorageI
 	Comment() repo.CommentStorageI
 	Like() repo.LikeStorageI
+	EmailOutbox() repo.EmailOutboxStorageI
+	RecoveryCode() repo.RecoveryCodeStorageI
+	PersonalAccessToken() repo.PersonalAccessTokenStorageI
+	UserIdentity() repo.UserIdentityStorageI
+	Session() repo.SessionStorageI
+	DataExport() repo.DataExportStorageI
+	ModerationAction() repo.ModerationActionStorageI
+	AuditLog() repo.AuditLogStorageI
+	Media() repo.MediaStorageI
+	Sitemap() repo.SitemapStorageI
+	Webhook() repo.WebhookStorageI
+	WebhookDelivery() repo.WebhookDeliveryStorageI
 }
 
 type storagePg struct {
-	userRepo     repo.UserStorageI
-	categoryRepo repo.CategoryStorageI
-	postRepo     repo.PostStorageI
-	commentRepo  repo.CommentStorageI
-	likeRepo     repo.LikeStorageI
+	userRepo                repo.UserStorageI
+	categoryRepo            repo.CategoryStorageI
+	postRepo                repo.PostStorageI
+	commentRepo             repo.CommentStorageI
+	likeRepo                repo.LikeStorageI
+	emailOutboxRepo         repo.EmailOutboxStorageI
+	recoveryCodeRepo        repo.RecoveryCodeStorageI
+	personalAccessTokenRepo repo.PersonalAccessTokenStorageI
+	userIdentityRepo        repo.UserIdentityStorageI
+	sessionRepo             repo.SessionStorageI
+	dataExportRepo          repo.DataExportStorageI
+	moderationActionRepo    repo.ModerationActionStorageI
+	auditLogRepo            repo.AuditLogStorageI
+	mediaRepo               repo.MediaStorageI
+	sitemapRepo             repo.SitemapStorageI
+	webhookRepo             repo.WebhookStorageI
+	webhookDeliveryRepo     repo.WebhookDeliveryStorageI
 }
 
 func NewStoragePg(db *sqlx.DB) StorageI {
 	return &storagePg{
-		userRepo:     postgres.NewUser(db),
-		categoryRepo: postgres.NewCategory(db),
-		postRepo:     postgres.NewPost(db),
-		commentRepo:  postgres.NewComment(db),
-		likeRepo:     postgres.NewLike(db),
+		userRepo:                postgres.NewUser(db),
+		categoryRepo:            postgres.NewCategory(db),
+		postRepo:                postgres.NewPost(db),
+		commentRepo:             postgres.NewComment(db),
+		likeRepo:                postgres.NewLike(db),
+		emailOutboxRepo:         postgres.NewEmailOutbox(db),
+		recoveryCodeRepo:        postgres.NewRecoveryCode(db),
+		personalAccessTokenRepo: postgres.NewPersonalAccessToken(db),
+		userIdentityRepo:        postgres.NewUserIdentity(db),
+		sessionRepo:             postgres.NewSession(db),
+		dataExportRepo:          postgres.NewDataExport(db),
+		moderationActionRepo:    postgres.NewModerationAction(db),
+		auditLogRepo:            postgres.NewAuditLog(db),
+		mediaRepo:               postgres.NewMedia(db),
+		sitemapRepo:             postgres.NewSitemap(db),
+		webhookRepo:             postgres.NewWebhook(db),
+		webhookDeliveryRepo:     postgres.NewWebhookDelivery(db),
 	}
 }
 
@@ -51,3 +87,51 @@ func (s *storagePg) Comment() repo.CommentStorageI {
 func (s *storagePg) Like() repo.LikeStorageI {
 	return s.likeRepo
 }
+
+func (s *storagePg) EmailOutbox() repo.EmailOutboxStorageI {
+	return s.emailOutboxRepo
+}
+
+func (s *storagePg) RecoveryCode() repo.RecoveryCodeStorageI {
+	return s.recoveryCodeRepo
+}
+
+func (s *storagePg) PersonalAccessToken() repo.PersonalAccessTokenStorageI {
+	return s.personalAccessTokenRepo
+}
+
+func (s *storagePg) UserIdentity() repo.UserIdentityStorageI {
+	return s.userIdentityRepo
+}
+
+func (s *storagePg) Session() repo.SessionStorageI {
+	return s.sessionRepo
+}
+
+func (s *storagePg) DataExport() repo.DataExportStorageI {
+	return s.dataExportRepo
+}
+
+func (s *storagePg) ModerationAction() repo.ModerationActionStorageI {
+	return s.moderationActionRepo
+}
+
+func (s *storagePg) AuditLog() repo.AuditLogStorageI {
+	return s.auditLogRepo
+}
+
+func (s *storagePg) Media() repo.MediaStorageI {
+	return s.mediaRepo
+}
+
+func (s *storagePg) Sitemap() repo.SitemapStorageI {
+	return s.sitemapRepo
+}
+
+func (s *storagePg) Webhook() repo.WebhookStorageI {
+	return s.webhookRepo
+}
+
+func (s *storagePg) WebhookDelivery() repo.WebhookDeliveryStorageI {
+	return s.webhookDeliveryRepo
+}
@@ -0,0 +1,13 @@
+package templates
+
+import "embed"
+
+// Email holds the email layouts and their per-locale contents
+//
+//go:embed email
+var Email embed.FS
+
+// Web holds the layout, the pages and the static assets of the public site
+//
+//go:embed web
+var Web embed.FS
@@ -0,0 +1,117 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ibrat-muslim/blog-app/config"
+	"github.com/ibrat-muslim/blog-app/pkg/blob"
+	"github.com/ibrat-muslim/blog-app/pkg/imaging"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+const accountDeletionBatchSize = 20
+
+type accountDeletionWorker struct {
+	cfg       *config.AccountDeletion
+	users     repo.UserStorageI
+	exportDir string
+	media     repo.MediaStorageI
+	blobs     blob.BlobStore
+	images    *imaging.Processor
+}
+
+type AccountDeletionWorkerOptions struct {
+	Cfg   *config.AccountDeletion
+	Users repo.UserStorageI
+	// ExportDir holds the data export archives, Media lists the files the user uploaded,
+	// Blobs holds them and Images knows which resized copies were made of them
+	ExportDir string
+	Media     repo.MediaStorageI
+	Blobs     blob.BlobStore
+	Images    *imaging.Processor
+}
+
+func NewAccountDeletionWorker(options *AccountDeletionWorkerOptions) *accountDeletionWorker {
+	return &accountDeletionWorker{
+		cfg:       options.Cfg,
+		users:     options.Users,
+		exportDir: options.ExportDir,
+		media:     options.Media,
+		blobs:     options.Blobs,
+		images:    options.Images,
+	}
+}
+
+// Run deletes accounts whose grace period is over until the context is cancelled
+func (w *accountDeletionWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		err := w.ProcessBatch()
+		if err != nil {
+			log.Printf("failed to process account deletions: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch deletes every account that is due, a failed account is retried on the next run
+func (w *accountDeletionWorker) ProcessBatch() error {
+	users, err := w.users.GetDueForDeletion(accountDeletionBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		err = w.delete(user)
+		if err != nil {
+			log.Printf("failed to delete account %d: %v", user.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// delete removes the files the user uploaded that nothing links anymore, the URLs in posts
+// and profiles are not followed as they may name files of other users. Files still linked
+// by reassigned posts are left to the media cleanup job
+func (w *accountDeletionWorker) delete(user *repo.User) error {
+	// The files are listed first, they have no owner once the account is gone
+	uploads, err := userMedia(w.media, user.ID)
+	if err != nil {
+		return err
+	}
+
+	err = w.users.DeleteAccount(user.ID, w.cfg.RemoveContent)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("account %d has been deleted", user.ID)
+
+	for _, media := range uploads {
+		linked, err := w.media.IsLinked(media.Path)
+		if err == nil && !linked {
+			err = removeMedia(w.blobs, w.images, w.media, media)
+		}
+		if err != nil {
+			log.Printf("failed to remove media %d of deleted account %d: %v", media.ID, user.ID, err)
+		}
+	}
+
+	err = os.RemoveAll(DataExportDir(w.exportDir, user.ID))
+	if err != nil {
+		log.Printf("failed to remove data exports of deleted account %d: %v", user.ID, err)
+	}
+
+	return nil
+}
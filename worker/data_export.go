@@ -0,0 +1,412 @@
+package worker
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/ibrat-muslim/blog-app/config"
+	"github.com/ibrat-muslim/blog-app/pkg/blob"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+const (
+	dataExportBatchSize = 5
+	dataExportPageSize  = 100
+
+	dataExportBaseBackoff = time.Minute
+	dataExportMaxBackoff  = time.Hour
+)
+
+type exportProfile struct {
+	ID              int64     `json:"id"`
+	FirstName       string    `json:"first_name"`
+	LastName        string    `json:"last_name"`
+	PhoneNumber     *string   `json:"phone_number"`
+	Email           string    `json:"email"`
+	Gender          *string   `json:"gender"`
+	Username        *string   `json:"username"`
+	ProfileImageUrl *string   `json:"profile_image_url"`
+	Type            string    `json:"type"`
+	Language        *string   `json:"language"`
+	TwoFactor       bool      `json:"two_factor_enabled"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type exportPost struct {
+	ID          int64      `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	ImageUrl    *string    `json:"image_url"`
+	CategoryID  int64      `json:"category_id"`
+	ViewsCount  int32      `json:"views_count"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+}
+
+type exportComment struct {
+	ID          int64      `json:"id"`
+	PostID      int64      `json:"post_id"`
+	Description string     `json:"description"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+}
+
+type exportLike struct {
+	PostID int64 `json:"post_id"`
+	Status bool  `json:"status"`
+}
+
+type dataExportWorker struct {
+	cfg      *config.DataExport
+	exports  repo.DataExportStorageI
+	users    repo.UserStorageI
+	posts    repo.PostStorageI
+	comments repo.CommentStorageI
+	likes    repo.LikeStorageI
+	media    repo.MediaStorageI
+	blobs    blob.BlobStore
+}
+
+type DataExportWorkerOptions struct {
+	Cfg      *config.DataExport
+	Exports  repo.DataExportStorageI
+	Users    repo.UserStorageI
+	Posts    repo.PostStorageI
+	Comments repo.CommentStorageI
+	Likes    repo.LikeStorageI
+	// Media lists the files the user uploaded and Blobs holds them
+	Media repo.MediaStorageI
+	Blobs blob.BlobStore
+}
+
+func NewDataExportWorker(options *DataExportWorkerOptions) *dataExportWorker {
+	return &dataExportWorker{
+		cfg:      options.Cfg,
+		exports:  options.Exports,
+		users:    options.Users,
+		posts:    options.Posts,
+		comments: options.Comments,
+		likes:    options.Likes,
+		media:    options.Media,
+		blobs:    options.Blobs,
+	}
+}
+
+// Run builds requested exports and removes expired ones until the context is cancelled
+func (w *dataExportWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		err := w.ProcessBatch()
+		if err != nil {
+			log.Printf("failed to process data exports: %v", err)
+		}
+
+		err = w.RemoveExpired()
+		if err != nil {
+			log.Printf("failed to remove expired data exports: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch builds the archive of every export that is due and schedules retries for failures
+func (w *dataExportWorker) ProcessBatch() error {
+	exports, err := w.exports.ClaimPending(dataExportBatchSize, w.cfg.Lease)
+	if err != nil {
+		return err
+	}
+
+	for _, export := range exports {
+		filePath, err := w.build(export)
+		if err == nil {
+			err = w.exports.MarkReady(export.ID, filePath, time.Now().Add(w.cfg.TTL))
+			if err != nil {
+				log.Printf("failed to mark data export %s as ready: %v", export.ID, err)
+			}
+			continue
+		}
+
+		attempts := export.Attempts + 1
+		failed := attempts >= w.cfg.MaxAttempts
+		if failed {
+			log.Printf("data export %s of user %d failed after %d attempts: %v", export.ID, export.UserID, attempts, err)
+		}
+
+		nextAttemptAt := time.Now().Add(Backoff(attempts, dataExportBaseBackoff, dataExportMaxBackoff))
+
+		err = w.exports.MarkFailed(export.ID, err.Error(), nextAttemptAt, failed)
+		if err != nil {
+			log.Printf("failed to mark data export %s as failed: %v", export.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// RemoveExpired deletes expired exports together with their archives
+func (w *dataExportWorker) RemoveExpired() error {
+	exports, err := w.exports.DeleteExpired()
+	if err != nil {
+		return err
+	}
+
+	for _, export := range exports {
+		if export.FilePath == nil {
+			continue
+		}
+
+		err = os.Remove(*export.FilePath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("failed to remove data export %s: %v", *export.FilePath, err)
+		}
+	}
+
+	return nil
+}
+
+// DataExportDir is the directory with the archives of the user, it is removed with the account
+func DataExportDir(dir string, userID int64) string {
+	return filepath.Join(dir, strconv.FormatInt(userID, 10))
+}
+
+// build writes the archive next to its final path first, so a crash never leaves a partial archive behind
+func (w *dataExportWorker) build(export *repo.DataExport) (string, error) {
+	dir := DataExportDir(w.cfg.Dir, export.UserID)
+
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return "", err
+	}
+
+	filePath := filepath.Join(dir, export.ID+".zip")
+	tmpPath := filePath + ".tmp"
+
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpPath)
+
+	err = w.write(zip.NewWriter(file), export.UserID)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	err = os.Rename(tmpPath, filePath)
+	if err != nil {
+		return "", err
+	}
+
+	return filePath, nil
+}
+
+func (w *dataExportWorker) write(archive *zip.Writer, userID int64) error {
+	user, err := w.users.Get(userID)
+	if err != nil {
+		return err
+	}
+
+	posts, err := userPosts(w.posts, userID)
+	if err != nil {
+		return err
+	}
+
+	comments, err := w.userComments(userID)
+	if err != nil {
+		return err
+	}
+
+	likes, err := w.likes.GetAllByUser(userID)
+	if err != nil {
+		return err
+	}
+
+	uploads, err := userMedia(w.media, userID)
+	if err != nil {
+		return err
+	}
+
+	exportPosts := make([]*exportPost, 0, len(posts))
+	for _, post := range posts {
+		exportPosts = append(exportPosts, &exportPost{
+			ID:          post.ID,
+			Title:       post.Title,
+			Description: post.Description,
+			ImageUrl:    post.ImageUrl,
+			CategoryID:  post.CategoryID,
+			ViewsCount:  post.ViewsCount,
+			CreatedAt:   post.CreatedAt,
+			UpdatedAt:   post.UpdatedAt,
+		})
+	}
+
+	exportComments := make([]*exportComment, 0, len(comments))
+	for _, comment := range comments {
+		exportComments = append(exportComments, &exportComment{
+			ID:          comment.ID,
+			PostID:      comment.PostID,
+			Description: comment.Description,
+			CreatedAt:   comment.CreatedAt,
+			UpdatedAt:   comment.UpdatedAt,
+		})
+	}
+
+	exportLikes := make([]*exportLike, 0, len(likes))
+	for _, like := range likes {
+		exportLikes = append(exportLikes, &exportLike{
+			PostID: like.PostID,
+			Status: like.Status,
+		})
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", &exportProfile{
+			ID:              user.ID,
+			FirstName:       user.FirstName,
+			LastName:        user.LastName,
+			PhoneNumber:     user.PhoneNumber,
+			Email:           user.Email,
+			Gender:          user.Gender,
+			Username:        user.Username,
+			ProfileImageUrl: user.ProfileImageUrl,
+			Type:            user.Type,
+			Language:        user.Language,
+			TwoFactor:       user.TotpEnabled,
+			CreatedAt:       user.CreatedAt,
+		}},
+		{"posts.json", exportPosts},
+		{"comments.json", exportComments},
+		{"likes.json", exportLikes},
+	}
+
+	for _, file := range files {
+		writer, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+
+		err = encoder.Encode(file.data)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The URLs in posts and profiles may name files of other users, only the uploads of the user are added
+	for _, media := range uploads {
+		err = w.addMedia(archive, media.Path)
+		if err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// addMedia copies an uploaded file into the archive, a file that is gone is skipped
+func (w *dataExportWorker) addMedia(archive *zip.Writer, key string) error {
+	body, err := w.blobs.Get(context.Background(), key)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	defer body.Close()
+
+	writer, err := archive.Create("media/" + key)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(writer, body)
+	return err
+}
+
+// userPosts pages through every post of the user
+func userPosts(storage repo.PostStorageI, userID int64) ([]*repo.Post, error) {
+	result := make([]*repo.Post, 0)
+
+	for page := int32(1); ; page++ {
+		posts, err := storage.GetAll(&repo.GetPostsParams{
+			Limit:  dataExportPageSize,
+			Page:   page,
+			UserID: userID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, posts.Posts...)
+
+		if len(posts.Posts) < dataExportPageSize {
+			return result, nil
+		}
+	}
+}
+
+// userMedia pages through every file the user uploaded
+func userMedia(storage repo.MediaStorageI, userID int64) ([]*repo.Media, error) {
+	result := make([]*repo.Media, 0)
+
+	for page := int32(1); ; page++ {
+		media, err := storage.GetAll(&repo.GetMediaParams{
+			Limit:  dataExportPageSize,
+			Page:   page,
+			UserID: userID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, media.Media...)
+
+		if len(media.Media) < dataExportPageSize {
+			return result, nil
+		}
+	}
+}
+
+func (w *dataExportWorker) userComments(userID int64) ([]*repo.Comment, error) {
+	result := make([]*repo.Comment, 0)
+
+	for page := int32(1); ; page++ {
+		comments, err := w.comments.GetAll(&repo.GetCommentsParams{
+			Limit:  dataExportPageSize,
+			Page:   page,
+			UserID: userID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, comments.Comments...)
+
+		if len(comments.Comments) < dataExportPageSize {
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package worker
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ibrat-muslim/blog-app/config"
+	"github.com/ibrat-muslim/blog-app/pkg/blob"
+	"github.com/ibrat-muslim/blog-app/pkg/imaging"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeExports struct {
+	repo.DataExportStorageI
+	exports map[string]*repo.DataExport
+}
+
+func (f *fakeExports) ClaimPending(limit int32, lease time.Duration) ([]*repo.DataExport, error) {
+	result := make([]*repo.DataExport, 0)
+	for _, export := range f.exports {
+		if export.Status == repo.DataExportStatusPending {
+			result = append(result, export)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeExports) MarkReady(id, filePath string, expiresAt time.Time) error {
+	export := f.exports[id]
+	export.Status = repo.DataExportStatusReady
+	export.FilePath = &filePath
+	export.ExpiresAt = &expiresAt
+	return nil
+}
+
+func (f *fakeExports) MarkFailed(id, errMsg string, nextAttemptAt time.Time, failed bool) error {
+	export := f.exports[id]
+	export.Attempts++
+	export.LastError = &errMsg
+	if failed {
+		export.Status = repo.DataExportStatusFailed
+	}
+	return nil
+}
+
+type fakeUsers struct {
+	repo.UserStorageI
+	users   map[int64]*repo.User
+	deleted map[int64]bool
+}
+
+func (f *fakeUsers) Get(id int64) (*repo.User, error) {
+	user, ok := f.users[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return user, nil
+}
+
+func (f *fakeUsers) GetDueForDeletion(limit int32) ([]*repo.User, error) {
+	result := make([]*repo.User, 0)
+	for _, user := range f.users {
+		if user.DeletionScheduledAt != nil && user.DeletionScheduledAt.Before(time.Now()) {
+			result = append(result, user)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeUsers) DeleteAccount(userID int64, removeContent bool) error {
+	delete(f.users, userID)
+	f.deleted[userID] = removeContent
+	return nil
+}
+
+type fakePosts struct {
+	repo.PostStorageI
+	posts []*repo.Post
+}
+
+func (f *fakePosts) GetAll(params *repo.GetPostsParams) (*repo.GetPostsResult, error) {
+	result := &repo.GetPostsResult{Posts: make([]*repo.Post, 0)}
+	if params.Page != 1 {
+		return result, nil
+	}
+	for _, post := range f.posts {
+		if post.UserID == params.UserID {
+			result.Posts = append(result.Posts, post)
+		}
+	}
+	return result, nil
+}
+
+type fakeComments struct {
+	repo.CommentStorageI
+}
+
+func (f *fakeComments) GetAll(params *repo.GetCommentsParams) (*repo.GetCommentsResult, error) {
+	return &repo.GetCommentsResult{Comments: []*repo.Comment{
+		{ID: 3, PostID: 9, UserID: params.UserID, Description: "Nice post"},
+	}}, nil
+}
+
+type fakeLikes struct {
+	repo.LikeStorageI
+}
+
+func (f *fakeLikes) GetAllByUser(userID int64) ([]*repo.Like, error) {
+	return []*repo.Like{{ID: 4, PostID: 9, UserID: userID, Status: true}}, nil
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestDataExportProcessBatch(t *testing.T) {
+	mediaDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(mediaDir, "avatar.png"), []byte("avatar"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(mediaDir, "other.png"), []byte("other"), 0o600))
+
+	owner, other := int64(7), int64(8)
+
+	exports := &fakeExports{exports: map[string]*repo.DataExport{
+		"export": {ID: "export", UserID: 7, Status: repo.DataExportStatusPending},
+	}}
+
+	w := NewDataExportWorker(&DataExportWorkerOptions{
+		Cfg: &config.DataExport{
+			Dir:         t.TempDir(),
+			TTL:         time.Hour,
+			Lease:       time.Minute,
+			MaxAttempts: 3,
+		},
+		Exports: exports,
+		Users: &fakeUsers{users: map[int64]*repo.User{
+			7: {ID: 7, FirstName: "John", Email: "john@example.com", ProfileImageUrl: stringPtr("/media/avatar.png")},
+		}},
+		Posts: &fakePosts{posts: []*repo.Post{
+			{ID: 9, Title: "Hello", UserID: 7, ImageUrl: stringPtr("/media/other.png")},
+			{ID: 10, Title: "Someone else's", UserID: 8},
+		}},
+		Comments: &fakeComments{},
+		Likes:    &fakeLikes{},
+		Media: &fakeMedia{media: map[int64]*repo.Media{
+			1: {ID: 1, UserID: &owner, Path: "avatar.png"},
+			2: {ID: 2, UserID: &owner, Path: "missing.png"},
+			3: {ID: 3, UserID: &other, Path: "other.png"},
+		}},
+		Blobs: blob.NewLocalStore(mediaDir),
+	})
+
+	err := w.ProcessBatch()
+	require.NoError(t, err)
+
+	export := exports.exports["export"]
+	require.Equal(t, repo.DataExportStatusReady, export.Status)
+	require.NotNil(t, export.FilePath)
+
+	archive, err := zip.OpenReader(*export.FilePath)
+	require.NoError(t, err)
+	defer archive.Close()
+
+	files := make(map[string][]byte)
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		reader.Close()
+
+		files[file.Name] = data
+	}
+
+	// The post links a file of another user, only the uploads of the user are exported
+	require.Len(t, files, 5)
+	require.Equal(t, []byte("avatar"), files["media/avatar.png"])
+	require.NotContains(t, files, "media/other.png")
+
+	var profile exportProfile
+	require.NoError(t, json.Unmarshal(files["profile.json"], &profile))
+	require.Equal(t, "john@example.com", profile.Email)
+
+	var posts []exportPost
+	require.NoError(t, json.Unmarshal(files["posts.json"], &posts))
+	require.Len(t, posts, 1)
+	require.Equal(t, "Hello", posts[0].Title)
+
+	require.Contains(t, string(files["comments.json"]), "Nice post")
+	require.Contains(t, string(files["likes.json"]), `"post_id": 9`)
+}
+
+func TestDataExportProcessBatchFails(t *testing.T) {
+	exports := &fakeExports{exports: map[string]*repo.DataExport{
+		"export": {ID: "export", UserID: 7, Status: repo.DataExportStatusPending, Attempts: 2},
+	}}
+
+	w := NewDataExportWorker(&DataExportWorkerOptions{
+		Cfg:     &config.DataExport{Dir: t.TempDir(), MaxAttempts: 3},
+		Exports: exports,
+		Users:   &fakeUsers{users: map[int64]*repo.User{}},
+	})
+
+	err := w.ProcessBatch()
+	require.NoError(t, err)
+
+	export := exports.exports["export"]
+	require.Equal(t, repo.DataExportStatusFailed, export.Status)
+	require.Equal(t, "not found", *export.LastError)
+
+	entries, err := os.ReadDir(DataExportDir(w.cfg.Dir, 7))
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestAccountDeletionProcessBatch(t *testing.T) {
+	mediaDir := t.TempDir()
+	exportDir := t.TempDir()
+
+	files := []string{
+		"images/a1/original.png",
+		"images/a1/thumb.png",
+		"images/b2/original.png",
+		"images/c3/original.png",
+	}
+	for _, file := range files {
+		path := filepath.Join(mediaDir, filepath.FromSlash(file))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+		require.NoError(t, os.WriteFile(path, []byte(file), 0o600))
+	}
+	require.NoError(t, os.MkdirAll(DataExportDir(exportDir, 7), 0o700))
+
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	users := &fakeUsers{
+		users: map[int64]*repo.User{
+			7: {ID: 7, ProfileImageUrl: stringPtr("/media/images/c3/original.png"), DeletionScheduledAt: &past},
+			8: {ID: 8, DeletionScheduledAt: &future},
+			9: {ID: 9},
+		},
+		deleted: make(map[int64]bool),
+	}
+
+	owner, other := int64(7), int64(8)
+
+	media := &fakeMedia{
+		media: map[int64]*repo.Media{
+			1: {ID: 1, UserID: &owner, Path: "images/a1/original.png"},
+			2: {ID: 2, UserID: &owner, Path: "images/b2/original.png"},
+			3: {ID: 3, UserID: &other, Path: "images/c3/original.png"},
+		},
+		linked: map[string]bool{"images/b2/original.png": true},
+	}
+
+	blobs := blob.NewLocalStore(mediaDir)
+
+	images, err := imaging.NewProcessor(&imaging.ProcessorOptions{
+		Blobs:    blobs,
+		Variants: map[string]string{"avatar": "thumb:64x64:crop"},
+	})
+	require.NoError(t, err)
+
+	w := NewAccountDeletionWorker(&AccountDeletionWorkerOptions{
+		Cfg:       &config.AccountDeletion{},
+		Users:     users,
+		ExportDir: exportDir,
+		Media:     media,
+		Blobs:     blobs,
+		Images:    images,
+	})
+
+	err = w.ProcessBatch()
+	require.NoError(t, err)
+
+	require.Equal(t, map[int64]bool{7: false}, users.deleted)
+	require.NoDirExists(t, DataExportDir(exportDir, 7))
+
+	// Only the unlinked upload of the user goes, the profile named a file of another user
+	require.NotContains(t, media.media, int64(1))
+	require.NoFileExists(t, filepath.Join(mediaDir, "images", "a1", "original.png"))
+	require.NoFileExists(t, filepath.Join(mediaDir, "images", "a1", "thumb.png"))
+
+	require.Contains(t, media.media, int64(2))
+	require.FileExists(t, filepath.Join(mediaDir, "images", "b2", "original.png"))
+	require.Contains(t, media.media, int64(3))
+	require.FileExists(t, filepath.Join(mediaDir, "images", "c3", "original.png"))
+}
@@ -0,0 +1,115 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ibrat-muslim/blog-app/config"
+	emailPkg "github.com/ibrat-muslim/blog-app/pkg/email"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+type emailOutboxWorker struct {
+	cfg    *config.EmailOutbox
+	outbox repo.EmailOutboxStorageI
+	mailer emailPkg.Mailer
+}
+
+type EmailOutboxWorkerOptions struct {
+	Cfg    *config.EmailOutbox
+	Outbox repo.EmailOutboxStorageI
+	Mailer emailPkg.Mailer
+}
+
+func NewEmailOutboxWorker(options *EmailOutboxWorkerOptions) *emailOutboxWorker {
+	return &emailOutboxWorker{
+		cfg:    options.Cfg,
+		outbox: options.Outbox,
+		mailer: options.Mailer,
+	}
+}
+
+// Run polls the outbox until the context is cancelled
+func (w *emailOutboxWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		err := w.ProcessBatch()
+		if err != nil {
+			log.Printf("failed to process email outbox: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch sends every email that is due and schedules retries for failures
+func (w *emailOutboxWorker) ProcessBatch() error {
+	// The lease must outlive a slow SMTP round trip, otherwise another worker could pick the email up twice
+	emails, err := w.outbox.ClaimPending(w.cfg.BatchSize, w.cfg.Lease)
+	if err != nil {
+		return err
+	}
+
+	for _, email := range emails {
+		// The code or link in it would not work anymore
+		if email.ExpiresAt != nil && !email.ExpiresAt.After(time.Now()) {
+			err := w.outbox.MarkExpired(email.ID)
+			if err != nil {
+				log.Printf("failed to mark email %d as expired: %v", email.ID, err)
+			}
+			continue
+		}
+
+		err := w.mailer.Send(&emailPkg.Message{
+			To:       []string{email.Recipient},
+			Subject:  email.Subject,
+			HTMLBody: email.HTMLBody,
+			TextBody: email.TextBody,
+		})
+		if err == nil {
+			err = w.outbox.MarkSent(email.ID)
+			if err != nil {
+				log.Printf("failed to mark email %d as sent: %v", email.ID, err)
+			}
+			continue
+		}
+
+		attempts := email.Attempts + 1
+		dead := attempts >= w.cfg.MaxAttempts
+		if dead {
+			log.Printf("email %d to %s moved to dead letter after %d attempts: %v", email.ID, email.Recipient, attempts, err)
+		}
+
+		nextAttemptAt := time.Now().Add(Backoff(attempts, w.cfg.BaseBackoff, w.cfg.MaxBackoff))
+
+		err = w.outbox.MarkFailed(email.ID, err.Error(), nextAttemptAt, dead)
+		if err != nil {
+			log.Printf("failed to mark email %d as failed: %v", email.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// Backoff returns the exponential delay before the next attempt, capped at max
+func Backoff(attempts int32, base, max time.Duration) time.Duration {
+	delay := base
+	for i := int32(1); i < attempts; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+
+	if delay > max {
+		return max
+	}
+	return delay
+}
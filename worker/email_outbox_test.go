@@ -0,0 +1,136 @@
+package worker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ibrat-muslim/blog-app/config"
+	emailPkg "github.com/ibrat-muslim/blog-app/pkg/email"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeOutbox struct {
+	repo.EmailOutboxStorageI
+	emails map[int64]*repo.EmailOutbox
+}
+
+func (f *fakeOutbox) ClaimPending(limit int32, lease time.Duration) ([]*repo.EmailOutbox, error) {
+	result := make([]*repo.EmailOutbox, 0)
+	for _, email := range f.emails {
+		if email.Status == repo.EmailStatusPending {
+			result = append(result, email)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeOutbox) MarkSent(id int64) error {
+	f.emails[id].Status = repo.EmailStatusSent
+	f.emails[id].Attempts++
+	return nil
+}
+
+func (f *fakeOutbox) MarkExpired(id int64) error {
+	f.emails[id].Status = repo.EmailStatusDead
+	f.emails[id].HTMLBody = ""
+	f.emails[id].TextBody = ""
+	return nil
+}
+
+func (f *fakeOutbox) MarkFailed(id int64, errMsg string, nextAttemptAt time.Time, dead bool) error {
+	email := f.emails[id]
+	email.Attempts++
+	email.LastError = &errMsg
+	email.NextAttemptAt = nextAttemptAt
+	if dead {
+		email.Status = repo.EmailStatusDead
+	}
+	return nil
+}
+
+type failingMailer struct{}
+
+func (failingMailer) Send(msg *emailPkg.Message) error {
+	return errors.New("connection refused")
+}
+
+func newTestConfig() *config.EmailOutbox {
+	return &config.EmailOutbox{
+		MaxAttempts: 2,
+		BaseBackoff: time.Second,
+		MaxBackoff:  time.Hour,
+		Lease:       time.Minute,
+		BatchSize:   10,
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	require.Equal(t, 30*time.Second, Backoff(1, 30*time.Second, time.Hour))
+	require.Equal(t, 60*time.Second, Backoff(2, 30*time.Second, time.Hour))
+	require.Equal(t, 4*time.Minute, Backoff(4, 30*time.Second, time.Hour))
+	require.Equal(t, time.Hour, Backoff(20, 30*time.Second, time.Hour))
+}
+
+func TestProcessBatchSends(t *testing.T) {
+	outbox := &fakeOutbox{emails: map[int64]*repo.EmailOutbox{
+		1: {ID: 1, Recipient: "user@example.com", Subject: "Verification email", Status: repo.EmailStatusPending},
+	}}
+	mailer := emailPkg.NewMemoryMailer()
+
+	w := NewEmailOutboxWorker(&EmailOutboxWorkerOptions{
+		Cfg:    newTestConfig(),
+		Outbox: outbox,
+		Mailer: mailer,
+	})
+
+	err := w.ProcessBatch()
+	require.NoError(t, err)
+
+	require.Equal(t, repo.EmailStatusSent, outbox.emails[1].Status)
+	require.Len(t, mailer.Messages(), 1)
+	require.Equal(t, []string{"user@example.com"}, mailer.Last().To)
+}
+
+func TestProcessBatchDeadLetter(t *testing.T) {
+	outbox := &fakeOutbox{emails: map[int64]*repo.EmailOutbox{
+		1: {ID: 1, Recipient: "user@example.com", Status: repo.EmailStatusPending},
+	}}
+
+	w := NewEmailOutboxWorker(&EmailOutboxWorkerOptions{
+		Cfg:    newTestConfig(),
+		Outbox: outbox,
+		Mailer: failingMailer{},
+	})
+
+	err := w.ProcessBatch()
+	require.NoError(t, err)
+	require.Equal(t, repo.EmailStatusPending, outbox.emails[1].Status)
+	require.Equal(t, "connection refused", *outbox.emails[1].LastError)
+
+	err = w.ProcessBatch()
+	require.NoError(t, err)
+	require.Equal(t, repo.EmailStatusDead, outbox.emails[1].Status)
+	require.EqualValues(t, 2, outbox.emails[1].Attempts)
+}
+
+func TestProcessBatchSkipsExpired(t *testing.T) {
+	expiresAt := time.Now().Add(-time.Minute)
+	outbox := &fakeOutbox{emails: map[int64]*repo.EmailOutbox{
+		1: {ID: 1, Recipient: "user@example.com", TextBody: "123456", Status: repo.EmailStatusPending, ExpiresAt: &expiresAt},
+	}}
+	mailer := emailPkg.NewMemoryMailer()
+
+	w := NewEmailOutboxWorker(&EmailOutboxWorkerOptions{
+		Cfg:    newTestConfig(),
+		Outbox: outbox,
+		Mailer: mailer,
+	})
+
+	err := w.ProcessBatch()
+	require.NoError(t, err)
+	require.Equal(t, repo.EmailStatusDead, outbox.emails[1].Status)
+	require.Empty(t, outbox.emails[1].TextBody)
+	require.Empty(t, mailer.Messages())
+}
@@ -0,0 +1,92 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ibrat-muslim/blog-app/config"
+	"github.com/ibrat-muslim/blog-app/pkg/blob"
+	"github.com/ibrat-muslim/blog-app/pkg/imaging"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+const mediaCleanupBatchSize = 100
+
+type mediaCleanupWorker struct {
+	cfg    *config.Media
+	media  repo.MediaStorageI
+	blobs  blob.BlobStore
+	images *imaging.Processor
+}
+
+type MediaCleanupWorkerOptions struct {
+	Cfg   *config.Media
+	Media repo.MediaStorageI
+	Blobs blob.BlobStore
+	// Images knows which resized copies were made of the files
+	Images *imaging.Processor
+}
+
+func NewMediaCleanupWorker(options *MediaCleanupWorkerOptions) *mediaCleanupWorker {
+	return &mediaCleanupWorker{
+		cfg:    options.Cfg,
+		media:  options.Media,
+		blobs:  options.Blobs,
+		images: options.Images,
+	}
+}
+
+// Run removes orphaned uploads until the context is cancelled
+func (w *mediaCleanupWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		err := w.ProcessBatch()
+		if err != nil {
+			log.Printf("failed to clean up media: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch removes uploads that no post or profile has linked since the grace period began,
+// a file that fails to be removed is retried on the next run
+func (w *mediaCleanupWorker) ProcessBatch() error {
+	orphans, err := w.media.GetOrphans(time.Now().Add(-w.cfg.OrphanGracePeriod), mediaCleanupBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, media := range orphans {
+		err = w.delete(media)
+		if err != nil {
+			log.Printf("failed to remove orphaned media %d: %v", media.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (w *mediaCleanupWorker) delete(media *repo.Media) error {
+	return removeMedia(w.blobs, w.images, w.media, media)
+}
+
+// removeMedia removes the file with its resized copies before the record,
+// so a failure leaves the record to retry with
+func removeMedia(blobs blob.BlobStore, images *imaging.Processor, storage repo.MediaStorageI, media *repo.Media) error {
+	for _, key := range images.Keys(media.Path) {
+		err := blobs.Delete(context.Background(), key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return storage.Delete(media.ID)
+}
@@ -0,0 +1,108 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ibrat-muslim/blog-app/config"
+	"github.com/ibrat-muslim/blog-app/pkg/blob"
+	"github.com/ibrat-muslim/blog-app/pkg/imaging"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMedia struct {
+	repo.MediaStorageI
+	media  map[int64]*repo.Media
+	linked map[string]bool
+}
+
+func (f *fakeMedia) GetOrphans(uploadedBefore time.Time, limit int32) ([]*repo.Media, error) {
+	result := make([]*repo.Media, 0)
+	for _, media := range f.media {
+		if media.LastUploadedAt.Before(uploadedBefore) && !f.linked[media.Path] {
+			result = append(result, media)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeMedia) GetAll(params *repo.GetMediaParams) (*repo.GetMediaResult, error) {
+	result := &repo.GetMediaResult{Media: make([]*repo.Media, 0)}
+	if params.Page != 1 {
+		return result, nil
+	}
+	for _, media := range f.media {
+		if media.UserID != nil && *media.UserID == params.UserID {
+			result.Media = append(result.Media, media)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeMedia) IsLinked(path string) (bool, error) {
+	return f.linked[path], nil
+}
+
+func (f *fakeMedia) Delete(id int64) error {
+	delete(f.media, id)
+	return nil
+}
+
+func TestMediaCleanupProcessBatch(t *testing.T) {
+	dir := t.TempDir()
+
+	files := []string{
+		"images/a1/original.png",
+		"images/a1/thumb.webp",
+		"images/b2/original.png",
+		"images/c3/original.png",
+	}
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+		require.NoError(t, os.WriteFile(path, []byte(file), 0o600))
+	}
+
+	blobs := blob.NewLocalStore(dir)
+
+	images, err := imaging.NewProcessor(&imaging.ProcessorOptions{
+		Blobs:    blobs,
+		Variants: map[string]string{"post_image": "thumb:320x320"},
+		Format:   imaging.FormatWebP,
+	})
+	require.NoError(t, err)
+
+	old := time.Now().Add(-48 * time.Hour)
+
+	media := &fakeMedia{
+		media: map[int64]*repo.Media{
+			1: {ID: 1, Path: "images/a1/original.png", LastUploadedAt: old},
+			2: {ID: 2, Path: "images/b2/original.png", LastUploadedAt: old},
+			3: {ID: 3, Path: "images/c3/original.png", LastUploadedAt: time.Now()},
+		},
+		linked: map[string]bool{"images/b2/original.png": true},
+	}
+
+	w := NewMediaCleanupWorker(&MediaCleanupWorkerOptions{
+		Cfg:    &config.Media{OrphanGracePeriod: 24 * time.Hour},
+		Media:  media,
+		Blobs:  blobs,
+		Images: images,
+	})
+
+	err = w.ProcessBatch()
+	require.NoError(t, err)
+
+	// Only the old file nothing links is removed, with its resized copies
+	require.NotContains(t, media.media, int64(1))
+	require.NoFileExists(t, filepath.Join(dir, "images", "a1", "original.png"))
+	require.NoFileExists(t, filepath.Join(dir, "images", "a1", "thumb.webp"))
+
+	require.Contains(t, media.media, int64(2))
+	require.FileExists(t, filepath.Join(dir, "images", "b2", "original.png"))
+	require.Contains(t, media.media, int64(3))
+	require.FileExists(t, filepath.Join(dir, "images", "c3", "original.png"))
+}
@@ -0,0 +1,98 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ibrat-muslim/blog-app/config"
+	"github.com/ibrat-muslim/blog-app/pkg/webhook"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+)
+
+type webhookWorker struct {
+	cfg        *config.Webhook
+	deliveries repo.WebhookDeliveryStorageI
+	client     *http.Client
+}
+
+type WebhookWorkerOptions struct {
+	Cfg        *config.Webhook
+	Deliveries repo.WebhookDeliveryStorageI
+	Client     *http.Client
+}
+
+func NewWebhookWorker(options *WebhookWorkerOptions) *webhookWorker {
+	return &webhookWorker{
+		cfg:        options.Cfg,
+		deliveries: options.Deliveries,
+		client:     options.Client,
+	}
+}
+
+// Run polls the deliveries until the context is cancelled
+func (w *webhookWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		err := w.ProcessBatch()
+		if err != nil {
+			log.Printf("failed to process webhook deliveries: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch sends every delivery that is due and schedules retries for failures,
+// the client bounds every attempt with its timeout
+func (w *webhookWorker) ProcessBatch() error {
+	// The lease must outlive the attempts of a whole batch, otherwise another worker could send a delivery twice
+	deliveries, err := w.deliveries.ClaimPending(w.cfg.BatchSize, w.cfg.Lease)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		status, err := webhook.Send(context.Background(), w.client, &webhook.Request{
+			URL:        delivery.URL,
+			Secret:     delivery.Secret,
+			Event:      delivery.Event,
+			DeliveryID: delivery.ID,
+			Body:       delivery.Payload,
+		})
+		if err == nil {
+			err = w.deliveries.MarkDelivered(delivery.ID, status)
+			if err != nil {
+				log.Printf("failed to mark webhook delivery %d as delivered: %v", delivery.ID, err)
+			}
+			continue
+		}
+
+		var responseCode *int
+		if status != 0 {
+			responseCode = &status
+		}
+
+		attempts := delivery.Attempts + 1
+		dead := attempts >= w.cfg.MaxAttempts
+		if dead {
+			log.Printf("webhook delivery %d to %s gave up after %d attempts: %v", delivery.ID, delivery.URL, attempts, err)
+		}
+
+		nextAttemptAt := time.Now().Add(Backoff(attempts, w.cfg.BaseBackoff, w.cfg.MaxBackoff))
+
+		err = w.deliveries.MarkFailed(delivery.ID, responseCode, err.Error(), nextAttemptAt, dead)
+		if err != nil {
+			log.Printf("failed to mark webhook delivery %d as failed: %v", delivery.ID, err)
+		}
+	}
+
+	return nil
+}
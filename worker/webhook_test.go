@@ -0,0 +1,140 @@
+package worker
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ibrat-muslim/blog-app/config"
+	"github.com/ibrat-muslim/blog-app/pkg/webhook"
+	"github.com/ibrat-muslim/blog-app/storage/repo"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeWebhookDeliveries struct {
+	repo.WebhookDeliveryStorageI
+	deliveries map[int64]*repo.WebhookDelivery
+}
+
+func (f *fakeWebhookDeliveries) ClaimPending(limit int32, lease time.Duration) ([]*repo.WebhookDelivery, error) {
+	result := make([]*repo.WebhookDelivery, 0)
+	for _, delivery := range f.deliveries {
+		if delivery.Status == repo.WebhookDeliveryStatusPending {
+			result = append(result, delivery)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeWebhookDeliveries) MarkDelivered(id int64, responseCode int) error {
+	delivery := f.deliveries[id]
+	delivery.Status = repo.WebhookDeliveryStatusDelivered
+	delivery.Attempts++
+	delivery.ResponseCode = &responseCode
+	return nil
+}
+
+func (f *fakeWebhookDeliveries) MarkFailed(id int64, responseCode *int, errMsg string, nextAttemptAt time.Time, dead bool) error {
+	delivery := f.deliveries[id]
+	delivery.Attempts++
+	delivery.ResponseCode = responseCode
+	delivery.LastError = &errMsg
+	delivery.NextAttemptAt = nextAttemptAt
+	if dead {
+		delivery.Status = repo.WebhookDeliveryStatusDead
+	}
+	return nil
+}
+
+func newWebhookTestConfig() *config.Webhook {
+	return &config.Webhook{
+		MaxAttempts: 2,
+		BaseBackoff: time.Minute,
+		MaxBackoff:  time.Hour,
+		Lease:       time.Minute,
+		BatchSize:   10,
+	}
+}
+
+func TestWebhookWorkerDelivers(t *testing.T) {
+	var verified bool
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(webhook.TimestampHeader), 10, 64)
+
+		verified = webhook.Verify("secret", timestamp, body, r.Header.Get(webhook.SignatureHeader)) &&
+			r.Header.Get(webhook.DeliveryHeader) == "1"
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer receiver.Close()
+
+	deliveries := &fakeWebhookDeliveries{deliveries: map[int64]*repo.WebhookDelivery{
+		1: {
+			ID:      1,
+			Event:   repo.WebhookEventPostCreated,
+			Payload: []byte(`{"event":"post.created"}`),
+			Status:  repo.WebhookDeliveryStatusPending,
+			URL:     receiver.URL,
+			Secret:  "secret",
+		},
+	}}
+
+	w := NewWebhookWorker(&WebhookWorkerOptions{
+		Cfg:        newWebhookTestConfig(),
+		Deliveries: deliveries,
+		Client:     receiver.Client(),
+	})
+
+	err := w.ProcessBatch()
+	require.NoError(t, err)
+
+	require.True(t, verified)
+	require.Equal(t, repo.WebhookDeliveryStatusDelivered, deliveries.deliveries[1].Status)
+	require.Equal(t, http.StatusAccepted, *deliveries.deliveries[1].ResponseCode)
+}
+
+func TestWebhookWorkerRetries(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "try again later", http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	deliveries := &fakeWebhookDeliveries{deliveries: map[int64]*repo.WebhookDelivery{
+		1: {
+			ID:      1,
+			Event:   repo.WebhookEventCommentCreated,
+			Payload: []byte(`{}`),
+			Status:  repo.WebhookDeliveryStatusPending,
+			URL:     receiver.URL,
+			Secret:  "secret",
+		},
+	}}
+
+	w := NewWebhookWorker(&WebhookWorkerOptions{
+		Cfg:        newWebhookTestConfig(),
+		Deliveries: deliveries,
+		Client:     receiver.Client(),
+	})
+
+	err := w.ProcessBatch()
+	require.NoError(t, err)
+
+	delivery := deliveries.deliveries[1]
+	require.Equal(t, repo.WebhookDeliveryStatusPending, delivery.Status)
+	require.Equal(t, http.StatusServiceUnavailable, *delivery.ResponseCode)
+	require.Contains(t, *delivery.LastError, "try again later")
+	require.WithinDuration(t, time.Now().Add(time.Minute), delivery.NextAttemptAt, 5*time.Second)
+
+	// The receiver is gone on the last attempt, there is no response code then
+	receiver.Close()
+
+	err = w.ProcessBatch()
+	require.NoError(t, err)
+	require.Equal(t, repo.WebhookDeliveryStatusDead, delivery.Status)
+	require.Nil(t, delivery.ResponseCode)
+	require.EqualValues(t, 2, delivery.Attempts)
+}